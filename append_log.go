@@ -0,0 +1,317 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Append logs start with a header of appendLogMagic and snapshotVersion, followed by the entry records
+// of snapshots for every put, and delete records of the encoded key, prefixed with its length as uvarint.
+const (
+	appendLogMagic = "LFCA"
+
+	appendLogDelete = 2
+)
+
+// SyncPolicy sets when an AppendLog syncs its file to disk.
+type SyncPolicy int
+
+const (
+	// SyncNever leaves syncing to the operating system. Records survive a crash of the process,
+	// but not of the operating system.
+	SyncNever SyncPolicy = iota
+	// SyncEverySecond syncs once per second, so at most a second of records is lost on a crash.
+	SyncEverySecond
+	// SyncAlways syncs after every record, which makes every write wait for the disk.
+	SyncAlways
+)
+
+// AppendLog appends the puts and deletes of a LockFreeCache to a file, so its contents survive crashes.
+// Every record is written to the file as it happens, and replayed by Attach. Evictions are not recorded,
+// so the log grows until it is removed and rewritten from a fresh cache.
+type AppendLog[K comparable, V any] struct {
+	keys   Codec[K]
+	values Codec[V]
+	policy SyncPolicy
+
+	lock   sync.Mutex
+	file   *os.File
+	closed bool
+	// err is the first error of a write since the log was opened.
+	err error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OpenAppendLog opens or creates the append log at path, with keys and values encoded by the codecs.
+func OpenAppendLog[K comparable, V any](path string, keys Codec[K], values Codec[V], policy SyncPolicy) (*AppendLog[K, V], error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		if _, err := file.Write(append([]byte(appendLogMagic), snapshotVersion)); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+
+	return &AppendLog[K, V]{
+		keys:   keys,
+		values: values,
+		policy: policy,
+		file:   file,
+	}, nil
+}
+
+// Attach replays the log into c, and then appends all puts and deletes of c to the log.
+// A record which was torn by a crash at the end of the log is removed. Other corruption returns
+// ErrInvalidSnapshot, after replaying the records before it.
+func (l *AppendLog[K, V]) Attach(c *LockFreeCache[K, V]) error {
+	if err := c.usable(); err != nil {
+		return err
+	}
+
+	if err := l.replay(c); err != nil {
+		return err
+	}
+
+	if _, err := l.file.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+
+	if l.policy == SyncEverySecond {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+
+		go l.syncLoop()
+	}
+
+	c.appendLog.Store(l)
+
+	return nil
+}
+
+func (l *AppendLog[K, V]) replay(c *LockFreeCache[K, V]) error {
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	counter := &countingReader{r: l.file}
+	br := bufio.NewReader(counter)
+
+	header := make([]byte, len(appendLogMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("%w: header: %w", ErrInvalidSnapshot, err)
+	}
+
+	if string(header[:len(appendLogMagic)]) != appendLogMagic {
+		return fmt.Errorf("%w: not an append log", ErrInvalidSnapshot)
+	}
+
+	if version := header[len(appendLogMagic)]; version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, version)
+	}
+
+	for {
+		// The offset of the record which is read next.
+		offset := counter.n - int64(br.Buffered())
+
+		tag, err := br.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err == nil {
+			switch tag {
+			case snapshotEntry:
+				err = c.loadEntry(br, l.keys, l.values)
+			case appendLogDelete:
+				err = c.loadDelete(br, l.keys)
+			default:
+				err = fmt.Errorf("%w: unknown record %d", ErrInvalidSnapshot, tag)
+			}
+		}
+
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return l.file.Truncate(offset)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// put appends a put record of entry.
+func (l *AppendLog[K, V]) put(entry *cacheEntry[K, V]) {
+	record, ok, err := appendEncodedEntry(nil, entry, l.keys, l.values)
+	if ok || err != nil {
+		l.write(record, err)
+	}
+}
+
+// delete appends a delete record of key.
+func (l *AppendLog[K, V]) delete(key K) {
+	l.write(appendDeleteRecord(nil, key, l.keys))
+}
+
+// write writes record, or records err if encoding the record failed.
+func (l *AppendLog[K, V]) write(record []byte, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if err != nil {
+		l.err = firstError(l.err, err)
+		return
+	}
+
+	if l.closed {
+		return
+	}
+
+	if _, err := l.file.Write(record); err != nil {
+		l.err = firstError(l.err, err)
+		return
+	}
+
+	if l.policy == SyncAlways {
+		if err := l.file.Sync(); err != nil {
+			l.err = firstError(l.err, err)
+		}
+	}
+}
+
+func (l *AppendLog[K, V]) syncLoop() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.lock.Lock()
+
+			if !l.closed {
+				if err := l.file.Sync(); err != nil {
+					l.err = firstError(l.err, err)
+				}
+			}
+
+			l.lock.Unlock()
+		}
+	}
+}
+
+// sync syncs the file to disk, unless the log is closed.
+func (l *AppendLog[K, V]) sync() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return nil
+	}
+
+	return l.file.Sync()
+}
+
+// Err returns the first error of a write since the log was opened.
+func (l *AppendLog[K, V]) Err() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.err
+}
+
+// Close syncs and closes the file. Later writes of the attached cache are not recorded.
+// It returns the first error of a write since the log was opened, if any.
+func (l *AppendLog[K, V]) Close() error {
+	l.lock.Lock()
+
+	if l.closed {
+		l.lock.Unlock()
+		return l.err
+	}
+
+	l.closed = true
+	l.lock.Unlock()
+
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return errors.Join(l.err, l.file.Sync(), l.file.Close())
+}
+
+// appendDeleteRecord appends a delete record of key, encoded by keys, to record.
+func appendDeleteRecord[K comparable](record []byte, key K, keys Codec[K]) ([]byte, error) {
+	encoded, err := keys.Encode(&key)
+	if err != nil {
+		return record, fmt.Errorf("cache: encode key: %w", err)
+	}
+
+	record = append(record, appendLogDelete)
+	record = binary.AppendUvarint(record, uint64(len(encoded)))
+
+	return append(record, encoded...), nil
+}
+
+// loadDelete reads a single delete record, after its tag, and deletes its key.
+func (c *LockFreeCache[K, V]) loadDelete(br *bufio.Reader, keys Codec[K]) error {
+	encoded, err := readSnapshotField(br)
+	if err != nil {
+		return err
+	}
+
+	key, err := keys.Decode(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: decode key: %w", ErrInvalidSnapshot, err)
+	}
+
+	c.Delete(*key)
+
+	return nil
+}
+
+// firstError returns first, unless it is nil.
+func firstError(first, err error) error {
+	if first != nil {
+		return first
+	}
+
+	return err
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,73 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestAppendLog(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cache.log")
+	keys := cache.JSONCodec[string]{}
+	values := cache.JSONCodec[uint64]{}
+
+	log, err := cache.OpenAppendLog(path, keys, values, cache.SyncAlways)
+	check.True(t, err == nil)
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+	check.True(t, log.Attach(testCache) == nil)
+
+	val1, val2 := uint64(1), uint64(2)
+	testCache.Put("key1", &val1)
+	testCache.Put("key2", &val2)
+	testCache.Delete("key1")
+
+	check.True(t, log.Close() == nil)
+
+	runtime.KeepAlive(&val1)
+	runtime.KeepAlive(&val2)
+
+	before, err := os.Stat(path)
+	check.True(t, err == nil)
+
+	// Simulate a record torn by a crash.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	check.True(t, err == nil)
+
+	_, err = file.Write([]byte{1, 10})
+	check.True(t, err == nil)
+	check.True(t, file.Close() == nil)
+
+	var inserted, deleted []string
+
+	restored := cache.NewLockFreeCache(N/100, cache.WithHooks(cache.Hooks[string, uint64]{
+		OnInsert: func(key string, _ *uint64) {
+			inserted = append(inserted, key)
+		},
+		OnEvict: func(key string, _ *uint64, reason cache.EvictionReason) {
+			if reason == cache.EvictionDeleted {
+				deleted = append(deleted, key)
+			}
+		},
+	}))
+
+	log, err = cache.OpenAppendLog(path, keys, values, cache.SyncNever)
+	check.True(t, err == nil)
+	check.True(t, log.Attach(restored) == nil)
+	check.True(t, log.Close() == nil)
+
+	check.True(t, slices.Equal(inserted, []string{"key1", "key2"}))
+	check.True(t, slices.Equal(deleted, []string{"key1"}))
+
+	// The torn record was removed.
+	after, err := os.Stat(path)
+	check.True(t, err == nil)
+	check.Equal(t, after.Size(), before.Size())
+}
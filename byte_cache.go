@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// byteCacheShards is the number of independently locked shards of a ByteCache.
+	byteCacheShards = 64
+	// byteEntryHeader is the size of the header of a ByteCache entry: the key hash,
+	// and the lengths of the key and value.
+	byteEntryHeader = 16
+)
+
+// ByteCache is a cache of byte slices, which stores keys and values in large ring buffers instead of
+// separate heap allocations. Its index and buffers hold no pointers, so they are not scanned by the garbage
+// collector, which keeps collections cheap for caches with millions of entries.
+// Unlike the other caches, values are copied in and out, and held strongly. Once a shard's buffer is full,
+// new entries overwrite the oldest ones.
+type ByteCache struct {
+	shards [byteCacheShards]byteShard
+	seed   maphash.Seed
+	size   int
+
+	readMisses, readHits atomic.Uint64
+	writes               atomic.Uint64
+	overwrites           atomic.Uint64
+}
+
+var _ Interface[string, []byte] = (*ByteCache)(nil)
+
+type byteShard struct {
+	lock sync.RWMutex
+	// index maps key hashes to the position of their entry in the ring.
+	index map[uint64]uint64
+	ring  []byte
+	// head is the total number of bytes written to the ring, so the position of an entry
+	// is valid while head minus the position does not exceed the ring size.
+	head uint64
+}
+
+// NewByteCache returns a cache which stores up to capacity bytes of keys and values,
+// plus 16 bytes per entry. An entry must fit in a shard, which holds 1/64th of the capacity.
+func NewByteCache(capacity int) *ByteCache {
+	c := &ByteCache{
+		seed: maphash.MakeSeed(),
+		size: capacity,
+	}
+
+	for i := range c.shards {
+		c.shards[i].index = make(map[uint64]uint64)
+		c.shards[i].ring = make([]byte, max(byteEntryHeader, capacity/byteCacheShards))
+	}
+
+	return c
+}
+
+// Get returns a copy of the value for key.
+func (c *ByteCache) Get(key string) ([]byte, bool) {
+	keyHash := maphash.String(c.seed, key)
+	shard := c.shard(keyHash)
+
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+
+	position, ok := shard.index[keyHash]
+	if !ok || !shard.valid(position) {
+		c.readMisses.Add(1)
+		return nil, false
+	}
+
+	header := shard.read(position, byteEntryHeader, nil)
+	keySize := int(binary.LittleEndian.Uint32(header[8:12]))
+	valueSize := int(binary.LittleEndian.Uint32(header[12:16]))
+
+	// Hashes are not unique, so compare the stored key.
+	if keySize != len(key) || string(shard.read(position+byteEntryHeader, keySize, nil)) != key {
+		c.readMisses.Add(1)
+		return nil, false
+	}
+
+	c.readHits.Add(1)
+
+	return shard.read(position+byteEntryHeader+uint64(keySize), valueSize, make([]byte, 0, valueSize)), true
+}
+
+// Put stores a copy of the value for key. Values which do not fit in a shard are not stored.
+func (c *ByteCache) Put(key string, value *[]byte) {
+	if value == nil {
+		return
+	}
+
+	keyHash := maphash.String(c.seed, key)
+	shard := c.shard(keyHash)
+
+	size := byteEntryHeader + len(key) + len(*value)
+	if size > len(shard.ring) {
+		return
+	}
+
+	var header [byteEntryHeader]byte
+	binary.LittleEndian.PutUint64(header[0:8], keyHash)
+	binary.LittleEndian.PutUint32(header[8:12], uint32(len(key)))
+	binary.LittleEndian.PutUint32(header[12:16], uint32(len(*value)))
+
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	c.writes.Add(1)
+
+	position := shard.head
+	shard.write(header[:])
+	shard.write([]byte(key))
+	shard.write(*value)
+	shard.index[keyHash] = position
+
+	// Once per lap around the ring, remove the entries it overwrote from the index.
+	ringSize := uint64(len(shard.ring))
+	if position/ringSize != shard.head/ringSize {
+		c.overwrites.Add(shard.prune())
+	}
+}
+
+func (c *ByteCache) Delete(key string) {
+	keyHash := maphash.String(c.seed, key)
+	shard := c.shard(keyHash)
+
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	delete(shard.index, keyHash)
+}
+
+// Len returns the number of entries, including entries overwritten during the current lap around a shard's buffer.
+func (c *ByteCache) Len() int {
+	count := 0
+
+	for i := range c.shards {
+		c.shards[i].lock.RLock()
+		count += len(c.shards[i].index)
+		c.shards[i].lock.RUnlock()
+	}
+
+	return count
+}
+
+// Cap returns the capacity in bytes.
+func (c *ByteCache) Cap() int {
+	return c.size
+}
+
+func (c *ByteCache) Metrics() Metrics {
+	metrics := Metrics{
+		ReadMisses:  c.readMisses.Load(),
+		ReadHits:    c.readHits.Load(),
+		EmptyWrites: c.writes.Load(),
+	}
+
+	metrics.Evictions[EvictionOverwritten] = c.overwrites.Load()
+
+	return metrics
+}
+
+func (c *ByteCache) shard(keyHash uint64) *byteShard {
+	return &c.shards[keyHash%byteCacheShards]
+}
+
+// valid reports whether the entry at position was not yet overwritten.
+func (s *byteShard) valid(position uint64) bool {
+	return s.head-position <= uint64(len(s.ring))
+}
+
+// read appends size bytes from position in the ring to dst, wrapping around its end.
+// If dst is nil, the bytes are returned without copying if they do not wrap.
+func (s *byteShard) read(position uint64, size int, dst []byte) []byte {
+	start := int(position % uint64(len(s.ring)))
+
+	if start+size <= len(s.ring) {
+		if dst == nil {
+			return s.ring[start : start+size]
+		}
+
+		return append(dst, s.ring[start:start+size]...)
+	}
+
+	dst = append(dst, s.ring[start:]...)
+
+	return append(dst, s.ring[:size-(len(s.ring)-start)]...)
+}
+
+// write appends data to the ring, wrapping around its end.
+func (s *byteShard) write(data []byte) {
+	start := int(s.head % uint64(len(s.ring)))
+	n := copy(s.ring[start:], data)
+	copy(s.ring, data[n:])
+
+	s.head += uint64(len(data))
+}
+
+// prune removes the entries which were overwritten from the index, and returns their number.
+func (s *byteShard) prune() uint64 {
+	var pruned uint64
+
+	for keyHash, position := range s.index {
+		if !s.valid(position) {
+			delete(s.index, keyHash)
+			pruned++
+		}
+	}
+
+	return pruned
+}
@@ -0,0 +1,63 @@
+package cache_test
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestByteCache(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewByteCache(1 << 16)
+
+	_, ok := testCache.Get("key")
+	check.True(t, !ok)
+
+	value := []byte("value")
+	testCache.Put("key", &value)
+
+	// Values are copied in, so they survive garbage collection and later changes.
+	value[0] = 'V'
+	runtime.GC()
+
+	got, ok := testCache.Get("key")
+	check.True(t, ok)
+	check.Equal(t, string(got), "value")
+
+	testCache.Delete("key")
+
+	_, ok = testCache.Get("key")
+	check.True(t, !ok)
+}
+
+func TestByteCacheOverwrite(t *testing.T) {
+	t.Parallel()
+
+	// Every shard holds 64 bytes, so it wraps around after a few entries.
+	testCache := cache.NewByteCache(64 * 64)
+
+	value := []byte("0123456789")
+
+	for i := range 10000 {
+		testCache.Put(strconv.Itoa(i), &value)
+	}
+
+	check.True(t, testCache.Len() < 10000)
+	check.True(t, testCache.Metrics().Evictions[cache.EvictionOverwritten] > 0)
+
+	// The latest entries are still intact.
+	got, ok := testCache.Get("9999")
+	check.True(t, ok)
+	check.Equal(t, string(got), "0123456789")
+
+	// Entries larger than a shard are not stored.
+	large := make([]byte, 64)
+	testCache.Put("large", &large)
+
+	_, ok = testCache.Get("large")
+	check.True(t, !ok)
+}
@@ -1,11 +1,13 @@
 package cache
 
 import (
+	"fmt"
 	"hash/maphash"
 	"math/rand/v2"
 	"runtime"
-	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 	"weak"
 )
 
@@ -13,24 +15,84 @@ import (
 // This means that the cache will be automatically cleaned up by the garbage collector.
 // The cache can only grow. However, it will reuse already claimed memory,
 // once underlying values are cleaned up.
+// Keys are found through a hash index, which is rehashed as the number of entries grows,
+// so lookups take constant time regardless of the size of the cache.
 // You can also specify a max cache size, once this size is reached and a new cache entry is put,
 // a random cache entry will be overwritten.
 type Cache[K comparable, V any] struct {
 	keyHashes   []uint64
-	values      []weak.Pointer[V]
+	entries     []cacheEntry[K, V]
+	cleanups    []runtime.Cleanup
+	index       hashIndex
+	free        []int
 	seed        maphash.Seed
 	lock        sync.RWMutex
 	maxSize     int
 	initialized bool
+	config      config[K, V]
+	equal       func(a, b *V) bool
+
+	readMisses, readHits     atomic.Uint64
+	firstWrites, emptyWrites atomic.Uint64
+	randomWrites             atomic.Uint64
+	overwrites               atomic.Uint64
+	deadReclaims             atomic.Uint64
+}
+
+// NewCache returns a cache with room for initialSize entries, which holds at most maxSize entries,
+// or any number if maxSize is 0. Of the options, it only supports WithEqualFunc, and panics with an error
+// wrapping ErrInvalidConfig if options which only apply to LockFreeCache are given.
+// Use TryNewCache or MustNewCache to also check the sizes and the arguments of the options.
+func NewCache[K comparable, V any](initialSize, maxSize int, opts ...Option[K, V]) *Cache[K, V] {
+	cfg := newConfig(opts)
+	if err := cfg.validateCacheOptions(); err != nil {
+		panic(err)
+	}
+
+	return newCache(initialSize, maxSize, maphash.MakeSeed(), cfg)
+}
+
+// TryNewCache is like NewCache, but returns an error wrapping ErrInvalidConfig on negative sizes, if maxSize
+// is smaller than initialSize, if the options are invalid, or if options which only apply to LockFreeCache are set.
+func TryNewCache[K comparable, V any](initialSize, maxSize int, opts ...Option[K, V]) (*Cache[K, V], error) {
+	switch {
+	case initialSize < 0:
+		return nil, fmt.Errorf("%w: negative initial size %d", ErrInvalidConfig, initialSize)
+	case maxSize < 0:
+		return nil, fmt.Errorf("%w: negative max size %d", ErrInvalidConfig, maxSize)
+	case maxSize != 0 && maxSize < initialSize:
+		return nil, fmt.Errorf("%w: max size %d is smaller than initial size %d", ErrInvalidConfig, maxSize, initialSize)
+	}
+
+	cfg := newConfig(opts)
+	if err := cfg.validateCache(); err != nil {
+		return nil, err
+	}
+
+	return newCache(initialSize, maxSize, maphash.MakeSeed(), cfg), nil
+}
+
+// MustNewCache is like TryNewCache, but panics with the error.
+func MustNewCache[K comparable, V any](initialSize, maxSize int, opts ...Option[K, V]) *Cache[K, V] {
+	c, err := TryNewCache(initialSize, maxSize, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
 }
 
-func NewCache[K comparable, V any](initialSize, maxSize int) *Cache[K, V] {
+func newCache[K comparable, V any](initialSize, maxSize int, seed maphash.Seed, cfg config[K, V]) *Cache[K, V] {
 	return &Cache[K, V]{
 		keyHashes:   make([]uint64, 0, initialSize),
-		values:      make([]weak.Pointer[V], 0, initialSize),
-		seed:        maphash.MakeSeed(),
+		entries:     make([]cacheEntry[K, V], 0, initialSize),
+		cleanups:    make([]runtime.Cleanup, 0, initialSize),
+		index:       newHashIndex(initialSize),
+		seed:        seed,
 		maxSize:     maxSize,
 		initialized: true,
+		config:      cfg,
+		equal:       cfg.equal(),
 	}
 }
 
@@ -39,53 +101,351 @@ func (c *Cache[K, V]) Put(key K, value *V) {
 		return
 	}
 
-	keyHash := maphash.Comparable(c.seed, key)
-	valueRef := weak.Make(value)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.put(key, value, weak.Make(value), time.Now().UnixNano())
+}
+
+// PutIfAbsent stores value for key only if no live entry exists for key, and reports whether it did.
+func (c *Cache[K, V]) PutIfAbsent(key K, value *V) bool {
+	if !c.initialized {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	index := c.find(maphash.Comparable(c.seed, key))
+	if index != -1 && c.entries[index].valueRef.Value() != nil {
+		return false
+	}
+
+	c.put(key, value, weak.Make(value), time.Now().UnixNano())
+
+	return true
+}
+
+// Swap stores value for key and returns the value it replaced, if a live one existed.
+func (c *Cache[K, V]) Swap(key K, value *V) (V, bool) {
+	if !c.initialized {
+		return *new(V), false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var old *V
+
+	if index := c.find(maphash.Comparable(c.seed, key)); index != -1 {
+		old = c.entries[index].valueRef.Value()
+	}
+
+	c.put(key, value, weak.Make(value), time.Now().UnixNano())
+
+	if old == nil {
+		return *new(V), false
+	}
+
+	return *old, true
+}
+
+// Update atomically replaces the value for key with the result of fn, which receives the current value
+// and whether it exists. If fn returns nil, the entry is deleted. Update returns the stored value.
+// The cache is locked while fn runs, so fn must not call into the cache.
+func (c *Cache[K, V]) Update(key K, fn func(current V, ok bool) *V) (V, bool) {
+	if !c.initialized {
+		return *new(V), false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var value *V
+
+	index := c.find(maphash.Comparable(c.seed, key))
+	if index != -1 {
+		value = c.entries[index].valueRef.Value()
+	}
+
+	var current V
+	if value != nil {
+		current = *value
+	}
+
+	newValue := fn(current, value != nil)
+	if newValue == nil {
+		if index != -1 {
+			c.remove(index)
+		}
+
+		return *new(V), false
+	}
+
+	c.put(key, newValue, weak.Make(newValue), time.Now().UnixNano())
+
+	return *newValue, true
+}
+
+// Delete removes the entry for key.
+func (c *Cache[K, V]) Delete(key K) {
+	if !c.initialized {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if index := c.find(maphash.Comparable(c.seed, key)); index != -1 {
+		c.remove(index)
+	}
+}
+
+// GetAndDelete removes the entry for key and returns its value, if a live one existed.
+func (c *Cache[K, V]) GetAndDelete(key K) (V, bool) {
+	if !c.initialized {
+		return *new(V), false
+	}
 
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	index := c.find(maphash.Comparable(c.seed, key))
+	if index == -1 {
+		return *new(V), false
+	}
+
+	value := c.entries[index].valueRef.Value()
+	c.remove(index)
+
+	if value == nil {
+		return *new(V), false
+	}
+
+	return *value, true
+}
+
+// CompareAndSwap stores newValue for key if the current value is equal to old.
+// Values are compared with the function set by WithEqualFunc, with == if V is comparable,
+// or otherwise by pointer identity.
+func (c *Cache[K, V]) CompareAndSwap(key K, old, newValue *V) bool {
+	if !c.initialized {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	index := c.find(maphash.Comparable(c.seed, key))
+	if index == -1 {
+		return false
+	}
+
+	value := c.entries[index].valueRef.Value()
+	if value == nil || !c.equal(value, old) {
+		return false
+	}
+
+	c.put(key, newValue, weak.Make(newValue), time.Now().UnixNano())
+
+	return true
+}
+
+// CompareAndDelete deletes the entry for key if its value is equal to old.
+// Values are compared as in CompareAndSwap.
+func (c *Cache[K, V]) CompareAndDelete(key K, old *V) bool {
+	if !c.initialized {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	index := c.find(maphash.Comparable(c.seed, key))
+	if index == -1 {
+		return false
+	}
+
+	value := c.entries[index].valueRef.Value()
+	if value == nil || !c.equal(value, old) {
+		return false
+	}
+
+	c.remove(index)
+
+	return true
+}
+
+// find returns the position of keyHash, or -1 if it is not in the cache. The caller must hold the lock.
+func (c *Cache[K, V]) find(keyHash uint64) int {
+	return c.index.find(c.keyHashes, keyHash)
+}
+
+// remove clears the slot, so its position in memory can be reused. The caller must hold the write lock.
+func (c *Cache[K, V]) remove(index int) {
+	if c.keyHashes[index] == 0 {
+		return
+	}
+
+	c.index.delete(c.keyHashes, index)
+	c.keyHashes[index] = 0
+	c.entries[index] = cacheEntry[K, V]{}
+	c.free = append(c.free, index)
+}
+
+// put stores the entry. The caller must hold the write lock.
+func (c *Cache[K, V]) put(key K, value *V, valueRef weak.Pointer[V], written int64) {
+	keyHash := maphash.Comparable(c.seed, key)
+	entry := cacheEntry[K, V]{
+		key:      key,
+		keyHash:  keyHash,
+		valueRef: valueRef,
+		written:  written,
+	}
+
 	// Find key hash in cache.
-	index := slices.Index(c.keyHashes, keyHash)
+	index := c.find(keyHash)
 	if index == -1 {
 		// Key hash does not exist yet.
-		// Check if there are any zero values.
-		zeroIndex := slices.Index(c.keyHashes, 0)
-		if zeroIndex == -1 {
-			// No zero value found
+		// Check if there are any free slots.
+		if len(c.free) == 0 {
+			// No free slot found
 			if c.maxSize != 0 && len(c.keyHashes) >= c.maxSize {
-				// The cache has reached its maximum size, generate a random index.
-				index := rand.IntN(len(c.keyHashes))
+				// The cache has reached its maximum size, pick a random unpinned index.
+				index := c.victim()
+				if index == -1 {
+					// All entries are pinned, drop the new entry.
+					return
+				}
+
+				if c.entries[index].valueRef.Value() == nil {
+					c.deadReclaims.Add(1)
+				} else {
+					c.overwrites.Add(1)
+				}
 
 				// Overwrite random cache entry.
+				c.index.delete(c.keyHashes, index)
 				c.keyHashes[index] = keyHash
-				c.values[index] = valueRef
+				c.entries[index] = entry
+				c.index.insert(c.keyHashes, index)
+				c.randomWrites.Add(1)
 
-				runtime.AddCleanup(value, c.invalidate, index)
+				c.cleanups[index] = runtime.AddCleanup(value, c.invalidate, index)
 
 				return
 			}
 
 			// Grow cache and append hash/value at the end.
 			c.keyHashes = append(c.keyHashes, keyHash)
-			c.values = append(c.values, valueRef)
+			c.entries = append(c.entries, entry)
+			c.index.insert(c.keyHashes, len(c.keyHashes)-1)
+			c.emptyWrites.Add(1)
 
-			runtime.AddCleanup(value, c.invalidate, len(c.keyHashes)-1)
+			c.cleanups = append(c.cleanups, runtime.AddCleanup(value, c.invalidate, len(c.keyHashes)-1))
 
 			return
 		}
 
-		// A zero value was found, overwrite.
-		c.keyHashes[zeroIndex] = keyHash
-		c.values[zeroIndex] = valueRef
+		// A free slot was found, overwrite.
+		freeIndex := c.free[len(c.free)-1]
+		c.free = c.free[:len(c.free)-1]
 
-		runtime.AddCleanup(value, c.invalidate, zeroIndex)
+		c.keyHashes[freeIndex] = keyHash
+		c.entries[freeIndex] = entry
+		c.index.insert(c.keyHashes, freeIndex)
+		c.emptyWrites.Add(1)
+
+		c.cleanups[freeIndex] = runtime.AddCleanup(value, c.invalidate, freeIndex)
 
 		return
 	}
 
-	// Key already exists in cache, overwrite value.
-	c.values[index] = valueRef
+	// Key already exists in cache, overwrite value. A pinned key stays pinned.
+	if c.entries[index].pinned != nil {
+		entry.pinned = value
+	}
+
+	c.entries[index] = entry
+	c.firstWrites.Add(1)
+}
+
+// victim returns a random index of an unpinned entry, or -1 if all entries are pinned.
+// An entry reclaimed by the garbage collector among the first few unpinned entries from a random start
+// is preferred over a live one. The caller must hold the lock.
+func (c *Cache[K, V]) victim() int {
+	if len(c.keyHashes) == 0 {
+		return -1
+	}
+
+	start := rand.IntN(len(c.keyHashes))
+	victim, candidates := -1, 0
+
+	for i := range len(c.keyHashes) {
+		index := (start + i) % len(c.keyHashes)
+		if c.entries[index].pinned != nil {
+			continue
+		}
+
+		if c.entries[index].valueRef.Value() == nil {
+			return index
+		}
+
+		if victim == -1 {
+			victim = index
+		}
+
+		if candidates++; candidates == randomEntrySamples {
+			break
+		}
+	}
+
+	return victim
+}
+
+// Pin holds a strong reference to the value of key, so it is never reclaimed by the garbage collector
+// nor overwritten to make room for other entries, until Unpin or Delete is called.
+// Overwriting a pinned key keeps it pinned. It reports whether a live entry was found.
+func (c *Cache[K, V]) Pin(key K) bool {
+	if !c.initialized {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	index := c.find(maphash.Comparable(c.seed, key))
+	if index == -1 {
+		return false
+	}
+
+	value := c.entries[index].valueRef.Value()
+	if value == nil {
+		return false
+	}
+
+	c.entries[index].pinned = value
+
+	return true
+}
+
+// Unpin releases the strong reference held by Pin. It reports whether the key was pinned.
+func (c *Cache[K, V]) Unpin(key K) bool {
+	if !c.initialized {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	index := c.find(maphash.Comparable(c.seed, key))
+	if index == -1 || c.entries[index].pinned == nil {
+		return false
+	}
+
+	c.entries[index].pinned = nil
+
+	return true
 }
 
 func (c *Cache[K, V]) Get(key K) (V, bool) {
@@ -95,28 +455,141 @@ func (c *Cache[K, V]) Get(key K) (V, bool) {
 	}
 
 	c.lock.RLock()
-	defer c.lock.RUnlock()
 
-	index := slices.Index(c.keyHashes, maphash.Comparable(c.seed, key))
+	index := c.find(maphash.Comparable(c.seed, key))
 	if index == -1 {
+		c.lock.RUnlock()
+		c.readMisses.Add(1)
+
 		// Key not found in cache.
 		return *new(V), false
 	}
 
-	value := c.values[index].Value()
+	value := c.entries[index].valueRef.Value()
+
+	c.lock.RUnlock()
+
 	if value == nil {
+		// Value pointer was cleaned up by garbage collector.
 		// Zero key hash, so its position in memory can be reused.
-		c.lock.Lock()
-		c.keyHashes[index] = 0
-		c.lock.Unlock()
+		c.invalidate(index)
+		c.readMisses.Add(1)
 
-		// Value pointer was cleaned up by garbage collector.
+		return *new(V), false
+	}
+
+	c.readHits.Add(1)
+
+	return *value, true
+}
+
+// GetE is like Get, but returns ErrNotInitialized or ErrNotFound instead of a boolean.
+func (c *Cache[K, V]) GetE(key K) (V, error) {
+	if !c.initialized {
+		return *new(V), ErrNotInitialized
+	}
+
+	value, ok := c.Get(key)
+	if !ok {
+		return *new(V), ErrNotFound
+	}
+
+	return value, nil
+}
+
+// Peek returns the value for key without invalidating reclaimed entries.
+// It is intended for health checks and debugging.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	if !c.initialized {
+		return *new(V), false
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	index := c.find(maphash.Comparable(c.seed, key))
+	if index == -1 {
+		return *new(V), false
+	}
+
+	value := c.entries[index].valueRef.Value()
+	if value == nil {
 		return *new(V), false
 	}
 
 	return *value, true
 }
 
+// Contains reports whether a live entry exists for key, without copying the value.
+func (c *Cache[K, V]) Contains(key K) bool {
+	if !c.initialized {
+		return false
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	index := c.find(maphash.Comparable(c.seed, key))
+
+	return index != -1 && c.entries[index].valueRef.Value() != nil
+}
+
+// GetMulti looks up multiple keys under a single lock acquisition.
+// It returns the values which were found and the keys which were missing.
+func (c *Cache[K, V]) GetMulti(keys []K) (map[K]V, []K) {
+	if !c.initialized {
+		return map[K]V{}, keys
+	}
+
+	found := make(map[K]V, len(keys))
+	missing := make([]K, 0)
+	invalid := make([]int, 0)
+
+	c.lock.RLock()
+
+	for _, key := range keys {
+		index := c.find(maphash.Comparable(c.seed, key))
+		if index == -1 {
+			missing = append(missing, key)
+			continue
+		}
+
+		value := c.entries[index].valueRef.Value()
+		if value == nil {
+			invalid = append(invalid, index)
+			missing = append(missing, key)
+
+			continue
+		}
+
+		found[key] = *value
+	}
+
+	c.lock.RUnlock()
+
+	for _, index := range invalid {
+		c.invalidate(index)
+	}
+
+	return found, missing
+}
+
+// PutMulti stores multiple entries under a single lock acquisition.
+func (c *Cache[K, V]) PutMulti(values map[K]*V) {
+	if !c.initialized {
+		return
+	}
+
+	written := time.Now().UnixNano()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key, value := range values {
+		c.put(key, value, weak.Make(value), written)
+	}
+}
+
 func (c *Cache[K, V]) Len() int {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -128,12 +601,166 @@ func (c *Cache[K, V]) Cap() int {
 	return c.maxSize
 }
 
+// Metrics returns the read and write counters of the cache.
+// Overwrites of existing keys are counted as first writes, and writes to free or new slots as empty writes.
+func (c *Cache[K, V]) Metrics() Metrics {
+	metrics := Metrics{
+		ReadMisses:   c.readMisses.Load(),
+		ReadHits:     c.readHits.Load(),
+		FirstWrites:  c.firstWrites.Load(),
+		EmptyWrites:  c.emptyWrites.Load(),
+		RandomWrites: c.randomWrites.Load(),
+		DeadReclaims: c.deadReclaims.Load(),
+	}
+
+	metrics.Evictions[EvictionOverwritten] = c.overwrites.Load()
+
+	return metrics
+}
+
+// Clone returns an independent cache with the same configuration and a copy of all live entries.
+func (c *Cache[K, V]) Clone() *Cache[K, V] {
+	if !c.initialized {
+		return &Cache[K, V]{}
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	clone := newCache(cap(c.keyHashes), c.maxSize, c.seed, c.config)
+
+	for i, keyHash := range c.keyHashes {
+		if keyHash == 0 {
+			continue
+		}
+
+		value := c.entries[i].valueRef.Value()
+		if value == nil {
+			continue
+		}
+
+		clone.keyHashes = append(clone.keyHashes, keyHash)
+		clone.entries = append(clone.entries, c.entries[i])
+		clone.index.insert(clone.keyHashes, len(clone.keyHashes)-1)
+
+		clone.cleanups = append(clone.cleanups, runtime.AddCleanup(value, clone.invalidate, len(clone.keyHashes)-1))
+	}
+
+	return clone
+}
+
+// Compact removes the free slots and the slots of reclaimed values, and moves the live entries into right-sized
+// backing arrays, so the memory of removed entries is released. It returns the number of slots which were removed.
+// Compact holds the write lock while it copies all live entries, so it is meant to be called periodically,
+// for example after many deletes, rather than on every write.
+func (c *Cache[K, V]) Compact() int {
+	if !c.initialized {
+		return 0
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	live := 0
+
+	for i, keyHash := range c.keyHashes {
+		if keyHash != 0 && c.entries[i].valueRef.Value() != nil {
+			live++
+		}
+	}
+
+	keyHashes := make([]uint64, 0, live)
+	entries := make([]cacheEntry[K, V], 0, live)
+	cleanups := make([]runtime.Cleanup, 0, live)
+
+	for i, keyHash := range c.keyHashes {
+		if keyHash == 0 {
+			continue
+		}
+
+		value := c.entries[i].valueRef.Value()
+		if value == nil {
+			continue
+		}
+
+		cleanup := c.cleanups[i]
+
+		if position := len(keyHashes); position != i {
+			// Move the cleanup along with the entry. A cleanup which already ran finds a live entry,
+			// or no slot at all, at its old position.
+			cleanup.Stop()
+			cleanup = runtime.AddCleanup(value, c.invalidate, position)
+		}
+
+		keyHashes = append(keyHashes, keyHash)
+		entries = append(entries, c.entries[i])
+		cleanups = append(cleanups, cleanup)
+	}
+
+	removed := len(c.keyHashes) - len(keyHashes)
+
+	c.keyHashes, c.entries, c.cleanups, c.free = keyHashes, entries, cleanups, nil
+	c.index.rehash(keyHashes, indexSlots(live))
+
+	return removed
+}
+
+// Merge inserts all live entries of other into the cache.
+// Conflicting keys are resolved according to the merge policy.
+// It returns the number of entries which were inserted.
+func (c *Cache[K, V]) Merge(other *Cache[K, V], policy MergePolicy) int {
+	if !c.initialized || !other.initialized {
+		return 0
+	}
+
+	type liveEntry struct {
+		entry cacheEntry[K, V]
+		value *V
+	}
+
+	// Collect live entries first, so both locks are never held at the same time.
+	other.lock.RLock()
+
+	live := make([]liveEntry, 0, len(other.entries))
+
+	for i, keyHash := range other.keyHashes {
+		if keyHash == 0 {
+			continue
+		}
+
+		if value := other.entries[i].valueRef.Value(); value != nil {
+			live = append(live, liveEntry{entry: other.entries[i], value: value})
+		}
+	}
+
+	other.lock.RUnlock()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	merged := 0
+
+	for _, l := range live {
+		index := c.find(maphash.Comparable(c.seed, l.entry.key))
+		if index != -1 && c.entries[index].valueRef.Value() != nil &&
+			!policy.replace(c.entries[index].written, l.entry.written) {
+			continue
+		}
+
+		c.put(l.entry.key, l.value, l.entry.valueRef, l.entry.written)
+		merged++
+	}
+
+	return merged
+}
+
 func (c *Cache[K, V]) invalidate(index int) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	// Check if the value is indeed nil. If not, then the cache value was already overwritten.
-	if c.values[index].Value() == nil {
-		c.keyHashes[index] = 0
+	// The slot no longer exists if the cache was compacted since.
+	if index < len(c.entries) && c.entries[index].valueRef.Value() == nil {
+		c.remove(index)
 	}
 }
@@ -0,0 +1,106 @@
+package cache
+
+import "math/bits"
+
+// hashIndexMinSlots is the number of slots of an empty hash index.
+const hashIndexMinSlots = 8
+
+// hashIndex maps the key hashes of a Cache to their position in its entries, so lookups take constant time instead
+// of scanning all key hashes. It is an open-addressed table with linear probing, which holds positions plus one,
+// so zero marks a free slot. Deletions shift the following slots back, so no tombstones accumulate, and the table
+// doubles and is rehashed once more than three quarters of its slots are used, which bounds the probe lengths.
+type hashIndex struct {
+	slots []int
+	count int
+}
+
+func newHashIndex(entries int) hashIndex {
+	return hashIndex{slots: make([]int, indexSlots(entries))}
+}
+
+// indexSlots returns the number of slots which holds entries within the load factor, as a power of two.
+func indexSlots(entries int) int {
+	return max(hashIndexMinSlots, 1<<bits.Len(uint(entries*4/3)))
+}
+
+// find returns the position of keyHash, or -1 if it is not indexed.
+func (x *hashIndex) find(keyHashes []uint64, keyHash uint64) int {
+	if keyHash == 0 {
+		return -1
+	}
+
+	mask := len(x.slots) - 1
+
+	for i := int(keyHash) & mask; x.slots[i] != 0; i = (i + 1) & mask {
+		if position := x.slots[i] - 1; keyHashes[position] == keyHash {
+			return position
+		}
+	}
+
+	return -1
+}
+
+// insert indexes the key hash at position, which must not be indexed yet.
+func (x *hashIndex) insert(keyHashes []uint64, position int) {
+	if (x.count+1)*4 > len(x.slots)*3 {
+		// The rehash indexes all key hashes, including the new one.
+		x.rehash(keyHashes, 2*len(x.slots))
+		return
+	}
+
+	x.place(keyHashes[position], position)
+	x.count++
+}
+
+// delete removes the key hash at position from the index. It must still be set in keyHashes.
+func (x *hashIndex) delete(keyHashes []uint64, position int) {
+	mask := len(x.slots) - 1
+
+	i := int(keyHashes[position]) & mask
+	for x.slots[i] != position+1 {
+		if x.slots[i] == 0 {
+			return
+		}
+
+		i = (i + 1) & mask
+	}
+
+	// Shift back the following slots which are not at their home slot, so lookups do not stop early at the gap.
+	for j := (i + 1) & mask; x.slots[j] != 0; j = (j + 1) & mask {
+		home := int(keyHashes[x.slots[j]-1]) & mask
+
+		// Move the slot into the gap, unless its home lies cyclically after the gap.
+		if (j > i && (home <= i || home > j)) || (j < i && home <= i && home > j) {
+			x.slots[i] = x.slots[j]
+			i = j
+		}
+	}
+
+	x.slots[i] = 0
+	x.count--
+}
+
+// rehash rebuilds the index with the given number of slots from all set key hashes.
+func (x *hashIndex) rehash(keyHashes []uint64, slots int) {
+	x.slots = make([]int, slots)
+	x.count = 0
+
+	for position, keyHash := range keyHashes {
+		if keyHash != 0 {
+			x.place(keyHash, position)
+			x.count++
+		}
+	}
+}
+
+// place stores position in the first free slot from the home slot of keyHash.
+func (x *hashIndex) place(keyHash uint64, position int) {
+	mask := len(x.slots) - 1
+
+	i := int(keyHash) & mask
+	for x.slots[i] != 0 {
+		i = (i + 1) & mask
+	}
+
+	x.slots[i] = position + 1
+}
@@ -2,9 +2,12 @@ package cache_test
 
 import (
 	cryptorand "crypto/rand"
+	"errors"
 	mathrand "math/rand/v2"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/samborkent/cache"
 	"github.com/samborkent/check"
@@ -53,3 +56,528 @@ func TestCache(t *testing.T) {
 	check.True(t, ok)
 	check.Equal(t, value, *object2)
 }
+
+func TestCacheClone(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+
+	object := &Object{
+		Field1: cryptorand.Text(),
+		Field2: mathrand.Int(),
+	}
+
+	key := cryptorand.Text()
+
+	store.Put(key, object)
+
+	clone := store.Clone()
+
+	value, ok := clone.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, *object)
+
+	object2 := &Object{
+		Field1: cryptorand.Text(),
+		Field2: mathrand.Int(),
+	}
+
+	key2 := cryptorand.Text()
+
+	clone.Put(key2, object2)
+
+	_, ok = store.Get(key2)
+	check.True(t, !ok)
+
+	runtime.KeepAlive(object)
+	runtime.KeepAlive(object2)
+}
+
+func TestCacheMerge(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+	other := cache.NewCache[string, Object](0, 0)
+
+	key, key2 := cryptorand.Text(), cryptorand.Text()
+
+	existing := &Object{Field1: cryptorand.Text()}
+	store.Put(key, existing)
+
+	incoming := &Object{Field1: cryptorand.Text()}
+	other.Put(key, incoming)
+
+	object2 := &Object{Field1: cryptorand.Text()}
+	other.Put(key2, object2)
+
+	check.Equal(t, store.Merge(other, cache.MergeKeepExisting), 1)
+
+	value, ok := store.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, *existing)
+
+	value, ok = store.Get(key2)
+	check.True(t, ok)
+	check.Equal(t, value, *object2)
+
+	check.Equal(t, store.Merge(other, cache.MergeKeepNewest), 1)
+
+	value, ok = store.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, *incoming)
+
+	runtime.KeepAlive(existing)
+	runtime.KeepAlive(incoming)
+	runtime.KeepAlive(object2)
+}
+
+func TestCacheMulti(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+
+	objects := map[string]*Object{
+		cryptorand.Text(): {Field1: cryptorand.Text()},
+		cryptorand.Text(): {Field1: cryptorand.Text()},
+	}
+
+	store.PutMulti(objects)
+
+	keys := []string{cryptorand.Text()}
+	for key := range objects {
+		keys = append(keys, key)
+	}
+
+	found, missing := store.GetMulti(keys)
+	check.Equal(t, len(found), len(objects))
+	check.Equal(t, len(missing), 1)
+	check.Equal(t, missing[0], keys[0])
+
+	for key, object := range objects {
+		check.Equal(t, found[key], *object)
+	}
+
+	runtime.KeepAlive(objects)
+}
+
+func TestCachePeek(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+
+	object := &Object{Field1: cryptorand.Text()}
+	key := cryptorand.Text()
+
+	store.Put(key, object)
+
+	value, ok := store.Peek(key)
+	check.True(t, ok)
+	check.Equal(t, value, *object)
+
+	_, ok = store.Peek(cryptorand.Text())
+	check.True(t, !ok)
+
+	runtime.KeepAlive(object)
+}
+
+func TestCacheContains(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+
+	object := &Object{Field1: cryptorand.Text()}
+	key := cryptorand.Text()
+
+	store.Put(key, object)
+
+	check.True(t, store.Contains(key))
+	check.True(t, !store.Contains(cryptorand.Text()))
+
+	runtime.KeepAlive(object)
+}
+
+func TestMustNewCache(t *testing.T) {
+	t.Parallel()
+
+	check.True(t, cache.MustNewCache[string, Object](1, 2) != nil)
+
+	for _, sizes := range [][2]int{{-1, 0}, {0, -1}, {4, 2}} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+			}()
+
+			cache.MustNewCache[string, Object](sizes[0], sizes[1])
+		}()
+	}
+
+	// Options which only apply to LockFreeCache are rejected.
+	for _, opt := range []cache.Option[string, Object]{
+		cache.WithCostFunc(func(string, Object) int64 { return 1 }),
+		cache.WithHooks(cache.Hooks[string, Object]{OnHit: func(string) {}}),
+		cache.WithTTL[string, Object](time.Minute),
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+			}()
+
+			cache.MustNewCache(1, 2, opt)
+		}()
+	}
+
+	check.True(t, cache.MustNewCache(1, 2, cache.WithEqualFunc[string](func(a, b Object) bool { return a == b })) != nil)
+}
+
+func TestTryNewCache(t *testing.T) {
+	t.Parallel()
+
+	testCache, err := cache.TryNewCache[string, Object](1, 2)
+	check.True(t, err == nil)
+	check.True(t, testCache != nil)
+
+	testCache, err = cache.TryNewCache(1, 2, cache.WithTTL[string, Object](time.Minute))
+	check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+	check.True(t, strings.Contains(err.Error(), "WithTTL"))
+	check.True(t, testCache == nil)
+}
+
+func TestNewCacheUnsupportedOptions(t *testing.T) {
+	t.Parallel()
+
+	check.True(t, cache.NewCache(0, 0, cache.WithEqualFunc[string](func(a, b Object) bool { return a == b })) != nil)
+
+	defer func() {
+		err, _ := recover().(error)
+		check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+	}()
+
+	cache.NewCache(0, 0, cache.WithTTL[string, Object](time.Minute))
+	t.Fatal("unsupported option was accepted")
+}
+
+func TestCacheSwap(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+
+	object := &Object{Field1: cryptorand.Text()}
+	object2 := &Object{Field1: cryptorand.Text()}
+	key := cryptorand.Text()
+
+	_, existed := store.Swap(key, object)
+	check.True(t, !existed)
+
+	old, existed := store.Swap(key, object2)
+	check.True(t, existed)
+	check.Equal(t, old, *object)
+
+	value, ok := store.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, *object2)
+
+	runtime.KeepAlive(object)
+	runtime.KeepAlive(object2)
+}
+
+func TestCacheCompareAndSwap(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+
+	object := &Object{Field1: "a"}
+	object2 := &Object{Field1: "b"}
+	key := cryptorand.Text()
+
+	check.True(t, !store.CompareAndSwap(key, object, object2))
+
+	store.Put(key, object)
+
+	check.True(t, !store.CompareAndSwap(key, object2, object2))
+	check.True(t, !store.CompareAndDelete(key, object2))
+
+	// Values are compared by value, not by pointer.
+	check.True(t, store.CompareAndSwap(key, &Object{Field1: "a"}, object2))
+
+	value, ok := store.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, *object2)
+
+	check.True(t, store.CompareAndDelete(key, object2))
+	check.True(t, !store.Contains(key))
+
+	store.Put(key, object)
+	store.Delete(key)
+	check.True(t, !store.Contains(key))
+
+	runtime.KeepAlive(object)
+	runtime.KeepAlive(object2)
+}
+
+func TestCacheUpdate(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+
+	key := cryptorand.Text()
+	retained := make([]*Object, 0)
+
+	for range 10 {
+		store.Update(key, func(current Object, _ bool) *Object {
+			next := &Object{Field2: current.Field2 + 1}
+			retained = append(retained, next)
+
+			return next
+		})
+	}
+
+	value, ok := store.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value.Field2, 10)
+
+	_, ok = store.Update(key, func(Object, bool) *Object { return nil })
+	check.True(t, !ok)
+	check.True(t, !store.Contains(key))
+
+	runtime.KeepAlive(retained)
+}
+
+func TestCachePutIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+
+	object := &Object{Field1: cryptorand.Text()}
+	object2 := &Object{Field1: cryptorand.Text()}
+	key := cryptorand.Text()
+
+	check.True(t, store.PutIfAbsent(key, object))
+	check.True(t, !store.PutIfAbsent(key, object2))
+
+	value, ok := store.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, *object)
+
+	runtime.KeepAlive(object)
+	runtime.KeepAlive(object2)
+}
+
+func TestCacheGetAndDelete(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 0)
+
+	object := &Object{Field1: cryptorand.Text()}
+	key := cryptorand.Text()
+
+	store.Put(key, object)
+
+	value, ok := store.GetAndDelete(key)
+	check.True(t, ok)
+	check.Equal(t, value, *object)
+
+	_, ok = store.GetAndDelete(key)
+	check.True(t, !ok)
+
+	runtime.KeepAlive(object)
+}
+
+func TestCachePin(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 2)
+
+	key := cryptorand.Text()
+	store.Put(key, &Object{Field2: 1})
+
+	check.True(t, store.Pin(key))
+
+	retained := make([]*Object, 0)
+
+	for range 10 {
+		object := &Object{Field1: cryptorand.Text()}
+		retained = append(retained, object)
+		store.Put(cryptorand.Text(), object)
+	}
+
+	runtime.GC()
+
+	// Pinned entries survive both garbage collection and random overwrites.
+	value, ok := store.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value.Field2, 1)
+
+	check.True(t, store.Unpin(key))
+	check.True(t, !store.Unpin(key))
+
+	runtime.KeepAlive(retained)
+}
+
+func TestCacheDeadReclaims(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[int, Object](0, 10)
+
+	// Alternate live and dead entries, so every few slots hold a reclaimed entry.
+	retained := make([]*Object, 0, 5)
+
+	for i := range 10 {
+		object := &Object{Field2: i}
+		if i%2 == 0 {
+			retained = append(retained, object)
+		}
+
+		store.Put(i, object)
+	}
+
+	runtime.GC()
+
+	object := &Object{Field2: 10}
+	store.Put(10, object)
+
+	// The new entry took the slot of a reclaimed entry, either directly or after its cleanup freed it.
+	metrics := store.Metrics()
+	check.Equal(t, metrics.Evictions[cache.EvictionOverwritten], 0)
+	check.Equal(t, metrics.DeadReclaims+metrics.EmptyWrites, 11)
+
+	for i := 0; i < 10; i += 2 {
+		_, ok := store.Get(i)
+		check.True(t, ok)
+	}
+
+	runtime.KeepAlive(retained)
+	runtime.KeepAlive(object)
+}
+
+func TestCacheGrowth(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[int, Object](0, 0)
+
+	retained := make([]*Object, 10000)
+
+	for i := range retained {
+		retained[i] = &Object{Field2: i}
+		store.Put(i, retained[i])
+	}
+
+	// Delete every other key, so the index shifts entries back and the freed slots are reused.
+	for i := 0; i < len(retained); i += 2 {
+		store.Delete(i)
+	}
+
+	for i := range retained {
+		_, ok := store.Get(i)
+		check.Equal(t, ok, i%2 == 1)
+	}
+
+	for i := 0; i < len(retained); i += 2 {
+		store.Put(i, retained[i])
+	}
+
+	check.Equal(t, store.Len(), len(retained))
+
+	for i := range retained {
+		value, ok := store.Get(i)
+		check.True(t, ok)
+		check.Equal(t, value.Field2, i)
+	}
+
+	runtime.KeepAlive(retained)
+}
+
+func TestCacheCompact(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[int, Object](0, 0)
+
+	retained := make([]*Object, 100)
+
+	for i := range retained {
+		retained[i] = &Object{Field2: i}
+		store.Put(i, retained[i])
+	}
+
+	for i := range 50 {
+		store.Delete(i)
+	}
+
+	for i := range 10 {
+		store.Put(len(retained)+i, &Object{})
+	}
+
+	runtime.GC()
+
+	check.Equal(t, store.Compact(), 50)
+	check.Equal(t, store.Len(), 50)
+	check.Equal(t, store.Compact(), 0)
+
+	for i := range retained {
+		value, ok := store.Get(i)
+		check.Equal(t, ok, i >= 50)
+
+		if ok {
+			check.Equal(t, value.Field2, i)
+		}
+	}
+
+	// The compacted cache keeps growing and reusing slots.
+	for i := range 50 {
+		store.Put(i, retained[i])
+	}
+
+	check.Equal(t, store.Len(), len(retained))
+
+	for i := range retained {
+		value, ok := store.Get(i)
+		check.True(t, ok)
+		check.Equal(t, value.Field2, i)
+	}
+
+	runtime.KeepAlive(retained)
+}
+
+func TestCacheGetE(t *testing.T) {
+	t.Parallel()
+
+	_, err := new(cache.Cache[string, uint64]).GetE("key")
+	check.True(t, errors.Is(err, cache.ErrNotInitialized))
+
+	testCache := cache.NewCache[string, uint64](0, 0)
+
+	_, err = testCache.GetE("key")
+	check.True(t, errors.Is(err, cache.ErrNotFound))
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+
+	value, err := testCache.GetE("key")
+	check.True(t, err == nil)
+	check.Equal(t, value, 1)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestCacheMetrics(t *testing.T) {
+	t.Parallel()
+
+	var testCache cache.Interface[string, uint64] = cache.NewCache[string, uint64](0, 0)
+
+	val1, val2 := uint64(1), uint64(2)
+
+	testCache.Put("key", &val1)
+	testCache.Put("key", &val2)
+	testCache.Get("key")
+	testCache.Get("missing")
+
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.EmptyWrites, 1)
+	check.Equal(t, metrics.FirstWrites, 1)
+	check.Equal(t, metrics.ReadHits, 1)
+	check.Equal(t, metrics.ReadMisses, 1)
+
+	runtime.KeepAlive(&val1)
+	runtime.KeepAlive(&val2)
+}
@@ -0,0 +1,190 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Node is a remote cache node. It is implemented by HTTPNode and grpccache.Client.
+type Node interface {
+	// Get returns the value for key, and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Put stores value for key.
+	Put(ctx context.Context, key string, value []byte) error
+	// Delete removes the value for key.
+	Delete(ctx context.Context, key string) error
+}
+
+// ErrNoNodes is returned when a cluster has no nodes.
+var ErrNoNodes = errors.New("cluster: no nodes")
+
+// Option configures a Cluster.
+type Option func(*config)
+
+type config struct {
+	virtualNodes int
+	replicas     int
+}
+
+// WithVirtualNodes sets the number of points of every node on the ring, which is DefaultVirtualNodes by default.
+// More points spread keys more evenly, at the cost of memory and lookup time.
+func WithVirtualNodes(n int) Option {
+	return func(cfg *config) {
+		cfg.virtualNodes = n
+	}
+}
+
+// WithReplication stores every key on n distinct nodes, which is 1 by default. Reads fall back to the
+// next replica when a node fails or misses, so the cluster tolerates the loss of n-1 nodes.
+func WithReplication(n int) Option {
+	return func(cfg *config) {
+		cfg.replicas = max(1, n)
+	}
+}
+
+// Cluster is a cache spread over remote nodes. Every key is stored on its nodes on a consistent hash ring,
+// so every node holds a share of the keys, and nodes can be added or removed while moving few keys.
+type Cluster struct {
+	ring     *Ring
+	replicas int
+
+	lock  sync.RWMutex
+	nodes map[string]Node
+}
+
+// New returns a cluster of nodes, by name. The names place the nodes on the ring, so all clients of
+// a cluster must use the same names, such as the addresses of the nodes.
+func New(nodes map[string]Node, opts ...Option) *Cluster {
+	cfg := config{replicas: 1}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c := &Cluster{
+		ring:     NewRing(cfg.virtualNodes),
+		replicas: cfg.replicas,
+		nodes:    make(map[string]Node, len(nodes)),
+	}
+
+	for name, node := range nodes {
+		c.Add(name, node)
+	}
+
+	return c
+}
+
+// Add adds node to the cluster, or replaces the node with the same name.
+func (c *Cluster) Add(name string, node Node) {
+	c.lock.Lock()
+	c.nodes[name] = node
+	c.lock.Unlock()
+
+	c.ring.Add(name)
+}
+
+// Remove removes the node with name from the cluster.
+func (c *Cluster) Remove(name string) {
+	c.ring.Remove(name)
+
+	c.lock.Lock()
+	delete(c.nodes, name)
+	c.lock.Unlock()
+}
+
+// Owners returns the names of the nodes which store key, starting with its primary node.
+func (c *Cluster) Owners(key string) []string {
+	return c.ring.Nodes(key, c.replicas)
+}
+
+// Get returns the value for key from the first of its nodes which has it. It only returns an error
+// if no node has it and a node failed.
+func (c *Cluster) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	nodes := c.owners(key)
+	if len(nodes) == 0 {
+		return nil, false, ErrNoNodes
+	}
+
+	var errs []error
+
+	for _, node := range nodes {
+		value, ok, err := node.node.Get(ctx, key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster: get from %s: %w", node.name, err))
+			continue
+		}
+
+		if ok {
+			return value, true, nil
+		}
+	}
+
+	return nil, false, errors.Join(errs...)
+}
+
+// Put stores value for key on all of its nodes, and returns the errors of the nodes which failed.
+func (c *Cluster) Put(ctx context.Context, key string, value []byte) error {
+	return c.each(ctx, key, "put to", func(ctx context.Context, node Node) error {
+		return node.Put(ctx, key, value)
+	})
+}
+
+// Delete removes the value for key from all of its nodes, and returns the errors of the nodes which failed.
+func (c *Cluster) Delete(ctx context.Context, key string) error {
+	return c.each(ctx, key, "delete from", func(ctx context.Context, node Node) error {
+		return node.Delete(ctx, key)
+	})
+}
+
+type namedNode struct {
+	name string
+	node Node
+}
+
+// owners returns the nodes of key.
+func (c *Cluster) owners(key string) []namedNode {
+	names := c.Owners(key)
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	nodes := make([]namedNode, 0, len(names))
+
+	for _, name := range names {
+		if node, ok := c.nodes[name]; ok {
+			nodes = append(nodes, namedNode{name: name, node: node})
+		}
+	}
+
+	return nodes
+}
+
+// each calls fn for all nodes of key concurrently.
+func (c *Cluster) each(ctx context.Context, key, action string, fn func(ctx context.Context, node Node) error) error {
+	nodes := c.owners(key)
+	if len(nodes) == 0 {
+		return ErrNoNodes
+	}
+
+	errs := make([]error, len(nodes))
+
+	var wg sync.WaitGroup
+
+	for i, node := range nodes {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := fn(ctx, node.node); err != nil {
+				errs[i] = fmt.Errorf("cluster: %s %s: %w", action, node.name, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package cluster_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/cluster"
+	"github.com/samborkent/cache/server"
+	"github.com/samborkent/check"
+)
+
+// memoryNode is a Node backed by a map, which fails while down is set.
+type memoryNode struct {
+	lock   sync.Mutex
+	values map[string][]byte
+	down   bool
+}
+
+func newMemoryNode() *memoryNode {
+	return &memoryNode{values: make(map[string][]byte)}
+}
+
+func (n *memoryNode) Get(_ context.Context, key string) ([]byte, bool, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.down {
+		return nil, false, errors.New("down")
+	}
+
+	value, ok := n.values[key]
+
+	return value, ok, nil
+}
+
+func (n *memoryNode) Put(_ context.Context, key string, value []byte) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.down {
+		return errors.New("down")
+	}
+
+	n.values[key] = value
+
+	return nil
+}
+
+func (n *memoryNode) Delete(_ context.Context, key string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.down {
+		return errors.New("down")
+	}
+
+	delete(n.values, key)
+
+	return nil
+}
+
+func (n *memoryNode) setDown(down bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.down = down
+}
+
+func TestCluster(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	_, _, err := cluster.New(nil).Get(ctx, "key")
+	check.True(t, errors.Is(err, cluster.ErrNoNodes))
+
+	nodes := map[string]*memoryNode{"a": newMemoryNode(), "b": newMemoryNode(), "c": newMemoryNode()}
+	c := cluster.New(map[string]cluster.Node{"a": nodes["a"], "b": nodes["b"], "c": nodes["c"]}, cluster.WithReplication(2))
+
+	check.True(t, c.Put(ctx, "key", []byte("value")) == nil)
+
+	owners := c.Owners("key")
+	check.Equal(t, len(owners), 2)
+
+	// Only the owners store the key.
+	for name, node := range nodes {
+		_, ok, _ := node.Get(ctx, "key")
+		check.Equal(t, ok, name == owners[0] || name == owners[1])
+	}
+
+	// Reads fall back to the replica while the primary node is down.
+	nodes[owners[0]].setDown(true)
+
+	value, ok, err := c.Get(ctx, "key")
+	check.True(t, err == nil)
+	check.True(t, ok)
+	check.Equal(t, string(value), "value")
+
+	// Writes return the errors of the failed nodes.
+	err = c.Delete(ctx, "key")
+	check.True(t, err != nil)
+
+	nodes[owners[0]].setDown(false)
+
+	value, ok, err = c.Get(ctx, "key")
+	check.True(t, err == nil)
+	check.True(t, ok)
+	check.Equal(t, string(value), "value")
+}
+
+func TestHTTPNode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	srv := httptest.NewServer(server.NewHandler(cache.NewLockFreeCache(64, cache.WithOwnedValues[string, json.RawMessage]())))
+	defer srv.Close()
+
+	node := cluster.NewHTTPNode(srv.URL, srv.Client())
+
+	_, ok, err := node.Get(ctx, "a/b")
+	check.True(t, err == nil)
+	check.True(t, !ok)
+
+	check.True(t, node.Put(ctx, "a/b", []byte(`{"x":1}`)) == nil)
+
+	value, ok, err := node.Get(ctx, "a/b")
+	check.True(t, err == nil)
+	check.True(t, ok)
+	check.Equal(t, string(value), `{"x":1}`)
+
+	// The handler rejects values which are not JSON.
+	check.True(t, node.Put(ctx, "key", []byte("value")) != nil)
+
+	check.True(t, node.Delete(ctx, "a/b") == nil)
+
+	_, ok, err = node.Get(ctx, "a/b")
+	check.True(t, err == nil)
+	check.True(t, !ok)
+}
@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTPNode is a Node served by a server.Handler. That handler stores JSON, so values must be valid JSON.
+type HTTPNode struct {
+	baseURL string
+	client  *http.Client
+}
+
+var _ Node = (*HTTPNode)(nil)
+
+// NewHTTPNode returns a node for the handler at baseURL, requested with client, or http.DefaultClient if it is nil.
+func NewHTTPNode(baseURL string, client *http.Client) *HTTPNode {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HTTPNode{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+func (n *HTTPNode) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	resp, err := n.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		value, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return value, true, nil
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, statusError(resp)
+	}
+}
+
+func (n *HTTPNode) Put(ctx context.Context, key string, value []byte) error {
+	resp, err := n.do(ctx, http.MethodPut, key, value)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return statusError(resp)
+	}
+
+	return nil
+}
+
+func (n *HTTPNode) Delete(ctx context.Context, key string) error {
+	resp, err := n.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return statusError(resp)
+	}
+
+	return nil
+}
+
+func (n *HTTPNode) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, n.baseURL+"/"+url.PathEscape(key), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return n.client.Do(req)
+}
+
+// statusError returns an error of an unexpected response, with the error message of the handler.
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(body))
+}
@@ -0,0 +1,135 @@
+// Package cluster spreads a cache over multiple remote nodes, such as servers of the server or grpccache packages,
+// by routing every key to its nodes on a consistent hash ring.
+package cluster
+
+import (
+	"cmp"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// DefaultVirtualNodes is the default number of points of every node on a Ring.
+const DefaultVirtualNodes = 128
+
+// Ring maps keys to nodes by consistent hashing. Every node is placed on the ring at a number of virtual
+// points, and a key belongs to the nodes of the first points following its hash. Adding or removing
+// a node only moves the keys next to its points, about 1/n of all keys.
+// Hashes are stable across processes, so all clients with the same nodes route keys the same way.
+type Ring struct {
+	virtualNodes int
+
+	lock   sync.RWMutex
+	points []point
+	nodes  map[string]struct{}
+}
+
+type point struct {
+	hash uint64
+	node string
+}
+
+// NewRing returns an empty ring, which places nodes at virtualNodes points, or DefaultVirtualNodes if it is not positive.
+func NewRing(virtualNodes int) *Ring {
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+
+	return &Ring{
+		virtualNodes: virtualNodes,
+		nodes:        make(map[string]struct{}),
+	}
+}
+
+// Add places node on the ring, if it is not on it already.
+func (r *Ring) Add(node string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.nodes[node]; ok {
+		return
+	}
+
+	r.nodes[node] = struct{}{}
+
+	for i := range r.virtualNodes {
+		r.points = append(r.points, point{hash: hash(node + "#" + strconv.Itoa(i)), node: node})
+	}
+
+	slices.SortFunc(r.points, func(a, b point) int {
+		if a.hash != b.hash {
+			return cmp.Compare(a.hash, b.hash)
+		}
+
+		// Order colliding points by node, so all rings agree.
+		return cmp.Compare(a.node, b.node)
+	})
+}
+
+// Remove removes node from the ring.
+func (r *Ring) Remove(node string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.nodes[node]; !ok {
+		return
+	}
+
+	delete(r.nodes, node)
+
+	r.points = slices.DeleteFunc(r.points, func(p point) bool {
+		return p.node == node
+	})
+}
+
+// Nodes returns up to n distinct nodes for key, starting with its primary node.
+func (r *Ring) Nodes(key string, n int) []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	n = min(n, len(r.nodes))
+	if n <= 0 {
+		return nil
+	}
+
+	keyHash := hash(key)
+	start, _ := slices.BinarySearchFunc(r.points, keyHash, func(p point, target uint64) int {
+		return cmp.Compare(p.hash, target)
+	})
+
+	nodes := make([]string, 0, n)
+
+	for i := 0; len(nodes) < n; i++ {
+		node := r.points[(start+i)%len(r.points)].node
+		if !slices.Contains(nodes, node) {
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes
+}
+
+// Len returns the number of nodes on the ring.
+func (r *Ring) Len() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return len(r.nodes)
+}
+
+// hash returns the FNV-1a hash of s, mixed so that similar strings spread over the ring.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+
+	// Finalizer of SplitMix64, since FNV spreads short strings with a common prefix poorly.
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,60 @@
+package cluster_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/samborkent/cache/cluster"
+	"github.com/samborkent/check"
+)
+
+func TestRing(t *testing.T) {
+	t.Parallel()
+
+	ring := cluster.NewRing(0)
+	check.Equal(t, len(ring.Nodes("key", 1)), 0)
+
+	for _, node := range []string{"a", "b", "c"} {
+		ring.Add(node)
+	}
+
+	check.Equal(t, ring.Len(), 3)
+
+	// Replicas are distinct, and capped at the number of nodes.
+	nodes := ring.Nodes("key", 5)
+	check.Equal(t, len(nodes), 3)
+	check.True(t, nodes[0] != nodes[1] && nodes[1] != nodes[2] && nodes[0] != nodes[2])
+
+	// Keys spread over all nodes, and the same ring routes keys the same way.
+	other := cluster.NewRing(0)
+	for _, node := range []string{"c", "b", "a"} {
+		other.Add(node)
+	}
+
+	const keys = 3000
+
+	counts := make(map[string]int)
+	before := make(map[string]string, keys)
+
+	for i := range keys {
+		key := strconv.Itoa(i)
+		node := ring.Nodes(key, 1)[0]
+		check.Equal(t, other.Nodes(key, 1)[0], node)
+
+		counts[node]++
+		before[key] = node
+	}
+
+	for _, count := range counts {
+		check.True(t, count > keys/6)
+	}
+
+	// Removing a node only moves its own keys.
+	ring.Remove("b")
+
+	for key, node := range before {
+		if node != "b" {
+			check.Equal(t, ring.Nodes(key, 1)[0], node)
+		}
+	}
+}
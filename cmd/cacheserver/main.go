@@ -0,0 +1,39 @@
+// Command cacheserver serves a LockFreeCache of JSON values over HTTP. See package server for the API.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/server"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	size := flag.Int("size", 1<<16, "number of cache slots")
+	ttl := flag.Duration("ttl", 0, "time to live of values, or 0 to keep them until deleted")
+	flag.Parse()
+
+	opts := []cache.Option[string, json.RawMessage]{cache.WithOwnedValues[string, json.RawMessage]()}
+	if *ttl > 0 {
+		opts = append(opts, cache.WithTTL[string, json.RawMessage](*ttl))
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.NewHandler(cache.MustNewLockFreeCache(*size, opts...)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	slog.Info("serving cache", slog.String("addr", *addr), slog.Int("size", *size))
+
+	if err := srv.ListenAndServe(); err != nil {
+		slog.Error("serving cache", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
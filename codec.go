@@ -0,0 +1,342 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Codec encodes values to bytes and back, for stores and persistence outside the Go heap.
+type Codec[V any] interface {
+	Encode(value *V) ([]byte, error)
+	Decode(data []byte) (*V, error)
+}
+
+// KeyedCodec is a Codec whose encodings depend on the encoded key of their entry, for example to authenticate
+// a value together with its key. Save, Load, append logs, replication, DiskTier and CodecCache pass the encoded key
+// to value codecs which implement it. Encode and Decode behave as for an empty key.
+type KeyedCodec[V any] interface {
+	Codec[V]
+	EncodeWithKey(key []byte, value *V) ([]byte, error)
+	DecodeWithKey(key, data []byte) (*V, error)
+}
+
+// encodeWithKey encodes value by codec, passing key if codec is a KeyedCodec.
+func encodeWithKey[V any](codec Codec[V], key []byte, value *V) ([]byte, error) {
+	if keyed, ok := codec.(KeyedCodec[V]); ok {
+		return keyed.EncodeWithKey(key, value)
+	}
+
+	return codec.Encode(value)
+}
+
+// decodeWithKey decodes data by codec, passing key if codec is a KeyedCodec.
+func decodeWithKey[V any](codec Codec[V], key, data []byte) (*V, error) {
+	if keyed, ok := codec.(KeyedCodec[V]); ok {
+		return keyed.DecodeWithKey(key, data)
+	}
+
+	return codec.Decode(data)
+}
+
+// JSONCodec encodes values as JSON.
+type JSONCodec[V any] struct{}
+
+var _ Codec[any] = JSONCodec[any]{}
+
+func (JSONCodec[V]) Encode(value *V) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec[V]) Decode(data []byte) (*V, error) {
+	value := new(V)
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// Compression compresses encoded values, as used by CompressedCodec. The standard library provides
+// FlateCompression. Faster algorithms such as snappy or zstd can be used with a small adapter around their package.
+type Compression interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// FlateCompression is a Compression using DEFLATE at the given level, as in compress/flate.
+// The zero value uses flate.DefaultCompression.
+type FlateCompression struct {
+	Level int
+}
+
+func (f FlateCompression) Compress(data []byte) ([]byte, error) {
+	level := f.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+
+	var b bytes.Buffer
+
+	w, err := flate.NewWriter(&b, level)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func (FlateCompression) Decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// Encodings of CompressedCodec are prefixed with one of these markers.
+const (
+	encodingRaw        = 0
+	encodingCompressed = 1
+)
+
+// compressedCodec compresses the encodings of codec of at least threshold bytes.
+type compressedCodec[V any] struct {
+	codec       Codec[V]
+	compression Compression
+	threshold   int
+}
+
+// CompressedCodec returns a codec which compresses the encodings of codec of at least threshold bytes,
+// trading CPU for memory on large values. Smaller encodings are stored as is, with a marker byte.
+func CompressedCodec[V any](codec Codec[V], compression Compression, threshold int) Codec[V] {
+	return compressedCodec[V]{codec: codec, compression: compression, threshold: threshold}
+}
+
+func (c compressedCodec[V]) Encode(value *V) ([]byte, error) {
+	return c.EncodeWithKey(nil, value)
+}
+
+// EncodeWithKey passes key on to the wrapped codec, if it is a KeyedCodec.
+func (c compressedCodec[V]) EncodeWithKey(key []byte, value *V) ([]byte, error) {
+	data, err := encodeWithKey(c.codec, key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < c.threshold {
+		return append([]byte{encodingRaw}, data...), nil
+	}
+
+	compressed, err := c.compression.Compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("cache: compress: %w", err)
+	}
+
+	return append([]byte{encodingCompressed}, compressed...), nil
+}
+
+func (c compressedCodec[V]) Decode(data []byte) (*V, error) {
+	return c.DecodeWithKey(nil, data)
+}
+
+// DecodeWithKey passes key on to the wrapped codec, if it is a KeyedCodec.
+func (c compressedCodec[V]) DecodeWithKey(key, data []byte) (*V, error) {
+	if len(data) == 0 {
+		return nil, errors.New("cache: decode: empty encoding")
+	}
+
+	switch data[0] {
+	case encodingRaw:
+		return decodeWithKey(c.codec, key, data[1:])
+	case encodingCompressed:
+		decompressed, err := c.compression.Decompress(data[1:])
+		if err != nil {
+			return nil, fmt.Errorf("cache: decompress: %w", err)
+		}
+
+		return decodeWithKey(c.codec, key, decompressed)
+	default:
+		return nil, fmt.Errorf("cache: decode: unknown encoding %d", data[0])
+	}
+}
+
+// CodecCache stores values encoded by a codec in a ByteCache, for example with CompressedCodec to keep large
+// values compressed in memory. Values which fail to encode are not stored, and values which fail to decode miss.
+type CodecCache[V any] struct {
+	cache *ByteCache
+	codec Codec[V]
+}
+
+var _ Interface[string, any] = (*CodecCache[any])(nil)
+
+// NewCodecCache returns a cache of values encoded by codec in c.
+func NewCodecCache[V any](c *ByteCache, codec Codec[V]) *CodecCache[V] {
+	return &CodecCache[V]{cache: c, codec: codec}
+}
+
+func (c *CodecCache[V]) Get(key string) (V, bool) {
+	data, ok := c.cache.Get(key)
+	if !ok {
+		return *new(V), false
+	}
+
+	value, err := decodeWithKey(c.codec, []byte(key), data)
+	if err != nil {
+		return *new(V), false
+	}
+
+	return *value, true
+}
+
+func (c *CodecCache[V]) Put(key string, value *V) {
+	data, err := encodeWithKey(c.codec, []byte(key), value)
+	if err != nil {
+		return
+	}
+
+	c.cache.Put(key, &data)
+}
+
+func (c *CodecCache[V]) Delete(key string) {
+	c.cache.Delete(key)
+}
+
+func (c *CodecCache[V]) Len() int {
+	return c.cache.Len()
+}
+
+// Cap returns the capacity in bytes of the underlying ByteCache.
+func (c *CodecCache[V]) Cap() int {
+	return c.cache.Cap()
+}
+
+func (c *CodecCache[V]) Metrics() Metrics {
+	return c.cache.Metrics()
+}
+
+// EncryptionKey is an AES key, and the ID which identifies it in the encodings of EncryptedCodec.
+type EncryptionKey struct {
+	ID  byte
+	Key []byte
+}
+
+// encryptedCodec seals the encodings of codec with AES-GCM. Encodings are the ID of the sealing key,
+// the nonce and the sealed encoding.
+type encryptedCodec[V any] struct {
+	codec   Codec[V]
+	context string
+	id      byte
+	aeads   map[byte]cipher.AEAD
+}
+
+// EncryptedCodec returns a codec which encrypts the encodings of codec with AES-GCM, so values written to disk
+// by Save, an AppendLog or a DiskTier are encrypted at rest. Every key must be 16, 24 or 32 bytes, to select
+// AES-128, AES-192 or AES-256, and have a distinct ID. Values are sealed with the first key, and the ID of the
+// sealing key prefixes every encoding, so the other keys still decode values sealed before a key rotation.
+//
+// Every encoding gets a random nonce, and is authenticated on decode together with context, such as the name
+// of the cache, and the encoded key of its entry, so encodings cannot be moved to another cache or key.
+func EncryptedCodec[V any](codec Codec[V], context string, keys ...EncryptionKey) (KeyedCodec[V], error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no encryption key", ErrInvalidConfig)
+	}
+
+	aeads := make(map[byte]cipher.AEAD, len(keys))
+
+	for _, key := range keys {
+		if _, ok := aeads[key.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate encryption key ID %d", ErrInvalidConfig, key.ID)
+		}
+
+		block, err := aes.NewCipher(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+
+		aeads[key.ID] = aead
+	}
+
+	return encryptedCodec[V]{codec: codec, context: context, id: keys[0].ID, aeads: aeads}, nil
+}
+
+func (c encryptedCodec[V]) Encode(value *V) ([]byte, error) {
+	return c.EncodeWithKey(nil, value)
+}
+
+func (c encryptedCodec[V]) EncodeWithKey(key []byte, value *V) ([]byte, error) {
+	data, err := encodeWithKey(c.codec, key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	aead := c.aeads[c.id]
+
+	sealed := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(data)+aead.Overhead())
+	sealed[0] = c.id
+
+	nonce := sealed[1:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("cache: nonce: %w", err)
+	}
+
+	return aead.Seal(sealed, nonce, data, c.additionalData(c.id, key)), nil
+}
+
+func (c encryptedCodec[V]) Decode(data []byte) (*V, error) {
+	return c.DecodeWithKey(nil, data)
+}
+
+func (c encryptedCodec[V]) DecodeWithKey(key, data []byte) (*V, error) {
+	if len(data) == 0 {
+		return nil, errors.New("cache: decrypt: empty encoding")
+	}
+
+	aead, ok := c.aeads[data[0]]
+	if !ok {
+		return nil, fmt.Errorf("cache: decrypt: unknown key ID %d", data[0])
+	}
+
+	if len(data) < 1+aead.NonceSize() {
+		return nil, errors.New("cache: decrypt: encoding too short")
+	}
+
+	nonce, sealed := data[1:1+aead.NonceSize()], data[1+aead.NonceSize():]
+
+	decrypted, err := aead.Open(nil, nonce, sealed, c.additionalData(data[0], key))
+	if err != nil {
+		return nil, fmt.Errorf("cache: decrypt: %w", err)
+	}
+
+	return decodeWithKey(c.codec, key, decrypted)
+}
+
+// additionalData returns the data which is authenticated with an encoding: the key ID, the length
+// of the context, the context and the encoded key of the entry.
+func (c encryptedCodec[V]) additionalData(id byte, key []byte) []byte {
+	data := make([]byte, 0, 1+binary.MaxVarintLen64+len(c.context)+len(key))
+	data = append(data, id)
+	data = binary.AppendUvarint(data, uint64(len(c.context)))
+	data = append(data, c.context...)
+
+	return append(data, key...)
+}
@@ -0,0 +1,166 @@
+package cache_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestCompressedCodec(t *testing.T) {
+	t.Parallel()
+
+	codec := cache.CompressedCodec[string](cache.JSONCodec[string]{}, cache.FlateCompression{}, 64)
+
+	for _, value := range []string{"small", strings.Repeat("large ", 100)} {
+		data, err := codec.Encode(&value)
+		check.True(t, err == nil)
+
+		decoded, err := codec.Decode(data)
+		check.True(t, err == nil)
+		check.Equal(t, *decoded, value)
+	}
+
+	// Large values are compressed.
+	large := strings.Repeat("large ", 100)
+
+	data, err := codec.Encode(&large)
+	check.True(t, err == nil)
+	check.True(t, len(data) < len(large))
+
+	_, err = codec.Decode([]byte{2})
+	check.True(t, err != nil)
+}
+
+func TestCodecCache(t *testing.T) {
+	t.Parallel()
+
+	codec := cache.CompressedCodec[[]string](cache.JSONCodec[[]string]{}, cache.FlateCompression{}, 64)
+	testCache := cache.NewCodecCache(cache.NewByteCache(1<<16), codec)
+
+	value := []string{strings.Repeat("a", 100), "b"}
+	testCache.Put("key", &value)
+
+	got, ok := testCache.Get("key")
+	check.True(t, ok)
+	check.Equal(t, len(got), 2)
+	check.Equal(t, got[0], value[0])
+}
+
+func TestEncryptedCodec(t *testing.T) {
+	t.Parallel()
+
+	_, err := cache.EncryptedCodec[string](cache.JSONCodec[string]{}, "test", cache.EncryptionKey{Key: []byte("short")})
+	check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+
+	_, err = cache.EncryptedCodec[string](cache.JSONCodec[string]{}, "test")
+	check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+
+	key := cache.EncryptionKey{ID: 1, Key: bytes.Repeat([]byte{1}, 32)}
+
+	_, err = cache.EncryptedCodec[string](cache.JSONCodec[string]{}, "test", key, key)
+	check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+
+	codec, err := cache.EncryptedCodec[string](cache.JSONCodec[string]{}, "test", key)
+	check.True(t, err == nil)
+
+	value := "secret"
+
+	data, err := codec.EncodeWithKey([]byte("key"), &value)
+	check.True(t, err == nil)
+	check.True(t, !bytes.Contains(data, []byte(value)))
+
+	// Encodings are prefixed with the ID of the sealing key.
+	check.Equal(t, data[0], key.ID)
+
+	decoded, err := codec.DecodeWithKey([]byte("key"), data)
+	check.True(t, err == nil)
+	check.Equal(t, *decoded, value)
+
+	// Encodings are bound to their key and context.
+	_, err = codec.DecodeWithKey([]byte("other"), data)
+	check.True(t, err != nil)
+
+	otherContext, err := cache.EncryptedCodec[string](cache.JSONCodec[string]{}, "other", key)
+	check.True(t, err == nil)
+
+	_, err = otherContext.DecodeWithKey([]byte("key"), data)
+	check.True(t, err != nil)
+
+	// Tampered encodings fail to decode.
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-1] ^= 1
+
+	_, err = codec.DecodeWithKey([]byte("key"), tampered)
+	check.True(t, err != nil)
+
+	// So do encodings sealed with an unknown key.
+	next := cache.EncryptionKey{ID: 2, Key: bytes.Repeat([]byte{2}, 32)}
+
+	rotated, err := cache.EncryptedCodec[string](cache.JSONCodec[string]{}, "test", next, key)
+	check.True(t, err == nil)
+
+	nextData, err := rotated.EncodeWithKey([]byte("key"), &value)
+	check.True(t, err == nil)
+	check.Equal(t, nextData[0], next.ID)
+
+	_, err = codec.DecodeWithKey([]byte("key"), nextData)
+	check.True(t, err != nil)
+
+	// After a rotation, values sealed with the previous key still decode.
+	decoded, err = rotated.DecodeWithKey([]byte("key"), data)
+	check.True(t, err == nil)
+	check.Equal(t, *decoded, value)
+}
+
+func TestEncryptedCodecSnapshot(t *testing.T) {
+	t.Parallel()
+
+	codec, err := cache.EncryptedCodec[string](cache.JSONCodec[string]{}, "test", cache.EncryptionKey{Key: bytes.Repeat([]byte{1}, 16)})
+	check.True(t, err == nil)
+
+	source := cache.NewLockFreeCache[string, string](8)
+
+	value := "secret"
+	source.Put("key", &value)
+	check.True(t, source.Pin("key"))
+
+	var b bytes.Buffer
+	check.True(t, source.Save(&b, cache.JSONCodec[string]{}, codec) == nil)
+	check.True(t, !bytes.Contains(b.Bytes(), []byte(value)))
+
+	target := cache.NewLockFreeCache[string, string](8)
+	check.True(t, target.Load(&b, cache.JSONCodec[string]{}, codec) == nil)
+
+	got, ok := target.Get("key")
+	check.True(t, ok)
+	check.Equal(t, got, value)
+}
+
+func TestEncryptedCodecCache(t *testing.T) {
+	t.Parallel()
+
+	codec, err := cache.EncryptedCodec[string](cache.JSONCodec[string]{}, "test", cache.EncryptionKey{Key: bytes.Repeat([]byte{1}, 16)})
+	check.True(t, err == nil)
+
+	byteCache := cache.NewByteCache(1 << 16)
+	testCache := cache.NewCodecCache(byteCache, codec)
+
+	value := "secret"
+	testCache.Put("key", &value)
+
+	got, ok := testCache.Get("key")
+	check.True(t, ok)
+	check.Equal(t, got, value)
+
+	// An encoding copied to another key fails to decode, as it is bound to its key.
+	data, ok := byteCache.Get("key")
+	check.True(t, ok)
+	byteCache.Put("other", &data)
+
+	_, ok = testCache.Get("other")
+	check.True(t, !ok)
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"time"
+	"weak"
+)
+
+// costSweepInterval is the default interval between sweeps for entries whose value was reclaimed.
+const costSweepInterval = time.Second
+
+func (c *LockFreeCache[K, V]) startCostSweep(interval time.Duration) {
+	if interval <= 0 {
+		interval = costSweepInterval
+	}
+
+	cache, stop := weak.Make(c), c.life.stop
+	c.life.goroutine(func() { costSweepLoop(cache, interval, stop) })
+}
+
+// costSweepLoop sweeps the table of the cache every interval. It only holds a weak reference between
+// intervals, so it does not keep the cache alive.
+func costSweepLoop[K comparable, V any](cache weak.Pointer[LockFreeCache[K, V]], interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c := cache.Value()
+			if c == nil {
+				return
+			}
+
+			c.sweepReclaimed()
+		}
+	}
+}
+
+// sweepReclaimed invalidates all entries whose value was reclaimed, which moves their cost
+// from Metrics.CurrentCost to Metrics.ReclaimedCost.
+func (c *LockFreeCache[K, V]) sweepReclaimed() {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	for index := range c.size {
+		entry := c.slot(index).Load()
+		if entry != nil && entry.keyHash != 0 && entry.valueRef.Value() == nil {
+			c.invalidate(entry, index)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"hash/maphash"
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// CounterCache is a fixed-size cache of integer counters, for rate limiting and statistics aggregation.
+// Counters are stored inline in atomic slots, so unlike LockFreeCache it does not rely on weak pointers
+// and counters are never reclaimed by the garbage collector.
+// Keys are identified by their 64-bit hash. Once all slots within the probe depth of a key are in use,
+// a random counter is evicted. Concurrent updates of an evicted counter may be lost.
+type CounterCache[K comparable] struct {
+	slots          []counterSlot
+	seed           maphash.Seed
+	prober         Prober
+	hashProbeDepth int
+}
+
+type counterSlot struct {
+	keyHash atomic.Uint64
+	value   atomic.Int64
+}
+
+func NewCounterCache[K comparable](size int) *CounterCache[K] {
+	if size <= 0 {
+		return &CounterCache[K]{}
+	}
+
+	return &CounterCache[K]{
+		slots:          make([]counterSlot, size),
+		seed:           maphash.MakeSeed(),
+		prober:         NewProber(size),
+		hashProbeDepth: max(1, int(math.Log2(float64(size)))),
+	}
+}
+
+// Add adds delta to the counter for key, and returns the new value.
+func (c *CounterCache[K]) Add(key K, delta int64) int64 {
+	if len(c.slots) == 0 {
+		return 0
+	}
+
+	slot, claimed := c.claim(c.hash(key))
+	if !claimed {
+		slot.value.Store(delta)
+		return delta
+	}
+
+	return slot.value.Add(delta)
+}
+
+// Store sets the counter for key to value.
+func (c *CounterCache[K]) Store(key K, value int64) {
+	if len(c.slots) == 0 {
+		return
+	}
+
+	slot, _ := c.claim(c.hash(key))
+	slot.value.Store(value)
+}
+
+// claim returns the slot holding keyHash, claiming an empty one if needed. Otherwise a random counter
+// within the probe depth is evicted, and claim reports false, as the slot still holds the evicted value.
+func (c *CounterCache[K]) claim(keyHash uint64) (*counterSlot, bool) {
+	for i := range c.hashProbeDepth {
+		slot := &c.slots[c.prober.Index(keyHash, i)]
+
+		current := slot.keyHash.Load()
+		if current == keyHash {
+			return slot, true
+		}
+
+		// Claim an empty slot, unless another goroutine claimed it first.
+		if current == 0 && (slot.keyHash.CompareAndSwap(0, keyHash) || slot.keyHash.Load() == keyHash) {
+			return slot, true
+		}
+	}
+
+	// Evict a random counter within the probe depth.
+	slot := &c.slots[c.prober.Index(keyHash, rand.IntN(c.hashProbeDepth))]
+	slot.keyHash.Store(keyHash)
+
+	return slot, false
+}
+
+// Load returns the counter for key, and whether it exists.
+func (c *CounterCache[K]) Load(key K) (int64, bool) {
+	if slot := c.find(key); slot != nil {
+		return slot.value.Load(), true
+	}
+
+	return 0, false
+}
+
+// Delete removes the counter for key.
+func (c *CounterCache[K]) Delete(key K) {
+	if slot := c.find(key); slot != nil {
+		slot.value.Store(0)
+		slot.keyHash.CompareAndSwap(c.hash(key), 0)
+	}
+}
+
+// Len returns the number of counters.
+func (c *CounterCache[K]) Len() int {
+	count := 0
+
+	for i := range c.slots {
+		if c.slots[i].keyHash.Load() != 0 {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (c *CounterCache[K]) Cap() int {
+	return len(c.slots)
+}
+
+func (c *CounterCache[K]) find(key K) *counterSlot {
+	if len(c.slots) == 0 {
+		return nil
+	}
+
+	keyHash := c.hash(key)
+
+	for i := range c.hashProbeDepth {
+		slot := &c.slots[c.prober.Index(keyHash, i)]
+		if slot.keyHash.Load() == keyHash {
+			return slot
+		}
+	}
+
+	return nil
+}
+
+// hash returns the key hash, where zero is reserved for empty slots.
+func (c *CounterCache[K]) hash(key K) uint64 {
+	return max(1, maphash.Comparable(c.seed, key))
+}
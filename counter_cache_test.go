@@ -0,0 +1,63 @@
+package cache_test
+
+import (
+	cryptorand "crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestCounterCache(t *testing.T) {
+	t.Parallel()
+
+	counters := cache.NewCounterCache[string](N / 100)
+
+	key := cryptorand.Text()
+
+	_, ok := counters.Load(key)
+	check.True(t, !ok)
+
+	var wg sync.WaitGroup
+
+	for range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 1000 {
+				counters.Add(key, 2)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	value, ok := counters.Load(key)
+	check.True(t, ok)
+	check.Equal(t, value, 16000)
+	check.Equal(t, counters.Add(key, -16000), 0)
+	check.Equal(t, counters.Len(), 1)
+
+	counters.Delete(key)
+
+	_, ok = counters.Load(key)
+	check.True(t, !ok)
+	check.Equal(t, counters.Len(), 0)
+}
+
+func TestCounterCacheStore(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewCounterCache[string](N / 100)
+
+	testCache.Store("key", 42)
+
+	value, ok := testCache.Load("key")
+	check.True(t, ok)
+	check.Equal(t, value, 42)
+
+	check.Equal(t, testCache.Add("key", 1), 43)
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"cmp"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"slices"
+)
+
+// maxDebugCollisions is the number of most contended home slots listed by the debug handler.
+const maxDebugCollisions = 10
+
+type debugInfo struct {
+	Size       int     `json:"size"`
+	ProbeDepth int     `json:"probeDepth"`
+	Metrics    Metrics `json:"metrics"`
+
+	Live  int `json:"live"`
+	Dead  int `json:"dead"`
+	Empty int `json:"empty"`
+
+	// ProbeDepths counts the live entries by the probe index of their slot.
+	ProbeDepths []int `json:"probeDepths"`
+	// OutsideWindow counts the live entries which were stored outside their probe window.
+	OutsideWindow int `json:"outsideWindow"`
+
+	Collisions []debugCollision `json:"collisions"`
+}
+
+// debugCollision is a home slot, the first probe index, shared by the hashes of multiple live entries.
+type debugCollision struct {
+	Slot    int `json:"slot"`
+	Entries int `json:"entries"`
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head><title>cache</title></head>
+<body>
+<h1>cache</h1>
+<table>
+<tr><th align="left">Size</th><td>{{.Size}}</td></tr>
+<tr><th align="left">Probe depth</th><td>{{.ProbeDepth}}</td></tr>
+<tr><th align="left">Live</th><td>{{.Live}}</td></tr>
+<tr><th align="left">Dead</th><td>{{.Dead}}</td></tr>
+<tr><th align="left">Empty</th><td>{{.Empty}}</td></tr>
+<tr><th align="left">Read hits</th><td>{{.Metrics.ReadHits}}</td></tr>
+<tr><th align="left">Read misses</th><td>{{.Metrics.ReadMisses}}</td></tr>
+<tr><th align="left">Probe overflows</th><td>{{.Metrics.ProbeOverflows}}</td></tr>
+<tr><th align="left">Outside window</th><td>{{.OutsideWindow}}</td></tr>
+</table>
+<h2>Probe depths</h2>
+<table>
+<tr><th>Depth</th><th>Entries</th></tr>
+{{range $depth, $entries := .ProbeDepths}}<tr><td>{{$depth}}</td><td>{{$entries}}</td></tr>
+{{end}}</table>
+<h2>Collisions</h2>
+<table>
+<tr><th>Slot</th><th>Entries</th></tr>
+{{range .Collisions}}<tr><td>{{.Slot}}</td><td>{{.Entries}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// DebugHandler returns a handler serving the metrics, occupancy, probe depth distribution and
+// most contended slots of the cache, for example mounted under /debug/cache.
+// It serves JSON, or HTML if the format query parameter is "html".
+// Each request scans the whole cache.
+func DebugHandler[K comparable, V any](c *LockFreeCache[K, V]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := c.debugInfo()
+
+		if r.URL.Query().Get("format") == "html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_ = debugTemplate.Execute(w, info)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(info)
+	})
+}
+
+func (c *LockFreeCache[K, V]) debugInfo() debugInfo {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	depth := c.probeDepth()
+
+	info := debugInfo{
+		Size:        c.size,
+		ProbeDepth:  depth,
+		Metrics:     c.Metrics(),
+		ProbeDepths: make([]int, depth),
+		Collisions:  []debugCollision{},
+	}
+
+	homes := make(map[int]int)
+
+	for index := range c.size {
+		entry := c.slot(index).Load()
+
+		switch {
+		case entry == nil || entry.keyHash == 0:
+			info.Empty++
+			continue
+		case entry.valueRef.Value() == nil:
+			info.Dead++
+			continue
+		}
+
+		info.Live++
+		homes[c.index(entry.keyHash, 0)]++
+
+		position := c.probePosition(entry.keyHash, index, depth)
+		if position == -1 {
+			info.OutsideWindow++
+			continue
+		}
+
+		info.ProbeDepths[position]++
+	}
+
+	for slot, entries := range homes {
+		if entries > 1 {
+			info.Collisions = append(info.Collisions, debugCollision{Slot: slot, Entries: entries})
+		}
+	}
+
+	slices.SortFunc(info.Collisions, func(a, b debugCollision) int {
+		return cmp.Or(cmp.Compare(b.Entries, a.Entries), cmp.Compare(a.Slot, b.Slot))
+	})
+
+	if len(info.Collisions) > maxDebugCollisions {
+		info.Collisions = info.Collisions[:maxDebugCollisions]
+	}
+
+	return info
+}
+
+// probePosition returns the probe index at which keyHash maps to index, or -1 if index is outside its probe window
+// of depth.
+func (c *LockFreeCache[K, V]) probePosition(keyHash uint64, index, depth int) int {
+	for i := range depth {
+		if c.index(keyHash, i) == index {
+			return i
+		}
+	}
+
+	return -1
+}
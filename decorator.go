@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"hash/maphash"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// InstrumentedCache wraps an implementation of Interface, such as a third-party cache backend,
+// counting its operations in Metrics and invoking hooks for them.
+type InstrumentedCache[K comparable, V any] struct {
+	inner Interface[K, V]
+	hooks Hooks[K, V]
+
+	readMisses, readHits atomic.Uint64
+	writes, deletes      atomic.Uint64
+}
+
+var _ Interface[string, any] = (*InstrumentedCache[string, any])(nil)
+
+// Instrument wraps inner, invoking the OnHit, OnMiss, OnInsert and OnEvict hooks for its operations.
+// Evictions by inner are not observed, so OnEvict is only called for Delete, without the deleted value.
+func Instrument[K comparable, V any](inner Interface[K, V], hooks Hooks[K, V]) *InstrumentedCache[K, V] {
+	return &InstrumentedCache[K, V]{
+		inner: inner,
+		hooks: hooks,
+	}
+}
+
+func (c *InstrumentedCache[K, V]) Get(key K) (V, bool) {
+	value, ok := c.inner.Get(key)
+
+	if ok {
+		c.readHits.Add(1)
+
+		if c.hooks.OnHit != nil {
+			c.hooks.OnHit(key)
+		}
+	} else {
+		c.readMisses.Add(1)
+
+		if c.hooks.OnMiss != nil {
+			c.hooks.OnMiss(key)
+		}
+	}
+
+	return value, ok
+}
+
+func (c *InstrumentedCache[K, V]) Put(key K, value *V) {
+	c.inner.Put(key, value)
+	c.writes.Add(1)
+
+	if c.hooks.OnInsert != nil {
+		c.hooks.OnInsert(key, value)
+	}
+}
+
+func (c *InstrumentedCache[K, V]) Delete(key K) {
+	c.inner.Delete(key)
+	c.deletes.Add(1)
+
+	if c.hooks.OnEvict != nil {
+		c.hooks.OnEvict(key, nil, EvictionDeleted)
+	}
+}
+
+func (c *InstrumentedCache[K, V]) Len() int {
+	return c.inner.Len()
+}
+
+func (c *InstrumentedCache[K, V]) Cap() int {
+	return c.inner.Cap()
+}
+
+// Metrics returns the operations counted by the wrapper. The metrics of the wrapped cache are not included,
+// as they count the same operations; use its own Metrics for them. As the write path of the wrapped cache
+// is unknown, every Put is counted as a first write.
+func (c *InstrumentedCache[K, V]) Metrics() Metrics {
+	metrics := Metrics{
+		ReadMisses:  c.readMisses.Load(),
+		ReadHits:    c.readHits.Load(),
+		FirstWrites: c.writes.Load(),
+	}
+
+	metrics.Evictions[EvictionDeleted] = c.deletes.Load()
+
+	return metrics
+}
+
+// LoggedCache wraps an implementation of Interface, logging a sample of its operations at debug level.
+type LoggedCache[K comparable, V any] struct {
+	inner  Interface[K, V]
+	logger *slog.Logger
+	seed   maphash.Seed
+	every  uint64
+	ops    atomic.Uint64
+}
+
+var _ Interface[string, any] = (*LoggedCache[string, any])(nil)
+
+// Log wraps inner, logging one in every calls to Put, Get and Delete with the key hash, outcome and duration.
+// Keys are logged as hashes, so they do not leak into logs, but equal keys can still be correlated.
+// If every is not positive, all calls are logged.
+func Log[K comparable, V any](inner Interface[K, V], logger *slog.Logger, every int) *LoggedCache[K, V] {
+	return &LoggedCache[K, V]{
+		inner:  inner,
+		logger: logger,
+		seed:   maphash.MakeSeed(),
+		every:  uint64(max(1, every)),
+	}
+}
+
+// sampled reports whether the current call should be logged.
+func (c *LoggedCache[K, V]) sampled() bool {
+	return c.logger.Enabled(context.Background(), slog.LevelDebug) && c.ops.Add(1)%c.every == 0
+}
+
+func (c *LoggedCache[K, V]) log(op string, key K, outcome string, start time.Time) {
+	c.logger.Debug("cache "+op,
+		slog.Uint64("keyHash", maphash.Comparable(c.seed, key)),
+		slog.String("outcome", outcome),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+func (c *LoggedCache[K, V]) Get(key K) (V, bool) {
+	if !c.sampled() {
+		return c.inner.Get(key)
+	}
+
+	start := time.Now()
+	value, ok := c.inner.Get(key)
+
+	outcome := "miss"
+	if ok {
+		outcome = "hit"
+	}
+
+	c.log("get", key, outcome, start)
+
+	return value, ok
+}
+
+func (c *LoggedCache[K, V]) Put(key K, value *V) {
+	if !c.sampled() {
+		c.inner.Put(key, value)
+		return
+	}
+
+	start := time.Now()
+	c.inner.Put(key, value)
+	c.log("put", key, "stored", start)
+}
+
+func (c *LoggedCache[K, V]) Delete(key K) {
+	if !c.sampled() {
+		c.inner.Delete(key)
+		return
+	}
+
+	start := time.Now()
+	c.inner.Delete(key)
+	c.log("delete", key, "deleted", start)
+}
+
+func (c *LoggedCache[K, V]) Len() int {
+	return c.inner.Len()
+}
+
+func (c *LoggedCache[K, V]) Cap() int {
+	return c.inner.Cap()
+}
+
+func (c *LoggedCache[K, V]) Metrics() Metrics {
+	return c.inner.Metrics()
+}
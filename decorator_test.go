@@ -0,0 +1,110 @@
+package cache_test
+
+import (
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+// mapCache is a minimal third-party cache without metrics.
+type mapCache[K comparable, V any] struct {
+	values sync.Map
+}
+
+func (c *mapCache[K, V]) Get(key K) (V, bool) {
+	value, ok := c.values.Load(key)
+	if !ok {
+		return *new(V), false
+	}
+
+	return *value.(*V), true
+}
+
+func (c *mapCache[K, V]) Put(key K, value *V) { c.values.Store(key, value) }
+func (c *mapCache[K, V]) Delete(key K)        { c.values.Delete(key) }
+func (c *mapCache[K, V]) Len() int            { return 0 }
+func (c *mapCache[K, V]) Cap() int            { return 0 }
+func (c *mapCache[K, V]) Metrics() cache.Metrics {
+	return cache.Metrics{}
+}
+
+func TestInstrument(t *testing.T) {
+	t.Parallel()
+
+	var hits, inserts, deletes int
+
+	testCache := cache.Instrument[string, uint64](&mapCache[string, uint64]{}, cache.Hooks[string, uint64]{
+		OnInsert: func(string, *uint64) { inserts++ },
+		OnEvict: func(_ string, _ *uint64, reason cache.EvictionReason) {
+			if reason == cache.EvictionDeleted {
+				deletes++
+			}
+		},
+		OnHit: func(string) { hits++ },
+	})
+
+	val := uint64(1)
+
+	testCache.Put("key", &val)
+	testCache.Get("key")
+	testCache.Delete("key")
+	testCache.Get("key")
+
+	check.Equal(t, inserts, 1)
+	check.Equal(t, hits, 1)
+	check.Equal(t, deletes, 1)
+
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.ReadHits, 1)
+	check.Equal(t, metrics.ReadMisses, 1)
+	check.Equal(t, metrics.TotalWrites(), 1)
+	check.Equal(t, metrics.Evictions[cache.EvictionDeleted], 1)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestInstrumentLockFreeCache(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.Instrument[string, uint64](cache.NewLockFreeCache[string, uint64](16), cache.Hooks[string, uint64]{})
+
+	val := uint64(1)
+
+	testCache.Put("key", &val)
+	testCache.Get("key")
+
+	// Operations are counted once, by the wrapper, and not again by the wrapped cache.
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.ReadHits, 1)
+	check.Equal(t, metrics.TotalWrites(), 1)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+
+	var logs strings.Builder
+
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	testCache := cache.Log[string, uint64](cache.NewLockFreeCache[string, uint64](16), logger, 2)
+
+	val := uint64(1)
+
+	testCache.Put("key", &val)
+	testCache.Get("key")
+	testCache.Get("key")
+	testCache.Delete("key")
+
+	// Every second call is logged.
+	check.Equal(t, strings.Count(logs.String(), "\n"), 2)
+	check.True(t, strings.Contains(logs.String(), "outcome=hit"))
+	check.True(t, strings.Contains(logs.String(), "outcome=deleted"))
+
+	runtime.KeepAlive(&val)
+}
@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// diskSlotHeader is the size of the header of a disk tier slot: the key hash, where zero marks
+	// an empty slot, the expiry in Unix nanoseconds, and the lengths of the encoded key and value.
+	diskSlotHeader = 24
+	// diskProbeDepth is the number of slots a key can be stored in.
+	diskProbeDepth = 4
+	// diskLockStripes is the number of locks guarding the slots.
+	diskLockStripes = 64
+)
+
+// DiskTier is a fixed-size hash table of encoded entries in a memory-mapped file, for datasets larger than memory.
+// Used with WithOverflow, it holds the entries which left the in-memory table. As keys are hashed with a random
+// seed, the file is scratch space which is truncated on open; use Save or an AppendLog for persistence.
+// Entries which do not fit in a slot are not stored, and counted by TooLarge.
+type DiskTier[K comparable, V any] struct {
+	file     *os.File
+	data     []byte
+	slots    int
+	slotSize int
+	seed     maphash.Seed
+	prober   Prober
+	keys     Codec[K]
+	values   Codec[V]
+	closed   bool
+	tooLarge atomic.Uint64
+
+	locks [diskLockStripes]sync.RWMutex
+}
+
+// OpenDiskTier creates the file at path, truncating it if it exists, and maps slots slots of slotSize bytes each.
+// A slot holds a header of 24 bytes, and the encoded key and value.
+func OpenDiskTier[K comparable, V any](path string, slots, slotSize int, keys Codec[K], values Codec[V]) (*DiskTier[K, V], error) {
+	if slots <= 0 || slotSize <= diskSlotHeader {
+		return nil, fmt.Errorf("%w: %d slots of %d bytes", ErrInvalidConfig, slots, slotSize)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := file.Truncate(int64(slots) * int64(slotSize)); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	data, err := mmap(file, slots*slotSize)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &DiskTier[K, V]{
+		file:     file,
+		data:     data,
+		slots:    slots,
+		slotSize: slotSize,
+		seed:     maphash.MakeSeed(),
+		prober:   NewProber(slots),
+		keys:     keys,
+		values:   values,
+	}, nil
+}
+
+// Get returns the value for key, and whether a live entry was found.
+func (t *DiskTier[K, V]) Get(key K) (*V, bool) {
+	value, _, ok := t.get(key)
+	return value, ok
+}
+
+// get returns the value for key, its expiry in Unix nanoseconds, and whether a live entry was found.
+func (t *DiskTier[K, V]) get(key K) (*V, int64, bool) {
+	keyHash, encodedKey, err := t.hash(key)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	for i := range diskProbeDepth {
+		index := t.prober.Index(keyHash, i)
+		lock := t.lock(index)
+
+		lock.RLock()
+
+		if t.closed {
+			lock.RUnlock()
+			return nil, 0, false
+		}
+
+		slot := t.slot(index)
+		if !t.matches(slot, keyHash, encodedKey) {
+			lock.RUnlock()
+			continue
+		}
+
+		expires := int64(binary.LittleEndian.Uint64(slot[8:16]))
+		if expires != 0 && time.Now().UnixNano() >= expires {
+			lock.RUnlock()
+			return nil, 0, false
+		}
+
+		keySize := binary.LittleEndian.Uint32(slot[16:20])
+		valueSize := binary.LittleEndian.Uint32(slot[20:24])
+		encodedValue := bytes.Clone(slot[diskSlotHeader+keySize : diskSlotHeader+keySize+valueSize])
+
+		lock.RUnlock()
+
+		value, err := decodeWithKey(t.values, encodedKey, encodedValue)
+		if err != nil {
+			return nil, 0, false
+		}
+
+		return value, expires, true
+	}
+
+	return nil, 0, false
+}
+
+// Put stores value for key, overwriting another entry if all slots for key are in use.
+func (t *DiskTier[K, V]) Put(key K, value *V) {
+	t.put(key, value, 0)
+}
+
+func (t *DiskTier[K, V]) put(key K, value *V, expires int64) {
+	keyHash, encodedKey, err := t.hash(key)
+	if err != nil {
+		return
+	}
+
+	encodedValue, err := encodeWithKey(t.values, encodedKey, value)
+	if err != nil {
+		return
+	}
+
+	if diskSlotHeader+len(encodedKey)+len(encodedValue) > t.slotSize {
+		t.tooLarge.Add(1)
+		return
+	}
+
+	// Overwrite the slot of the same key, or the first empty one, or else a slot chosen by the key hash.
+	target := t.prober.Index(keyHash, int(keyHash>>32)%diskProbeDepth)
+
+	for i := range diskProbeDepth {
+		index := t.prober.Index(keyHash, i)
+		lock := t.lock(index)
+
+		lock.RLock()
+		slot := t.slot(index)
+		found := !t.closed && t.matches(slot, keyHash, encodedKey)
+		empty := !t.closed && binary.LittleEndian.Uint64(slot[0:8]) == 0
+		lock.RUnlock()
+
+		if found || empty {
+			target = index
+
+			if found {
+				break
+			}
+		}
+	}
+
+	lock := t.lock(target)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if t.closed {
+		return
+	}
+
+	slot := t.slot(target)
+	binary.LittleEndian.PutUint64(slot[0:8], keyHash)
+	binary.LittleEndian.PutUint64(slot[8:16], uint64(expires))
+	binary.LittleEndian.PutUint32(slot[16:20], uint32(len(encodedKey)))
+	binary.LittleEndian.PutUint32(slot[20:24], uint32(len(encodedValue)))
+	copy(slot[diskSlotHeader:], encodedKey)
+	copy(slot[diskSlotHeader+len(encodedKey):], encodedValue)
+}
+
+// Delete removes the entry for key.
+func (t *DiskTier[K, V]) Delete(key K) {
+	keyHash, encodedKey, err := t.hash(key)
+	if err != nil {
+		return
+	}
+
+	for i := range diskProbeDepth {
+		index := t.prober.Index(keyHash, i)
+		lock := t.lock(index)
+
+		lock.Lock()
+
+		if !t.closed && t.matches(t.slot(index), keyHash, encodedKey) {
+			binary.LittleEndian.PutUint64(t.slot(index)[0:8], 0)
+		}
+
+		lock.Unlock()
+	}
+}
+
+// TooLarge returns the number of entries which were not stored, as they did not fit in a slot.
+func (t *DiskTier[K, V]) TooLarge() uint64 {
+	return t.tooLarge.Load()
+}
+
+// Close unmaps and closes the file. Later calls miss, and writes are dropped.
+func (t *DiskTier[K, V]) Close() error {
+	for i := range t.locks {
+		t.locks[i].Lock()
+		defer t.locks[i].Unlock()
+	}
+
+	if t.closed {
+		return nil
+	}
+
+	t.closed = true
+
+	return errors.Join(munmap(t.data), t.file.Close())
+}
+
+// hash returns the key hash, where zero is reserved for empty slots, and the encoded key.
+func (t *DiskTier[K, V]) hash(key K) (uint64, []byte, error) {
+	encoded, err := t.keys.Encode(&key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return max(1, maphash.Comparable(t.seed, key)), encoded, nil
+}
+
+func (t *DiskTier[K, V]) slot(index int) []byte {
+	return t.data[index*t.slotSize : (index+1)*t.slotSize]
+}
+
+func (t *DiskTier[K, V]) lock(index int) *sync.RWMutex {
+	return &t.locks[index%diskLockStripes]
+}
+
+// matches reports whether slot holds the entry of keyHash and encodedKey.
+func (t *DiskTier[K, V]) matches(slot []byte, keyHash uint64, encodedKey []byte) bool {
+	if binary.LittleEndian.Uint64(slot[0:8]) != keyHash {
+		return false
+	}
+
+	keySize := binary.LittleEndian.Uint32(slot[16:20])
+
+	return int(keySize) == len(encodedKey) && bytes.Equal(slot[diskSlotHeader:diskSlotHeader+int(keySize)], encodedKey)
+}
@@ -0,0 +1,72 @@
+package cache_test
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestDiskTier(t *testing.T) {
+	t.Parallel()
+
+	tier, err := cache.OpenDiskTier(filepath.Join(t.TempDir(), "tier"), 64, 64, cache.JSONCodec[string]{}, cache.JSONCodec[string]{})
+	check.True(t, err == nil)
+
+	defer tier.Close()
+
+	_, ok := tier.Get("key")
+	check.True(t, !ok)
+
+	value := "value"
+	tier.Put("key", &value)
+
+	got, ok := tier.Get("key")
+	check.True(t, ok)
+	check.Equal(t, *got, "value")
+
+	tier.Delete("key")
+
+	_, ok = tier.Get("key")
+	check.True(t, !ok)
+
+	// Entries which do not fit in a slot are not stored.
+	large := strings.Repeat("x", 64)
+	tier.Put("large", &large)
+
+	_, ok = tier.Get("large")
+	check.True(t, !ok)
+	check.Equal(t, tier.TooLarge(), 1)
+}
+
+func TestLockFreeCacheOverflow(t *testing.T) {
+	t.Parallel()
+
+	tier, err := cache.OpenDiskTier(filepath.Join(t.TempDir(), "tier"), 64, 64, cache.JSONCodec[string]{}, cache.JSONCodec[uint64]{})
+	check.True(t, err == nil)
+
+	defer tier.Close()
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithOverflow(tier))
+
+	func() {
+		val := uint64(1)
+		testCache.Put("key", &val)
+	}()
+
+	// The value is reclaimed, but is promoted back from the overflow tier.
+	runtime.GC()
+
+	value, ok := testCache.Get("key")
+	check.True(t, ok)
+	check.Equal(t, value, 1)
+	check.Equal(t, testCache.Metrics().OverflowHits, 1)
+
+	testCache.Delete("key")
+
+	_, ok = tier.Get("key")
+	check.True(t, !ok)
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+const (
+	// doorkeeperHashes is the number of bits set per key, which with doorkeeperBitsPerKey bits per remembered key
+	// gives a false positive rate of about 1%.
+	doorkeeperHashes     = 4
+	doorkeeperBitsPerKey = 10
+)
+
+// doorkeeper is a bloom filter of the key hashes which were recently written, so new keys are only admitted into
+// the cache on their second write. It is cleared once it recorded its capacity of keys, so it only remembers
+// recent keys and its false positive rate stays bounded.
+// Concurrent writes may both record a key as new, or miss a clear, which only admits or rejects a write early.
+type doorkeeper struct {
+	words     []atomic.Uint64
+	mask      uint64
+	capacity  uint64
+	additions atomic.Uint64
+}
+
+func newDoorkeeper(capacity int) *doorkeeper {
+	size := max(64, 1<<bits.Len(uint(capacity*doorkeeperBitsPerKey-1)))
+
+	return &doorkeeper{
+		words:    make([]atomic.Uint64, size/64),
+		mask:     uint64(size - 1),
+		capacity: uint64(capacity),
+	}
+}
+
+// admit reports whether keyHash was recorded since the filter was last cleared, and records it otherwise.
+func (d *doorkeeper) admit(keyHash uint64) bool {
+	// Double hashing derives the bit positions from the two halves of the hash.
+	step := bits.RotateLeft64(keyHash, 32) | 1
+	seen := true
+
+	for i := range uint64(doorkeeperHashes) {
+		bit := (keyHash + i*step) & d.mask
+
+		word, mask := &d.words[bit/64], uint64(1)<<(bit%64)
+		if word.Load()&mask == 0 {
+			word.Or(mask)
+			seen = false
+		}
+	}
+
+	if seen {
+		return true
+	}
+
+	if d.additions.Add(1) == d.capacity {
+		for i := range d.words {
+			d.words[i].Store(0)
+		}
+
+		d.additions.Store(0)
+	}
+
+	return false
+}
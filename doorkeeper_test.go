@@ -0,0 +1,46 @@
+package cache_test
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheDoorkeeper(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(1024, cache.WithDoorkeeper[string, uint64](1024))
+
+	values := make([]uint64, 100)
+
+	// The first put of a key is rejected, the second admitted, and later puts update the entry.
+	testCache.Put("key", &values[0])
+	check.True(t, !testCache.Contains("key"))
+	check.Equal(t, testCache.Metrics().DoorkeeperRejects, 1)
+
+	testCache.Put("key", &values[0])
+	check.True(t, testCache.Contains("key"))
+
+	testCache.Put("key", &values[1])
+	check.Equal(t, testCache.Metrics().DoorkeeperRejects, 1)
+
+	for i := range values {
+		testCache.Put("hot"+strconv.Itoa(i), &values[i])
+		testCache.Put("hot"+strconv.Itoa(i), &values[i])
+	}
+
+	// A scan of keys which are put once only admits the false positives of the filter.
+	scan := make([]uint64, 10000)
+	for i := range scan {
+		testCache.Put("scan"+strconv.Itoa(i), &scan[i])
+	}
+
+	check.True(t, testCache.Len() < 3*len(values))
+	check.True(t, testCache.Metrics().DoorkeeperRejects > uint64(len(scan))*9/10)
+
+	runtime.KeepAlive(values)
+	runtime.KeepAlive(scan)
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EntryInfo describes an entry, as returned by GetEntryInfo.
+type EntryInfo struct {
+	// Written is the time the value was stored.
+	Written time.Time
+	// LastAccess is the time of the last hit, or the zero time if there was none.
+	// It is only tracked with WithAccessTracking.
+	LastAccess time.Time
+	// Hits is the number of hits of the entry. It is only tracked with WithAccessTracking.
+	Hits uint64
+	// Expires is the time the entry expires, or the zero time if it never expires.
+	Expires time.Time
+	// TTL is the time left until the entry expires, or 0 if it expired or never expires.
+	TTL time.Duration
+	// Expired reports whether the entry expired, as kept with WithStaleWhileRevalidate.
+	Expired bool
+	// Reachable reports whether the value was not reclaimed by the garbage collector yet.
+	Reachable bool
+	// Pinned reports whether the value is pinned.
+	Pinned bool
+	// Cost is the cost of the entry, as set by WithCostFunc or WithWeigher.
+	Cost int64
+	// Version is the version of the entry, as returned by GetVersioned.
+	Version Version
+}
+
+// entryAccess tracks the accesses of an entry.
+type entryAccess struct {
+	last atomic.Int64
+	hits atomic.Uint64
+}
+
+func (a *entryAccess) record() {
+	a.last.Store(time.Now().UnixNano())
+	a.hits.Add(1)
+}
+
+// GetEntryInfo returns information about the entry for key, for debugging and tuning. Unlike Get, it also
+// returns entries which expired or whose value was reclaimed, until their slot is cleared. It does not count
+// as an access, and has no side effects on metrics or entries.
+func (c *LockFreeCache[K, V]) GetEntryInfo(key K) (EntryInfo, bool) {
+	if !c.initialized.Load() {
+		return EntryInfo{}, false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	keyHash := c.hash(key)
+
+	for i := range c.probeDepth() {
+		entry := c.slot(c.index(keyHash, i)).Load()
+		if entry == nil || entry.keyHash != keyHash || entry.key != key || c.outdated(entry) {
+			continue
+		}
+
+		info := EntryInfo{
+			Written:   time.Unix(0, entry.written),
+			Expired:   c.expired(entry),
+			Reachable: entry.valueRef.Value() != nil,
+			Pinned:    entry.pinned != nil,
+			Cost:      entry.cost,
+			Version:   Version{v: entry.version},
+		}
+
+		if entry.expires != 0 {
+			info.Expires = time.Unix(0, entry.expires)
+			info.TTL = max(0, time.Until(info.Expires))
+		}
+
+		if entry.access != nil {
+			if last := entry.access.last.Load(); last != 0 {
+				info.LastAccess = time.Unix(0, last)
+			}
+
+			info.Hits = entry.access.hits.Load()
+		}
+
+		return info, true
+	}
+
+	return EntryInfo{}, false
+}
@@ -0,0 +1,52 @@
+package cache_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheGetEntryInfo(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithTTL[string, uint64](time.Hour),
+		cache.WithAccessTracking[string, uint64](),
+	)
+
+	_, ok := testCache.GetEntryInfo("key")
+	check.True(t, !ok)
+
+	before := time.Now()
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+
+	info, ok := testCache.GetEntryInfo("key")
+	check.True(t, ok)
+	check.True(t, !info.Written.Before(before))
+	check.True(t, info.LastAccess.IsZero())
+	check.Equal(t, info.Hits, 0)
+	check.True(t, info.TTL > 59*time.Minute && info.TTL <= time.Hour)
+	check.True(t, info.Reachable)
+	check.True(t, !info.Pinned)
+	check.True(t, !info.Expired)
+
+	_, _ = testCache.Get("key")
+	_, _ = testCache.Get("key")
+	check.True(t, testCache.Pin("key"))
+
+	// Access stats are kept when the entry is amended.
+	info, _ = testCache.GetEntryInfo("key")
+	check.Equal(t, info.Hits, 2)
+	check.True(t, !info.LastAccess.Before(info.Written))
+	check.True(t, info.Pinned)
+
+	_, version, _ := testCache.GetVersioned("key")
+	check.Equal(t, info.Version, version)
+
+	runtime.KeepAlive(&val)
+}
@@ -0,0 +1,21 @@
+package cache
+
+import "errors"
+
+// ErrInvalidConfig is returned when a cache is configured with invalid arguments or options.
+var ErrInvalidConfig = errors.New("cache: invalid configuration")
+
+var (
+	// ErrNotFound is returned when no live entry exists for a key.
+	ErrNotFound = errors.New("cache: not found")
+	// ErrNotInitialized is returned when a cache was not created by one of its constructors, or with an invalid size.
+	ErrNotInitialized = errors.New("cache: not initialized")
+	// ErrExpired is returned when the entry for a key has expired.
+	ErrExpired = errors.New("cache: expired")
+	// ErrClosed is returned when using a cache which was closed.
+	ErrClosed = errors.New("cache: closed")
+	// ErrInvalidSnapshot is returned when loading a snapshot which is incomplete, corrupt, or of an unsupported version.
+	ErrInvalidSnapshot = errors.New("cache: invalid snapshot")
+	// ErrLoaderPanic is returned to all calls waiting for a load which panicked.
+	ErrLoaderPanic = errors.New("cache: loader panicked")
+)
@@ -0,0 +1,68 @@
+package cache
+
+import "iter"
+
+// FrozenView is a read-only view of a LockFreeCache. It shares the storage of the cache, so it sees later writes
+// to the cache, but has no methods to write to it. To hand out contents which do not change, freeze a Clone.
+type FrozenView[K comparable, V any] struct {
+	cache *LockFreeCache[K, V]
+}
+
+// Freeze returns a read-only view of the cache, for code which must not modify it, such as request handlers.
+func (c *LockFreeCache[K, V]) Freeze() *FrozenView[K, V] {
+	return &FrozenView[K, V]{cache: c}
+}
+
+// Get is like LockFreeCache.Get.
+func (v *FrozenView[K, V]) Get(key K) (V, bool) {
+	return v.cache.Get(key)
+}
+
+// Len is like LockFreeCache.Len.
+func (v *FrozenView[K, V]) Len() int {
+	return v.cache.Len()
+}
+
+// All is like LockFreeCache.All.
+func (v *FrozenView[K, V]) All() iter.Seq2[K, V] {
+	return v.cache.All()
+}
+
+// All returns an iterator over the keys and values of the live entries, in table order. Entries which are written
+// during the iteration may or may not be yielded. It takes time proportional to the size of the cache.
+func (c *LockFreeCache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if !c.initialized.Load() {
+			return
+		}
+
+		for index := range c.size {
+			key, value, ok := c.live(index)
+			if !ok {
+				continue
+			}
+
+			if !yield(key, *value) {
+				return
+			}
+		}
+	}
+}
+
+// live returns the key and value of the live entry in the slot at index, if any. The entry itself is not returned,
+// as it may be recycled once removed, while the caller still yields it.
+func (c *LockFreeCache[K, V]) live(index int) (K, *V, bool) {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	entry := c.slot(index).Load()
+	if entry == nil || entry.keyHash == 0 || c.expired(entry) {
+		return *new(K), nil, false
+	}
+
+	value := entry.valueRef.Value()
+	if value == nil {
+		return *new(K), nil, false
+	}
+
+	return entry.key, value, true
+}
@@ -0,0 +1,55 @@
+package cache_test
+
+import (
+	cryptorand "crypto/rand"
+	mathrand "math/rand/v2"
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheFreeze(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	values := make(map[string]*uint64)
+	for range 10 {
+		val := mathrand.Uint64()
+		values[cryptorand.Text()] = &val
+	}
+
+	testCache.PutMulti(values)
+
+	view := testCache.Freeze()
+	check.Equal(t, view.Len(), len(values))
+
+	for key, value := range values {
+		got, ok := view.Get(key)
+		check.True(t, ok)
+		check.Equal(t, got, *value)
+	}
+
+	seen := 0
+
+	for key, value := range view.All() {
+		check.Equal(t, value, *values[key])
+		seen++
+	}
+
+	check.Equal(t, seen, len(values))
+
+	// The view shares the storage of the cache.
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+	testCache.Put(key, &val)
+
+	got, ok := view.Get(key)
+	check.True(t, ok)
+	check.Equal(t, got, val)
+
+	runtime.KeepAlive(values)
+	runtime.KeepAlive(&val)
+}
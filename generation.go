@@ -0,0 +1,48 @@
+package cache
+
+// Generation returns the current generation of the cache, which is recorded by every entry written.
+func (c *LockFreeCache[K, V]) Generation() uint64 {
+	return c.generation.Load()
+}
+
+// NextGeneration starts a new generation, and returns it.
+func (c *LockFreeCache[K, V]) NextGeneration() uint64 {
+	return c.generation.Add(1)
+}
+
+// InvalidateAllBefore makes all entries written before generation gen read as misses, and starts generation gen
+// if it is later than the current generation. It takes constant time: the table is not scanned, and the slots
+// of invalidated entries are reused by later writes. Entries in an overflow tier are not invalidated.
+func (c *LockFreeCache[K, V]) InvalidateAllBefore(gen uint64) {
+	for {
+		current := c.generation.Load()
+		if current >= gen || c.generation.CompareAndSwap(current, gen) {
+			break
+		}
+	}
+
+	for {
+		oldest := c.oldestGeneration.Load()
+		if oldest >= gen || c.oldestGeneration.CompareAndSwap(oldest, gen) {
+			return
+		}
+	}
+}
+
+// InvalidateAll makes all current entries read as misses, in constant time, as InvalidateAllBefore.
+// It is a cheap flush, for example to bust the cache on a deploy.
+func (c *LockFreeCache[K, V]) InvalidateAll() {
+	c.InvalidateAllBefore(c.NextGeneration())
+}
+
+// outdated reports whether the generation of the entry was invalidated.
+func (c *LockFreeCache[K, V]) outdated(entry *cacheEntry[K, V]) bool {
+	return entry.generation < c.oldestGeneration.Load()
+}
+
+// InvalidateAll makes all current entries of all shards read as misses, as LockFreeCache.InvalidateAll.
+func (c *ShardedCache[K, V]) InvalidateAll() {
+	for _, shard := range c.shards {
+		shard.InvalidateAll()
+	}
+}
@@ -0,0 +1,55 @@
+package cache_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheInvalidateAll(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+	check.Equal(t, testCache.Generation(), 0)
+
+	val1, val2 := uint64(1), uint64(2)
+	testCache.Put("key1", &val1)
+	testCache.Put("key2", &val1)
+	check.True(t, testCache.Pin("key2"))
+
+	testCache.InvalidateAll()
+	check.Equal(t, testCache.Generation(), 1)
+
+	// Entries of older generations read as misses, including pinned entries.
+	_, ok := testCache.Get("key1")
+	check.True(t, !ok)
+	check.True(t, !testCache.Contains("key2"))
+
+	testCache.Put("key1", &val2)
+
+	value, ok := testCache.Get("key1")
+	check.True(t, ok)
+	check.Equal(t, value, 2)
+
+	// Invalidating before a later generation also starts it.
+	testCache.InvalidateAllBefore(5)
+	check.Equal(t, testCache.Generation(), 5)
+	check.True(t, !testCache.Contains("key1"))
+
+	// Invalidating before an earlier generation has no effect.
+	testCache.Put("key1", &val2)
+	testCache.InvalidateAllBefore(3)
+	check.True(t, testCache.Contains("key1"))
+
+	sharded := cache.NewShardedCache[string, uint64](4, N/100)
+	sharded.Put("key1", &val1)
+	sharded.InvalidateAll()
+
+	_, ok = sharded.Get("key1")
+	check.True(t, !ok)
+
+	runtime.KeepAlive(&val1)
+	runtime.KeepAlive(&val2)
+}
@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// ghostList remembers the hashes of recently evicted keys, so misses of keys which a bigger cache would have kept
+// can be counted. It is a direct-mapped table, in which a later eviction replaces an earlier one with the same slot,
+// so it holds about as many keys as it has slots without storing keys or values.
+type ghostList struct {
+	slots []atomic.Uint64
+	mask  uint64
+}
+
+func newGhostList(keys int) *ghostList {
+	size := 1 << bits.Len(uint(keys-1))
+
+	return &ghostList{
+		slots: make([]atomic.Uint64, size),
+		mask:  uint64(size - 1),
+	}
+}
+
+func (g *ghostList) add(keyHash uint64) {
+	g.slots[keyHash&g.mask].Store(keyHash)
+}
+
+// remove forgets keyHash, and reports whether it was remembered, so every eviction is counted at most once.
+func (g *ghostList) remove(keyHash uint64) bool {
+	slot := &g.slots[keyHash&g.mask]
+
+	return keyHash != 0 && slot.Load() == keyHash && slot.CompareAndSwap(keyHash, 0)
+}
@@ -0,0 +1,47 @@
+package cache_test
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheGhostTracking(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(16, cache.WithGhostTracking[string, uint64](1024))
+
+	values := make([]uint64, 256)
+	for i := range values {
+		testCache.Put(strconv.Itoa(i), &values[i])
+	}
+
+	// Keys which were never stored are plain misses.
+	_, ok := testCache.Get("unknown")
+	check.True(t, !ok)
+	check.Equal(t, testCache.Metrics().GhostHits, 0)
+
+	misses := 0
+
+	for i := range values {
+		if _, ok := testCache.Get(strconv.Itoa(i)); !ok {
+			misses++
+		}
+	}
+
+	// Most missed keys were overwritten to make room, and each eviction counts once.
+	metrics := testCache.Metrics()
+	check.True(t, metrics.GhostHits > uint64(misses)/2)
+	check.True(t, metrics.GhostHits <= uint64(misses))
+
+	for i := range values {
+		testCache.Get(strconv.Itoa(i))
+	}
+
+	check.Equal(t, testCache.Metrics().GhostHits, metrics.GhostHits)
+
+	runtime.KeepAlive(values)
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"iter"
+	"math/bits"
+	"sync/atomic"
+)
+
+const (
+	// groupSize is the number of slots in a probe group, with a control byte each.
+	groupSize = 16
+	// groupWords is the number of control words of a group.
+	groupWords = groupSize / 8
+	// groupProbes is the minimum number of groups in a probe sequence.
+	groupProbes = 4
+
+	controlLSB = 0x0101010101010101
+	controlMSB = 0x8080808080808080
+)
+
+// controlGroups lays out the slots of a LockFreeCache in groups of groupSize consecutive slots, in the style of
+// SwissTable. Every slot has a control byte, which holds the top bits of the hash of its key, or zero if it is empty.
+// Probe sequences visit whole groups, and readers compare the control bytes of a group with the hash of their key
+// eight at a time, so they only load the entries of slots which likely hold their key.
+//
+// Control bytes are hints. Writers update them after swapping a slot, so a reader which races with a write may skip
+// the slot of a key which was just written, as it may without groups once it passed the slot.
+type controlGroups struct {
+	// prober generates the probe sequences over groups.
+	prober   Prober
+	controls []atomic.Uint64
+}
+
+func newControlGroups(size int) *controlGroups {
+	groups := size / groupSize
+
+	return &controlGroups{
+		prober:   NewProber(groups),
+		controls: make([]atomic.Uint64, groups*groupWords),
+	}
+}
+
+// index returns the slot index of the i-th probe for keyHash. Probes visit the slots of a group in order,
+// before moving on to the next group.
+func (g *controlGroups) index(keyHash uint64, i int) int {
+	return g.prober.Index(keyHash, i/groupSize)*groupSize + i%groupSize
+}
+
+// match returns a mask of the slots in the group of the i-th probe for keyHash, whose control byte matches keyHash.
+// It may include slots which do not match, but never excludes slots which do.
+func (g *controlGroups) match(keyHash uint64, i int) uint16 {
+	word := g.prober.Index(keyHash, i/groupSize) * groupWords
+	tag := controlTag(keyHash)
+
+	return uint16(matchControls(g.controls[word].Load(), tag)) |
+		uint16(matchControls(g.controls[word+1].Load(), tag))<<8
+}
+
+// set sets the control byte of the slot at index to tag.
+func (g *controlGroups) set(index int, tag uint8) {
+	word := &g.controls[index/8]
+	shift := uint(index%8) * 8
+
+	for {
+		current := word.Load()
+
+		updated := current&^(0xff<<shift) | uint64(tag)<<shift
+		if current == updated || word.CompareAndSwap(current, updated) {
+			return
+		}
+	}
+}
+
+// updateControl sets the control byte of the slot at index to match its current entry. It is repeated until the
+// entry did not change meanwhile, so concurrent writers leave the control byte of the last entry.
+func (c *LockFreeCache[K, V]) updateControl(slot *tableSlot[K, V], index int) {
+	for {
+		entry := slot.Load()
+
+		var tag uint8
+		if entry != nil && entry.keyHash != 0 {
+			tag = controlTag(entry.keyHash)
+		}
+
+		c.groups.set(index, tag)
+
+		if slot.Load() == entry {
+			return
+		}
+	}
+}
+
+// controlTag returns the control byte for keyHash. It takes seven bits below the bits which select the probe step,
+// so keys which share a probe sequence still differ in their control bytes. Its top bit is set, so it is never zero.
+func controlTag(keyHash uint64) uint8 {
+	return uint8(keyHash>>(64-proberStepBits-8)) | 0x80
+}
+
+// matchControls returns a mask of the control bytes in word which equal tag, with a bit per byte.
+// A byte after a matching byte may be included falsely.
+func matchControls(word uint64, tag uint8) uint8 {
+	x := word ^ controlLSB*uint64(tag)
+	zeros := (x - controlLSB) &^ x & controlMSB
+
+	// Gather the top bit of every byte into the low byte.
+	return uint8(((zeros >> 7) * 0x0102040810204080) >> 56)
+}
+
+// index returns the slot index of the i-th probe for keyHash.
+func (c *LockFreeCache[K, V]) index(keyHash uint64, i int) int {
+	if c.groups != nil {
+		return c.groups.index(keyHash, i)
+	}
+
+	return c.prober.Index(keyHash, i)
+}
+
+// candidates returns the probes for keyHash and their slot index, which may hold the key of keyHash.
+// Without groups, these are all probes within the hash probe depth.
+func (c *LockFreeCache[K, V]) candidates(keyHash uint64) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		if c.groups == nil {
+			for i := range c.probeDepth() {
+				if !yield(i, c.prober.Index(keyHash, i)) {
+					return
+				}
+			}
+
+			return
+		}
+
+		for group := 0; group < c.probeDepth(); group += groupSize {
+			first := c.groups.index(keyHash, group)
+
+			for mask := c.groups.match(keyHash, group); mask != 0; mask &= mask - 1 {
+				offset := bits.TrailingZeros16(mask)
+
+				if !yield(group+offset, first+offset) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package grpccache
+
+import (
+	"context"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/cluster"
+	"google.golang.org/grpc"
+)
+
+// Client is a client of the Cache service.
+type Client struct {
+	client CacheClient
+}
+
+var _ cluster.Node = (*Client)(nil)
+
+// NewClient returns a client which calls the Cache service on conn.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{client: NewCacheClient(conn)}
+}
+
+// Get returns the value for key, and whether it was found.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	resp, err := c.client.Get(ctx, &GetRequest{Key: key})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return resp.GetValue(), resp.GetFound(), nil
+}
+
+// Put stores value for key.
+func (c *Client) Put(ctx context.Context, key string, value []byte) error {
+	_, err := c.client.Put(ctx, &PutRequest{Key: key, Value: value})
+	return err
+}
+
+// Delete removes the value for key.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	_, err := c.client.Delete(ctx, &DeleteRequest{Key: key})
+	return err
+}
+
+// BatchGet returns the values of the keys which were found.
+func (c *Client) BatchGet(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	resp, err := c.client.BatchGet(ctx, &BatchGetRequest{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(resp.GetEntries()))
+
+	for _, e := range resp.GetEntries() {
+		values[e.GetKey()] = e.GetValue()
+	}
+
+	return values, nil
+}
+
+// Watch streams the eviction events of the cache, until ctx is canceled.
+func (c *Client) Watch(ctx context.Context) (*Watcher, error) {
+	stream, err := c.client.Watch(ctx, &WatchRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{stream: stream}, nil
+}
+
+// Watcher receives the eviction events streamed by Watch.
+type Watcher struct {
+	stream grpc.ServerStreamingClient[WatchEvent]
+}
+
+// Recv blocks until the next event is received. It returns io.EOF once the stream ended.
+func (w *Watcher) Recv() (cache.EvictionEvent, error) {
+	event, err := w.stream.Recv()
+	if err != nil {
+		return cache.EvictionEvent{}, err
+	}
+
+	return cache.EvictionEvent{
+		KeyHash: event.GetKeyHash(),
+		Reason:  parseReason(event.GetReason()),
+		Time:    time.Unix(0, event.GetTimeUnixNano()),
+	}, nil
+}
+
+// parseReason returns the eviction reason of its String form.
+func parseReason(s string) cache.EvictionReason {
+	for reason := cache.EvictionReplaced; reason <= cache.EvictionExpired; reason++ {
+		if reason.String() == s {
+			return reason
+		}
+	}
+
+	return cache.EvictionReason(-1)
+}
@@ -0,0 +1,19 @@
+module github.com/samborkent/cache/grpccache
+
+go 1.24.0
+
+require (
+	github.com/samborkent/cache v0.0.0-00010101000000-000000000000
+	github.com/samborkent/check v0.0.0-20250216103840-eaa4d4426057
+	google.golang.org/grpc v1.68.0
+	google.golang.org/protobuf v1.35.2
+)
+
+require (
+	golang.org/x/net v0.29.0 // indirect
+	golang.org/x/sys v0.25.0 // indirect
+	golang.org/x/text v0.18.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240903143218-8af14fe29dc1 // indirect
+)
+
+replace github.com/samborkent/cache => ../
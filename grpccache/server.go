@@ -0,0 +1,142 @@
+// Package grpccache serves a cache over gRPC, as defined by cache.proto, and provides a client for it.
+//
+//	srv := grpc.NewServer()
+//	grpccache.NewServer(c, nil).Register(srv)
+package grpccache
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cache.proto
+
+import (
+	"context"
+	"sync"
+
+	"github.com/samborkent/cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchBuffer is the number of events buffered per watcher. Events are dropped for watchers which fall behind.
+const watchBuffer = 64
+
+// Server serves a cache over gRPC.
+// A LockFreeCache should be created with cache.WithOwnedValues, so it holds the stored values until they are
+// deleted, overwritten, evicted, or expire, instead of leaving them to the garbage collector.
+type Server struct {
+	UnimplementedCacheServer
+
+	cache cache.Interface[string, []byte]
+
+	lock     sync.Mutex
+	watchers map[chan cache.EvictionEvent]struct{}
+}
+
+// NewServer returns a server for c. If events is not nil, its eviction events are streamed to watchers,
+// until it is closed. It should be the channel given to WithEvictionEvents.
+func NewServer(c cache.Interface[string, []byte], events <-chan cache.EvictionEvent) *Server {
+	s := &Server{
+		cache:    c,
+		watchers: make(map[chan cache.EvictionEvent]struct{}),
+	}
+
+	if events != nil {
+		go s.broadcast(events)
+	}
+
+	return s
+}
+
+// Register registers the Cache service on r.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	RegisterCacheServer(r, s)
+}
+
+func (s *Server) Get(_ context.Context, req *GetRequest) (*GetResponse, error) {
+	value, ok := s.cache.Get(req.GetKey())
+	return &GetResponse{Value: value, Found: ok}, nil
+}
+
+func (s *Server) Put(_ context.Context, req *PutRequest) (*PutResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty key")
+	}
+
+	value := req.GetValue()
+	s.cache.Put(req.GetKey(), &value)
+
+	return &PutResponse{}, nil
+}
+
+func (s *Server) Delete(_ context.Context, req *DeleteRequest) (*DeleteResponse, error) {
+	s.cache.Delete(req.GetKey())
+	return &DeleteResponse{}, nil
+}
+
+func (s *Server) BatchGet(_ context.Context, req *BatchGetRequest) (*BatchGetResponse, error) {
+	resp := &BatchGetResponse{}
+
+	for _, key := range req.GetKeys() {
+		if value, ok := s.cache.Get(key); ok {
+			resp.Entries = append(resp.Entries, &Entry{Key: key, Value: value})
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *Server) Watch(_ *WatchRequest, stream grpc.ServerStreamingServer[WatchEvent]) error {
+	events := make(chan cache.EvictionEvent, watchBuffer)
+
+	s.lock.Lock()
+	s.watchers[events] = struct{}{}
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.watchers, events)
+		s.lock.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.Send(&WatchEvent{
+				KeyHash:      event.KeyHash,
+				Reason:       event.Reason.String(),
+				TimeUnixNano: event.Time.UnixNano(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// broadcast sends events to all watchers, and ends their streams once events is closed.
+func (s *Server) broadcast(events <-chan cache.EvictionEvent) {
+	for event := range events {
+		s.lock.Lock()
+
+		for watcher := range s.watchers {
+			select {
+			case watcher <- event:
+			default:
+			}
+		}
+
+		s.lock.Unlock()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for watcher := range s.watchers {
+		close(watcher)
+		delete(s.watchers, watcher)
+	}
+}
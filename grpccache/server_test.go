@@ -0,0 +1,84 @@
+package grpccache_test
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/grpccache"
+	"github.com/samborkent/check"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan cache.EvictionEvent, 16)
+	testCache := cache.NewLockFreeCache(16, cache.WithOwnedValues[string, []byte](), cache.WithEvictionEvents[string, []byte](events))
+
+	lis := bufconn.Listen(1 << 20)
+
+	srv := grpc.NewServer()
+	grpccache.NewServer(testCache, events).Register(srv)
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	check.True(t, err == nil)
+
+	defer conn.Close()
+
+	client := grpccache.NewClient(conn)
+
+	_, found, err := client.Get(ctx, "key")
+	check.True(t, err == nil)
+	check.True(t, !found)
+
+	check.True(t, client.Put(ctx, "key", []byte("value")) == nil)
+
+	// The cache owns the stored values, so they survive garbage collection.
+	runtime.GC()
+
+	value, found, err := client.Get(ctx, "key")
+	check.True(t, err == nil)
+	check.True(t, found)
+	check.Equal(t, string(value), "value")
+
+	values, err := client.BatchGet(ctx, "key", "other")
+	check.True(t, err == nil)
+	check.Equal(t, len(values), 1)
+	check.Equal(t, string(values["key"]), "value")
+
+	watcher, err := client.Watch(ctx)
+	check.True(t, err == nil)
+
+	// Delete until the watcher is registered and receives the eviction.
+	go func() {
+		for ctx.Err() == nil {
+			_ = client.Put(ctx, "watched", []byte("value"))
+			_ = client.Delete(ctx, "watched")
+
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	event, err := watcher.Recv()
+	check.True(t, err == nil)
+	check.Equal(t, event.Reason, cache.EvictionDeleted)
+}
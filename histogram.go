@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Latencies are counted in buckets per power of two nanoseconds, each split in 4 linear sub-buckets,
+	// so bucket bounds are within 25% of the recorded latency. Latencies from 2^41ns (about 37 minutes)
+	// are counted in the last bucket.
+	latencySubBuckets = 4
+	latencyMaxExp     = 40
+	latencyBuckets    = latencySubBuckets * latencyMaxExp
+
+	cacheLineSize = 64
+)
+
+// LatencyHistogram counts operation latencies in logarithmic buckets.
+// Counts is nil if latency tracking is not enabled with WithLatencyTracking.
+type LatencyHistogram struct {
+	Counts []uint64
+}
+
+// LatencyBucketBound returns the upper bound of the latencies counted in the bucket at index.
+func LatencyBucketBound(index int) time.Duration {
+	if index < latencySubBuckets {
+		return time.Duration(index)
+	}
+
+	exp := index/latencySubBuckets + 1
+	sub := index % latencySubBuckets
+	shift := exp - 2
+
+	return time.Duration((latencySubBuckets+sub+1)<<shift - 1)
+}
+
+// Count returns the number of recorded latencies.
+func (h LatencyHistogram) Count() uint64 {
+	var total uint64
+
+	for _, count := range h.Counts {
+		total += count
+	}
+
+	return total
+}
+
+// Quantile returns the upper bound of the bucket containing the q-th quantile, for q between 0 and 1.
+// It returns 0 if no latencies were recorded.
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	total := h.Count()
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(total))
+	if rank >= total {
+		rank = total - 1
+	}
+
+	var seen uint64
+
+	for index, count := range h.Counts {
+		seen += count
+		if seen > rank {
+			return LatencyBucketBound(index)
+		}
+	}
+
+	return LatencyBucketBound(len(h.Counts) - 1)
+}
+
+// delta returns the change of the counts since prev.
+func (h LatencyHistogram) delta(prev LatencyHistogram) LatencyHistogram {
+	if h.Counts == nil {
+		return h
+	}
+
+	counts := make([]uint64, len(h.Counts))
+	copy(counts, h.Counts)
+
+	for index := range min(len(counts), len(prev.Counts)) {
+		counts[index] -= prev.Counts[index]
+	}
+
+	return LatencyHistogram{Counts: counts}
+}
+
+// add returns the sum of the counts.
+func (h LatencyHistogram) add(other LatencyHistogram) LatencyHistogram {
+	switch {
+	case h.Counts == nil:
+		return other
+	case other.Counts == nil:
+		return h
+	}
+
+	counts := make([]uint64, max(len(h.Counts), len(other.Counts)))
+	copy(counts, h.Counts)
+
+	for index, count := range other.Counts {
+		counts[index] += count
+	}
+
+	return LatencyHistogram{Counts: counts}
+}
+
+// latencyRecorder accumulates latencies in stripes, to avoid contention on the counters.
+// Each record picks a random stripe, which approximates per-P counters.
+type latencyRecorder struct {
+	stripes []latencyStripe
+	mask    uint32
+}
+
+type latencyStripe struct {
+	counts [latencyBuckets]atomic.Uint64
+	_      [cacheLineSize]byte
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	stripes := stripeCount()
+
+	return &latencyRecorder{
+		stripes: make([]latencyStripe, stripes),
+		mask:    uint32(stripes - 1),
+	}
+}
+
+// record counts the time elapsed since start.
+func (r *latencyRecorder) record(start time.Time) {
+	stripe := &r.stripes[rand.Uint32()&r.mask]
+	stripe.counts[latencyBucket(time.Since(start))].Add(1)
+}
+
+func (r *latencyRecorder) histogram() LatencyHistogram {
+	if r == nil {
+		return LatencyHistogram{}
+	}
+
+	counts := make([]uint64, latencyBuckets)
+
+	for i := range r.stripes {
+		for index := range counts {
+			counts[index] += r.stripes[i].counts[index].Load()
+		}
+	}
+
+	return LatencyHistogram{Counts: counts}
+}
+
+func (r *latencyRecorder) reset() {
+	if r == nil {
+		return
+	}
+
+	for i := range r.stripes {
+		for index := range r.stripes[i].counts {
+			r.stripes[i].counts[index].Store(0)
+		}
+	}
+}
+
+// latencyBucket returns the index of the bucket counting latency.
+func latencyBucket(latency time.Duration) int {
+	nanos := uint64(max(0, latency))
+	if nanos < latencySubBuckets {
+		return int(nanos)
+	}
+
+	exp := bits.Len64(nanos) - 1
+	if exp > latencyMaxExp {
+		return latencyBuckets - 1
+	}
+
+	sub := int(nanos>>(exp-2)) & (latencySubBuckets - 1)
+
+	return latencySubBuckets*(exp-1) + sub
+}
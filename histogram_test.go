@@ -0,0 +1,40 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLatencyBucketBound(t *testing.T) {
+	t.Parallel()
+
+	check.Equal(t, cache.LatencyBucketBound(0), 0)
+	check.Equal(t, cache.LatencyBucketBound(3), 3)
+	check.Equal(t, cache.LatencyBucketBound(4), 4)
+	check.Equal(t, cache.LatencyBucketBound(7), 7)
+	check.Equal(t, cache.LatencyBucketBound(8), 9)
+	check.Equal(t, cache.LatencyBucketBound(11), 15)
+
+	for index := 1; index < 160; index++ {
+		check.True(t, cache.LatencyBucketBound(index) > cache.LatencyBucketBound(index-1))
+	}
+}
+
+func TestLatencyHistogramQuantile(t *testing.T) {
+	t.Parallel()
+
+	histogram := cache.LatencyHistogram{Counts: make([]uint64, 160)}
+	check.Equal(t, histogram.Quantile(0.5), 0)
+
+	// 90 latencies of 2ns and 10 latencies of 12-15ns.
+	histogram.Counts[2] = 90
+	histogram.Counts[11] = 10
+
+	check.Equal(t, histogram.Count(), 100)
+	check.Equal(t, histogram.Quantile(0.5), 2*time.Nanosecond)
+	check.Equal(t, histogram.Quantile(0.95), 15*time.Nanosecond)
+	check.Equal(t, histogram.Quantile(1), 15*time.Nanosecond)
+}
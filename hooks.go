@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"runtime"
+	"time"
+	"weak"
+)
+
+// Hooks are optional callbacks invoked by a LockFreeCache, set with WithHooks.
+// Callbacks run synchronously on the goroutine performing the operation, so they should be fast.
+// They must not call back into the cache for the same key.
+type Hooks[K comparable, V any] struct {
+	// OnInsert is called after a value was stored for key, both for new and existing keys.
+	OnInsert func(key K, value *V)
+	// OnEvict is called after an entry was removed from the cache. The value is nil
+	// if it was already reclaimed by the garbage collector.
+	OnEvict func(key K, value *V, reason EvictionReason)
+	// OnHit is called when Get finds a live entry for key.
+	OnHit func(key K)
+	// OnMiss is called when Get finds no live entry for key.
+	OnMiss func(key K)
+	// OnLoad is called when GetOrLoad starts, for example to start a trace span. The returned context
+	// is passed to the loader, and the returned function, if not nil, is called when GetOrLoad returns.
+	OnLoad func(ctx context.Context, key K) (context.Context, func(result LoadResult, err error))
+}
+
+// EvictionEvent describes an entry removed from the cache, as sent on the channel set by WithEvictionEvents.
+type EvictionEvent struct {
+	KeyHash uint64
+	Reason  EvictionReason
+	Time    time.Time
+}
+
+// EvictionReason describes why an entry was removed from the cache.
+type EvictionReason int
+
+const (
+	// evictionNone suppresses eviction hooks, for entries which were never visible.
+	evictionNone EvictionReason = iota - 1
+
+	// EvictionReplaced means a new value was stored for the same key.
+	EvictionReplaced
+	// EvictionOverwritten means the entry was overwritten to make room for another key.
+	EvictionOverwritten
+	// EvictionReclaimed means the value was reclaimed by the garbage collector.
+	EvictionReclaimed
+	// EvictionDeleted means the entry was explicitly deleted.
+	EvictionDeleted
+	// EvictionCapacity means the entry was evicted to stay within the max cost, memory budget or memory limit.
+	EvictionCapacity
+	// EvictionExpired means the time to live of the entry passed.
+	EvictionExpired
+
+	// evictionReasons is the number of eviction reasons.
+	evictionReasons = iota - 1
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionReplaced:
+		return "replaced"
+	case EvictionOverwritten:
+		return "overwritten"
+	case EvictionReclaimed:
+		return "reclaimed"
+	case EvictionDeleted:
+		return "deleted"
+	case EvictionCapacity:
+		return "capacity"
+	case EvictionExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// notify sends an eviction event without blocking, counting it as dropped if the channel is full.
+func (c *LockFreeCache[K, V]) notify(keyHash uint64, reason EvictionReason) {
+	select {
+	case c.evictionEvents <- EvictionEvent{KeyHash: keyHash, Reason: reason, Time: time.Now()}:
+	default:
+		c.droppedEvictionEvents.Add(1)
+	}
+}
+
+// reclaimTarget identifies the entry to sweep once its value was reclaimed.
+// The cache is referenced weakly, so pending cleanups do not keep it alive.
+type reclaimTarget[K comparable, V any] struct {
+	cache   weak.Pointer[LockFreeCache[K, V]]
+	keyHash uint64
+}
+
+// watch registers a cleanup which sweeps the entry for keyHash once value is reclaimed,
+// so the reclaim callback fires without waiting for the slot to be visited.
+func (c *LockFreeCache[K, V]) watch(value *V, keyHash uint64) {
+	runtime.AddCleanup(value, func(target reclaimTarget[K, V]) {
+		if c := target.cache.Value(); c != nil {
+			c.sweep(target.keyHash)
+		}
+	}, reclaimTarget[K, V]{cache: weak.Make(c), keyHash: keyHash})
+}
+
+// sweep invalidates the reclaimed entries for keyHash within the probe window.
+func (c *LockFreeCache[K, V]) sweep(keyHash uint64) {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	for i := range c.probeDepth() {
+		index := c.index(keyHash, i)
+
+		entry := c.slot(index).Load()
+		if entry != nil && entry.keyHash == keyHash && entry.valueRef.Value() == nil {
+			c.invalidate(entry, index)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package httpcache provides an http.RoundTripper which caches responses in a LockFreeCache.
+package httpcache
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samborkent/cache"
+)
+
+// Response is a cached response, with its body read into memory.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// Expires is the time until which the response is fresh, and served without contacting the server.
+	Expires time.Time
+}
+
+// Transport caches the responses to GET requests, honoring basic Cache-Control and validator semantics.
+// Fresh responses are served from the cache, as set by the max-age directive or the Expires header.
+// Stale responses with an ETag or Last-Modified header are revalidated with a conditional request.
+// Responses with no-store, a Vary header, or a status other than 200 OK are not cached.
+//
+// As the cache holds values weakly, cached responses may be reclaimed by the garbage collector.
+type Transport struct {
+	base  http.RoundTripper
+	cache *cache.LockFreeCache[string, Response]
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// NewTransport returns a transport which caches up to size responses of base.
+// If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, size int, opts ...cache.Option[string, Response]) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		base:  base,
+		cache: cache.NewLockFreeCache(size, opts...),
+	}
+}
+
+// Cache returns the underlying cache, for metrics and invalidation.
+func (t *Transport) Cache() *cache.LockFreeCache[string, Response] {
+	return t.cache
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestControl := parseCacheControl(req.Header)
+
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || requestControl.has("no-store") {
+		return t.base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+
+	cached, ok := t.cache.Get(key)
+	if ok && !requestControl.has("no-cache") && time.Now().Before(cached.Expires) {
+		return cached.response(req), nil
+	}
+
+	outgoing := req
+
+	if ok {
+		outgoing = revalidation(req, &cached)
+	}
+
+	resp, err := t.base.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok && resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		// Headers of the not modified response update the cached ones.
+		updated := cached
+		updated.Header = cached.Header.Clone()
+
+		for name, values := range resp.Header {
+			updated.Header[name] = values
+		}
+
+		updated.Expires = expires(updated.Header, time.Now())
+		t.cache.Put(key, &updated)
+
+		return updated.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK || !storable(resp.Header) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.cache.Put(key, &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Expires:    expires(resp.Header, time.Now()),
+	})
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+
+	return resp, nil
+}
+
+// response returns an HTTP response for the cached response, as a response to req.
+func (r *Response) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// revalidation returns a conditional request for the cached response, if it has validators.
+func revalidation(req *http.Request, cached *Response) *http.Request {
+	etag := cached.Header.Get("ETag")
+	lastModified := cached.Header.Get("Last-Modified")
+
+	if etag == "" && lastModified == "" {
+		return req
+	}
+
+	req = req.Clone(req.Context())
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	return req
+}
+
+// storable reports whether a response with header may be cached, and is of use once cached.
+func storable(header http.Header) bool {
+	control := parseCacheControl(header)
+
+	switch {
+	case control.has("no-store"), header.Get("Vary") != "":
+		return false
+	case header.Get("ETag") != "", header.Get("Last-Modified") != "":
+		return true
+	default:
+		return time.Now().Before(expires(header, time.Now()))
+	}
+}
+
+// expires returns the time until which a response with header, received at now, is fresh.
+func expires(header http.Header, now time.Time) time.Time {
+	control := parseCacheControl(header)
+
+	if control.has("no-cache") {
+		return now
+	}
+
+	if maxAge, ok := control["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return now
+		}
+
+		age, _ := strconv.Atoi(header.Get("Age"))
+
+		return now.Add(time.Duration(seconds-age) * time.Second)
+	}
+
+	if value := header.Get("Expires"); value != "" {
+		if expires, err := http.ParseTime(value); err == nil {
+			return expires
+		}
+	}
+
+	return now
+}
+
+// cacheControl holds the directives of a Cache-Control header, with their arguments.
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	control := cacheControl{}
+
+	for _, value := range header.Values("Cache-Control") {
+		for directive := range strings.SplitSeq(value, ",") {
+			name, argument, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+
+			control[strings.ToLower(name)] = strings.Trim(argument, `"`)
+		}
+	}
+
+	return control
+}
+
+func (c cacheControl) has(directive string) bool {
+	_, ok := c[directive]
+	return ok
+}
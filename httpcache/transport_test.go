@@ -0,0 +1,74 @@
+package httpcache_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/samborkent/cache/httpcache"
+	"github.com/samborkent/check"
+)
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	var requests, notModified atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+
+				return
+			}
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		}
+
+		_, _ = io.WriteString(w, "body")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: httpcache.NewTransport(nil, 16)}
+
+	get := func(path string) string {
+		resp, err := client.Get(server.URL + path)
+		check.True(t, err == nil)
+
+		defer resp.Body.Close()
+
+		check.Equal(t, resp.StatusCode, http.StatusOK)
+
+		body, err := io.ReadAll(resp.Body)
+		check.True(t, err == nil)
+
+		return string(body)
+	}
+
+	// Fresh responses are served from the cache.
+	check.Equal(t, get("/fresh"), "body")
+	check.Equal(t, get("/fresh"), "body")
+	check.Equal(t, requests.Load(), 1)
+
+	// Responses with validators are revalidated.
+	check.Equal(t, get("/etag"), "body")
+	check.Equal(t, get("/etag"), "body")
+	check.Equal(t, requests.Load(), 3)
+	check.Equal(t, notModified.Load(), 1)
+
+	// Responses with no-store are not cached.
+	check.Equal(t, get("/no-store"), "body")
+	check.Equal(t, get("/no-store"), "body")
+	check.Equal(t, requests.Load(), 5)
+}
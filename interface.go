@@ -0,0 +1,31 @@
+package cache
+
+import "context"
+
+// Interface is the common interface of the caches in this package,
+// so application code can swap implementations.
+type Interface[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Put(key K, value *V)
+	Delete(key K)
+	Len() int
+	Cap() int
+	Metrics() Metrics
+}
+
+var (
+	_ Interface[string, any] = (*Cache[string, any])(nil)
+	_ Interface[string, any] = (*LockFreeCache[string, any])(nil)
+	_ Interface[string, any] = (*ShardedCache[string, any])(nil)
+	_ Interface[string, any] = (*PerProcCache[string, any])(nil)
+)
+
+// Loader is implemented by the caches which load missing values on demand, coalescing concurrent loads of a key.
+type Loader[K comparable, V any] interface {
+	GetOrLoad(ctx context.Context, key K, load func(ctx context.Context, key K) (*V, error)) (V, error)
+}
+
+var (
+	_ Loader[string, any] = (*LockFreeCache[string, any])(nil)
+	_ Loader[string, any] = (*ShardedCache[string, any])(nil)
+)
@@ -0,0 +1,49 @@
+package cache
+
+import "weak"
+
+// Invalidator broadcasts invalidations between the caches of multiple processes, such as over Redis pub/sub
+// or NATS, so their local caches stay coherent. Key hashes are published by one cache and received
+// by the caches of all other processes, which then evict their entries for the key.
+//
+// Implementations should not deliver the invalidations of a cache back to itself, for example by tagging
+// messages with a process ID, as it would evict the values it just stored.
+type Invalidator interface {
+	// Publish broadcasts the invalidation of the key with keyHash.
+	Publish(keyHash uint64)
+	// Subscribe calls fn for every invalidation received from other processes.
+	Subscribe(fn func(keyHash uint64))
+}
+
+// subscribe evicts the entries of received invalidations. The subscription only holds a weak reference,
+// so it does not keep the cache alive.
+func (c *LockFreeCache[K, V]) subscribe(invalidator Invalidator) {
+	cache := weak.Make(c)
+
+	invalidator.Subscribe(func(keyHash uint64) {
+		if c := cache.Value(); c != nil {
+			c.evictHash(keyHash)
+		}
+	})
+}
+
+// publish broadcasts the invalidation of key, if an invalidator is set.
+func (c *LockFreeCache[K, V]) publish(key K) {
+	if c.invalidator != nil {
+		c.invalidator.Publish(c.hash(key))
+	}
+}
+
+// evictHash removes all entries with keyHash, including pinned entries.
+func (c *LockFreeCache[K, V]) evictHash(keyHash uint64) {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	for i := range c.probeDepth() {
+		index := c.index(keyHash, i)
+
+		entry := c.slot(index).Load()
+		if entry != nil && entry.keyHash == keyHash {
+			c.remove(entry, index, EvictionDeleted)
+		}
+	}
+}
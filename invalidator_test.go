@@ -0,0 +1,77 @@
+package cache_test
+
+import (
+	"hash/fnv"
+	"sync"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+// bus connects invalidators in memory, like a pub/sub channel shared by processes.
+type bus struct {
+	lock        sync.Mutex
+	subscribers map[*busInvalidator]func(keyHash uint64)
+}
+
+type busInvalidator struct {
+	bus *bus
+}
+
+func (i *busInvalidator) Publish(keyHash uint64) {
+	i.bus.lock.Lock()
+	defer i.bus.lock.Unlock()
+
+	for subscriber, fn := range i.bus.subscribers {
+		// Invalidations are not delivered back to their publisher.
+		if subscriber != i {
+			fn(keyHash)
+		}
+	}
+}
+
+func (i *busInvalidator) Subscribe(fn func(keyHash uint64)) {
+	i.bus.lock.Lock()
+	defer i.bus.lock.Unlock()
+
+	i.bus.subscribers[i] = fn
+}
+
+func hashString(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	return h.Sum64()
+}
+
+func TestLockFreeCacheInvalidator(t *testing.T) {
+	t.Parallel()
+
+	b := &bus{subscribers: make(map[*busInvalidator]func(keyHash uint64))}
+
+	cache1 := cache.NewLockFreeCache(N/100, cache.WithInvalidator[string, uint64](&busInvalidator{bus: b}, hashString))
+	cache2 := cache.NewLockFreeCache(N/100, cache.WithInvalidator[string, uint64](&busInvalidator{bus: b}, hashString))
+
+	val1, val2 := uint64(1), uint64(2)
+
+	cache1.Put("key", &val1)
+	cache2.Put("key", &val2)
+
+	// The write of the second cache evicted the stale value of the first.
+	_, ok := cache1.Get("key")
+	check.True(t, !ok)
+
+	value, ok := cache2.Get("key")
+	check.True(t, ok)
+	check.Equal(t, value, 2)
+
+	cache1.Put("key", &val1)
+	check.True(t, cache1.Pin("key"))
+
+	cache2.Delete("key")
+
+	// Pinned entries are evicted too.
+	_, ok = cache1.Get("key")
+	check.True(t, !ok)
+}
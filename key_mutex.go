@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"sync"
+)
+
+// DefaultKeyMutexStripes is the number of stripes of a KeyMutex created with a non-positive number of stripes.
+const DefaultKeyMutexStripes = 256
+
+// KeyMutex serializes work per key, such as generating a value which is too expensive to be done twice, with
+// a fixed pool of mutexes. Keys are mapped to mutexes by their hash, so distinct keys which share a stripe also
+// wait for each other. More stripes make this less likely, at the cost of a cache line each.
+type KeyMutex[K comparable] struct {
+	hash    func(K) uint64
+	stripes []paddedMutex
+	mask    uint64
+}
+
+type paddedMutex struct {
+	sync.Mutex
+	_ [cacheLineSize - 8]byte
+}
+
+// NewKeyMutex returns a KeyMutex with stripes mutexes, rounded up to a power of two.
+func NewKeyMutex[K comparable](stripes int) *KeyMutex[K] {
+	seed := maphash.MakeSeed()
+
+	return newKeyMutex(stripes, func(key K) uint64 {
+		return maphash.Comparable(seed, key)
+	})
+}
+
+func newKeyMutex[K comparable](stripes int, hash func(K) uint64) *KeyMutex[K] {
+	if stripes <= 0 {
+		stripes = DefaultKeyMutexStripes
+	}
+
+	stripes = 1 << bits.Len(uint(stripes-1))
+
+	return &KeyMutex[K]{
+		hash:    hash,
+		stripes: make([]paddedMutex, stripes),
+		mask:    uint64(stripes - 1),
+	}
+}
+
+// KeyMutex returns a KeyMutex which maps keys to its stripes by the hash of the cache, including the hash
+// set by WithInvalidator.
+func (c *LockFreeCache[K, V]) KeyMutex(stripes int) *KeyMutex[K] {
+	return newKeyMutex(stripes, c.hash)
+}
+
+// Lock locks the mutex of key, and returns the function which unlocks it.
+func (m *KeyMutex[K]) Lock(key K) (unlock func()) {
+	mutex := &m.stripes[m.hash(key)&m.mask].Mutex
+	mutex.Lock()
+
+	return mutex.Unlock
+}
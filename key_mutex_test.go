@@ -0,0 +1,56 @@
+package cache_test
+
+import (
+	cryptorand "crypto/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestKeyMutex(t *testing.T) {
+	t.Parallel()
+
+	for _, keyMutex := range []*cache.KeyMutex[string]{
+		cache.NewKeyMutex[string](0),
+		cache.NewKeyMutex[string](3),
+		cache.NewLockFreeCache[string, uint64](N / 100).KeyMutex(16),
+	} {
+		key := cryptorand.Text()
+
+		var (
+			wg               sync.WaitGroup
+			active, maxCount atomic.Int64
+			count            int
+		)
+
+		for range 16 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for range 100 {
+					unlock := keyMutex.Lock(key)
+
+					current := active.Add(1)
+					if current > maxCount.Load() {
+						maxCount.Store(current)
+					}
+
+					count++
+
+					active.Add(-1)
+					unlock()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		check.Equal(t, maxCount.Load(), 1)
+		check.Equal(t, count, 1600)
+	}
+}
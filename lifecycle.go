@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"weak"
+)
+
+// lifecycle stops the background workers of a cache, once it is closed or garbage collected.
+// The workers must not hold strong references to the cache, or it is never garbage collected.
+type lifecycle struct {
+	lock   sync.RWMutex
+	closed bool
+	// stop is closed to stop the workers.
+	stop chan struct{}
+	wg   sync.WaitGroup
+	// release unregisters the shutdown by the context set by WithContext, if any.
+	release func() bool
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{stop: make(chan struct{})}
+}
+
+// goroutine runs fn in a new goroutine, and reports whether it did. It does not once the lifecycle was stopped.
+func (l *lifecycle) goroutine(fn func()) bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	if l.closed {
+		return false
+	}
+
+	l.wg.Add(1)
+
+	go func() {
+		defer l.wg.Done()
+		fn()
+	}()
+
+	return true
+}
+
+// shutdown stops the workers, and waits until all goroutines returned if wait is set.
+// It reports whether the lifecycle was stopped by this call.
+func (l *lifecycle) shutdown(wait bool) bool {
+	l.lock.Lock()
+
+	if l.closed {
+		l.lock.Unlock()
+		return false
+	}
+
+	l.closed = true
+	close(l.stop)
+	release := l.release
+	l.lock.Unlock()
+
+	if release != nil {
+		release()
+	}
+
+	if wait {
+		l.wg.Wait()
+	}
+
+	return true
+}
+
+// Close stops the background workers of the cache, such as those of WithRefreshAhead and WithExpirationWheel,
+// waits until background refreshes finished, and flushes and closes the attached AppendLog and Replicator.
+// Afterwards writes are ignored and reads miss, like on an uninitialized cache, and methods which return errors
+// return ErrClosed. It returns the errors of closing the AppendLog and Replicator. Close is idempotent.
+func (c *LockFreeCache[K, V]) Close() error {
+	if c.life == nil || !c.life.shutdown(true) {
+		return nil
+	}
+
+	c.closed.Store(true)
+	c.initialized.Store(false)
+
+	var errs []error
+
+	if log := c.appendLog.Swap(nil); log != nil {
+		errs = append(errs, log.Close())
+	}
+
+	if replicator := c.replicator.Swap(nil); replicator != nil {
+		errs = append(errs, replicator.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// usable returns ErrClosed if the cache was closed, ErrNotInitialized if it was not initialized, or nil.
+func (c *LockFreeCache[K, V]) usable() error {
+	switch {
+	case c.closed.Load():
+		return ErrClosed
+	case !c.initialized.Load():
+		return ErrNotInitialized
+	default:
+		return nil
+	}
+}
+
+// closeWith closes the cache once ctx is done. The registration only holds a weak reference,
+// so it does not keep the cache alive.
+func (c *LockFreeCache[K, V]) closeWith(ctx context.Context) {
+	cache := weak.Make(c)
+
+	release := context.AfterFunc(ctx, func() {
+		if c := cache.Value(); c != nil {
+			_ = c.Close()
+		}
+	})
+
+	c.life.lock.Lock()
+	defer c.life.lock.Unlock()
+
+	if c.life.closed {
+		release()
+		return
+	}
+
+	c.life.release = release
+}
+
+// Close closes all shards, as LockFreeCache.Close, and returns their errors.
+func (c *ShardedCache[K, V]) Close() error {
+	errs := make([]error, 0, len(c.shards))
+
+	for _, shard := range c.shards {
+		errs = append(errs, shard.Close())
+	}
+
+	return errors.Join(errs...)
+}
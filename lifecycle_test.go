@@ -0,0 +1,88 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheClose(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithTTL[string, uint64](time.Hour),
+		cache.WithExpirationWheel[string, uint64](time.Millisecond),
+		cache.WithRefreshAhead(time.Millisecond, 1, func(context.Context, string) (*uint64, error) {
+			return nil, cache.ErrNotFound
+		}),
+	)
+
+	log, err := cache.OpenAppendLog(filepath.Join(t.TempDir(), "log"), cache.JSONCodec[string]{}, cache.JSONCodec[uint64]{}, cache.SyncEverySecond)
+	check.True(t, err == nil)
+	check.True(t, log.Attach(testCache) == nil)
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+
+	check.True(t, testCache.Close() == nil)
+	check.True(t, testCache.Close() == nil)
+
+	// The attached log was closed too.
+	check.True(t, log.Close() == nil)
+
+	// Later operations are rejected.
+	testCache.Put("key2", &val)
+	check.True(t, !testCache.Contains("key2"))
+
+	_, err = testCache.GetE("key")
+	check.True(t, errors.Is(err, cache.ErrClosed))
+
+	_, err = testCache.GetOrLoad(context.Background(), "key", func(context.Context, string) (*uint64, error) {
+		return &val, nil
+	})
+	check.True(t, errors.Is(err, cache.ErrClosed))
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheWithContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithContext[string, uint64](ctx))
+
+	_, err := testCache.GetE("key")
+	check.True(t, errors.Is(err, cache.ErrNotFound))
+
+	cancel()
+
+	for range 1000 {
+		if _, err = testCache.GetE("key"); errors.Is(err, cache.ErrClosed) {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	check.True(t, errors.Is(err, cache.ErrClosed))
+}
+
+func TestReadThroughCacheClose(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewReadThroughCache[string, uint64](N/100, newMapStore[string, uint64]())
+	check.True(t, testCache.Close() == nil)
+
+	val := uint64(1)
+	check.True(t, errors.Is(testCache.Put(context.Background(), "key", &val), cache.ErrClosed))
+
+	_, err := testCache.Get(context.Background(), "key")
+	check.True(t, errors.Is(err, cache.ErrClosed))
+}
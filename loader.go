@@ -0,0 +1,327 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime/debug"
+	"time"
+)
+
+// LoadResult describes how GetOrLoad obtained its value.
+type LoadResult int
+
+const (
+	// LoadHit means the value was found in the cache.
+	LoadHit LoadResult = iota
+	// LoadMiss means the value was loaded by this call.
+	LoadMiss
+	// LoadCoalesced means the call waited for a concurrent load of the same key.
+	LoadCoalesced
+	// LoadNegative means a recent load found no value, as cached by WithNegativeTTL.
+	LoadNegative
+	// LoadStale means an expired value was returned, while it is refreshed in the background.
+	LoadStale
+)
+
+func (r LoadResult) String() string {
+	switch r {
+	case LoadHit:
+		return "hit"
+	case LoadMiss:
+		return "miss"
+	case LoadCoalesced:
+		return "coalesced"
+	case LoadNegative:
+		return "negative"
+	case LoadStale:
+		return "stale"
+	default:
+		return "unknown"
+	}
+}
+
+// loadCall is an in-flight load, shared by all concurrent GetOrLoad calls for the same key.
+type loadCall[V any] struct {
+	done  chan struct{}
+	value *V
+	err   error
+
+	// waiters is the number of calls waiting for the load, guarded by the load lock.
+	// The load is canceled by cancel once all of them gave up.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// GetResult is the extended result of GetOrLoadResult.
+type GetResult[V any] struct {
+	Value V
+	// Result describes how the value was obtained.
+	Result LoadResult
+	// Stale reports whether the value is expired, and being refreshed in the background.
+	Stale bool
+}
+
+// GetOrLoad returns the value for key. If no live entry exists, load is called and a successful result is stored.
+// Concurrent calls for the same key are coalesced into a single load, whose result is returned to all of them.
+// Errors are returned, but not cached, except ErrNotFound if WithNegativeTTL is set. A panic of load is recovered,
+// and returned to all waiting calls as an error wrapping ErrLoaderPanic.
+// With WithStaleWhileRevalidate, expired values are returned immediately and refreshed in the background.
+//
+// Once ctx is done, the call returns its error without waiting for the load. The load runs in the background,
+// and its context is only canceled once all calls waiting for it gave up, or after the timeout of WithLoadTimeout.
+func (c *LockFreeCache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context, key K) (*V, error)) (V, error) {
+	result, err := c.GetOrLoadResult(ctx, key, load)
+	return result.Value, err
+}
+
+// GetOrLoadResult is like GetOrLoad, but also reports how the value was obtained and whether it is stale.
+func (c *LockFreeCache[K, V]) GetOrLoadResult(ctx context.Context, key K, load func(ctx context.Context, key K) (*V, error)) (GetResult[V], error) {
+	var done func(LoadResult, error)
+	if c.hooks.OnLoad != nil {
+		ctx, done = c.hooks.OnLoad(ctx, key)
+	}
+
+	value, result, err := c.getOrLoad(ctx, key, load)
+
+	if done != nil {
+		done(result, err)
+	}
+
+	return GetResult[V]{Value: value, Result: result, Stale: result == LoadStale}, err
+}
+
+func (c *LockFreeCache[K, V]) getOrLoad(ctx context.Context, key K, load func(ctx context.Context, key K) (*V, error)) (V, LoadResult, error) {
+	if c.closed.Load() {
+		return *new(V), LoadMiss, ErrClosed
+	}
+
+	if value, ok := c.Get(key); ok {
+		if !c.early(key) {
+			return value, LoadHit, nil
+		}
+
+		// Refresh the live entry ahead of its expiry, unless another call is already loading it.
+		call, started := c.start(key)
+		if !started {
+			return value, LoadHit, nil
+		}
+
+		c.launch(ctx, key, load, call)
+
+		// The entry is still live, so a failed or abandoned refresh falls back to it.
+		if c.wait(ctx, key, call) != nil || call.err != nil || call.value == nil {
+			return value, LoadHit, nil
+		}
+
+		return *call.value, LoadMiss, nil
+	}
+
+	if c.staleWhileRevalidate {
+		if value, stale := c.findStale(key); value != nil && stale {
+			c.refresh(ctx, key, load)
+			return *value, LoadStale, nil
+		}
+	}
+
+	if c.negatives != nil {
+		if expires, ok := c.negatives.Load(key); ok && time.Now().UnixNano() < expires {
+			return *new(V), LoadNegative, ErrNotFound
+		}
+	}
+
+	c.loadLock.Lock()
+
+	if call, ok := c.loads[key]; ok {
+		call.waiters++
+		c.loadLock.Unlock()
+
+		if err := c.wait(ctx, key, call); err != nil {
+			return *new(V), LoadCoalesced, err
+		}
+
+		return deref(call.value), LoadCoalesced, call.err
+	}
+
+	// Another load may have finished since the miss.
+	if value, ok := c.Peek(key); ok {
+		c.loadLock.Unlock()
+		return value, LoadHit, nil
+	}
+
+	call := &loadCall[V]{done: make(chan struct{}), waiters: 1}
+
+	if c.loads == nil {
+		c.loads = make(map[K]*loadCall[V])
+	}
+
+	c.loads[key] = call
+	c.loadLock.Unlock()
+
+	c.launch(ctx, key, load, call)
+
+	if err := c.wait(ctx, key, call); err != nil {
+		return *new(V), LoadMiss, err
+	}
+
+	return deref(call.value), LoadMiss, call.err
+}
+
+// refresh starts a background load of key, unless one is already in flight.
+// The load is not canceled with ctx, as it outlives the call which started it.
+func (c *LockFreeCache[K, V]) refresh(ctx context.Context, key K, load func(ctx context.Context, key K) (*V, error)) {
+	if call, started := c.start(key); started {
+		c.launch(ctx, key, load, call)
+	}
+}
+
+// start registers a load of key, and reports whether it did. It does not if a load is already in flight.
+func (c *LockFreeCache[K, V]) start(key K) (*loadCall[V], bool) {
+	c.loadLock.Lock()
+	defer c.loadLock.Unlock()
+
+	if call, ok := c.loads[key]; ok {
+		return call, false
+	}
+
+	// The caller which started the load is its first waiter. Background refreshes never give up.
+	call := &loadCall[V]{done: make(chan struct{}), waiters: 1}
+
+	if c.loads == nil {
+		c.loads = make(map[K]*loadCall[V])
+	}
+
+	c.loads[key] = call
+
+	return call, true
+}
+
+// launch runs the load of call in the background, with a context which is only canceled once all waiters gave up.
+// Once the cache is closed, the load runs synchronously, so the waiters are still released.
+func (c *LockFreeCache[K, V]) launch(ctx context.Context, key K, load func(ctx context.Context, key K) (*V, error), call *loadCall[V]) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	c.loadLock.Lock()
+	call.cancel = cancel
+	c.loadLock.Unlock()
+
+	run := func() {
+		defer cancel()
+		c.run(ctx, key, load, call)
+	}
+
+	if !c.life.goroutine(run) {
+		run()
+	}
+}
+
+// wait waits until call is done, or returns the error of ctx once it is done. The load of call is canceled
+// once all its waiters gave up.
+func (c *LockFreeCache[K, V]) wait(ctx context.Context, key K, call *loadCall[V]) error {
+	select {
+	case <-call.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	c.loadLock.Lock()
+	defer c.loadLock.Unlock()
+
+	if call.waiters--; call.waiters == 0 && call.cancel != nil {
+		call.cancel()
+
+		// Later calls start a new load, instead of waiting for the canceled one.
+		if c.loads[key] == call {
+			delete(c.loads, key)
+		}
+	}
+
+	return ctx.Err()
+}
+
+// early reports whether the live entry for key should be refreshed ahead of its expiry, as enabled by
+// WithEarlyExpiration. Following XFetch, the probability rises as the expiry nears, and is higher
+// for entries which took longer to load, so they are refreshed in time.
+func (c *LockFreeCache[K, V]) early(key K) bool {
+	if c.beta == 0 {
+		return false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	_, entry, _ := c.find(key)
+	if entry == nil || entry.expires == 0 || entry.delta == 0 {
+		return false
+	}
+
+	// The gap is delta * beta * -ln(r), for r uniform in (0, 1].
+	gap := float64(entry.delta) * c.beta * -math.Log(1-rand.Float64())
+
+	return float64(time.Now().UnixNano())+gap >= float64(entry.expires)
+}
+
+// run performs the load of an in-flight call, stores its result, and releases its waiters.
+func (c *LockFreeCache[K, V]) run(ctx context.Context, key K, load func(ctx context.Context, key K) (*V, error), call *loadCall[V]) {
+	if c.loadTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, c.loadTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	call.value, call.err = c.load(ctx, key, load)
+
+	switch {
+	case call.err == nil && call.value != nil:
+		c.store(key, call.value, time.Since(start))
+	case c.negatives != nil && errors.Is(call.err, ErrNotFound):
+		c.negatives.Store(key, time.Now().Add(c.negativeTTL).UnixNano())
+	}
+
+	c.loadLock.Lock()
+
+	if c.loads[key] == call {
+		delete(c.loads, key)
+	}
+
+	c.loadLock.Unlock()
+
+	close(call.done)
+}
+
+// load calls load, and converts a panic into an error wrapping ErrLoaderPanic, so the waiters are still released.
+func (c *LockFreeCache[K, V]) load(ctx context.Context, key K, load func(ctx context.Context, key K) (*V, error)) (value *V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.loaderPanics.Add(1)
+
+			value, err = nil, fmt.Errorf("%w: %v\n%s", ErrLoaderPanic, r, debug.Stack())
+		}
+	}()
+
+	return load(ctx, key)
+}
+
+// store stores a loaded value, with the duration of its load for early expiration.
+func (c *LockFreeCache[K, V]) store(key K, value *V, delta time.Duration) {
+	if !c.initialized.Load() {
+		return
+	}
+
+	entry := c.newEntry(key, value, time.Now().UnixNano())
+	entry.delta = int64(delta)
+
+	c.admit(entry)
+}
+
+// deref returns the value pointed to, or the zero value if the pointer is nil.
+func deref[V any](value *V) V {
+	if value == nil {
+		return *new(V)
+	}
+
+	return *value
+}
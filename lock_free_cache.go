@@ -1,96 +1,584 @@
 package cache
 
 import (
+	"expvar"
+	"fmt"
 	"hash/maphash"
 	"log/slog"
 	"math"
 	"math/rand/v2"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
-	"unsafe"
 	"weak"
 )
 
-const randomEntryRetries = 3
+const (
+	randomEntryRetries = 3
+	// randomEntrySamples is the number of random slots of which the oldest is overwritten on a probe overflow.
+	randomEntrySamples = 4
+
+	// Probe overflows are tracked per second over the last minute.
+	probeOverflowInterval = time.Second
+	probeOverflowBuckets  = 60
+
+	// Hits and misses are tracked per 10 seconds over the last 15 minutes, if enabled.
+	hitRateInterval = 10 * time.Second
+	hitRateBuckets  = 90
+
+	// approxLenMinSamples is the least number of slots sampled by ApproxLen, which bounds its error.
+	approxLenMinSamples = 1024
+)
 
 type LockFreeCache[K comparable, V any] struct {
-	entries        []atomic.Pointer[cacheEntry[V]]
+	entries        []tableSlot[K, V]
+	stride         int
 	pool           sync.Pool
 	seed           maphash.Seed
+	hashFunc       func(K) uint64
 	size           int
-	hashProbeDepth int
+	prober         Prober
+	hashProbeDepth atomic.Int64
 	initialized    atomic.Bool
-	rng            atomic.Pointer[rand.PCG]
+	closed         atomic.Bool
+	life           *lifecycle
 
-	readMisses, readHits atomic.Uint64
+	// The most frequently updated counters are striped, to avoid contention between cores.
+	readMisses, readHits stripedCounter
 
-	firstWrites, probeWrites      atomic.Uint64
-	emptyWrites                   atomic.Uint64
+	// hitWindow and missWindow are only set if hit rate tracking is enabled.
+	trackHitRate          bool
+	hitWindow, missWindow windowCounter
+
+	// getLatency and putLatency are only set if latency tracking is enabled.
+	getLatency, putLatency *latencyRecorder
+
+	firstWrites, probeWrites      stripedCounter
+	emptyWrites                   stripedCounter
 	randomCASWrites, randomWrites atomic.Uint64
+
+	probeOverflows      atomic.Uint64
+	probeOverflowWindow windowCounter
+
+	// hitDepths and writeDepths count hits and writes by probe index.
+	hitDepths, writeDepths []atomic.Uint64
+
+	config  config[K, V]
+	logger  *slog.Logger
+	hooks   Hooks[K, V]
+	equal   func(a, b *V) bool
+	weigh   func(K, *V) int64
+	maxCost int64
+	cost    atomic.Int64
+
+	costEvictions atomic.Uint64
+	reclaimedCost atomic.Int64
+	deadReclaims  atomic.Uint64
+
+	// doorkeeper is only set if enabled with WithDoorkeeper.
+	doorkeeper        *doorkeeper
+	doorkeeperRejects atomic.Uint64
+
+	// ghosts is only set if enabled with WithGhostTracking.
+	ghosts    *ghostList
+	ghostHits atomic.Uint64
+
+	pressure          *memoryPressure
+	pressureEvictions atomic.Uint64
+
+	evictions [evictionReasons]atomic.Uint64
+
+	evictionEvents        chan<- EvictionEvent
+	droppedEvictionEvents atomic.Uint64
+	onReclaim             func(keyHash uint64)
+
+	loadLock     sync.Mutex
+	loads        map[K]*loadCall[V]
+	loaderPanics atomic.Uint64
+
+	// waits holds the GetOrWait calls waiting for the next put of their key, and waiting counts them,
+	// so puts skip the wait lock while nobody waits.
+	waitLock sync.Mutex
+	waits    map[K]*putWait[V]
+	waiting  atomic.Int64
+
+	// asyncPuts queues the puts of PutAsync for the background writer, if enabled.
+	asyncPuts        chan asyncPut[K, V]
+	asyncFlushes     chan chan struct{}
+	asyncPolicy      QueuePolicy
+	droppedAsyncPuts atomic.Uint64
+
+	// negatives holds the expiry of cached ErrNotFound loads, if negative caching is enabled.
+	negatives   *CounterCache[K]
+	negativeTTL time.Duration
+
+	// loadTimeout limits the duration of every load, if set.
+	loadTimeout time.Duration
+
+	ttl                  time.Duration
+	ttlJitter            float64
+	staleWhileRevalidate bool
+	// ownedValues makes entries hold a copy of their value, instead of a weak reference to the value of the caller.
+	ownedValues bool
+
+	// beta scales the early expiration of loaded entries, if enabled.
+	beta float64
+
+	refreshAhead *refreshAhead[K, V]
+
+	// appendLog records puts and deletes, once attached.
+	appendLog atomic.Pointer[AppendLog[K, V]]
+	// replicator streams puts and deletes to followers, once attached.
+	replicator atomic.Pointer[Replicator[K, V]]
+	// snapshots are the active snapshot views, which keep the entries replaced since their snapshot.
+	snapshots    atomic.Pointer[[]*snapshotState[K, V]]
+	snapshotLock sync.Mutex
+
+	// groups holds the control bytes of the slots, if group probing is enabled.
+	groups *controlGroups
+
+	// reclaim returns removed entries to the pool, once no reader can still use them.
+	reclaim *reclaimer[K, V]
+
+	// overflow receives all writes, and serves the entries which left the table, if set.
+	overflow     *DiskTier[K, V]
+	overflowHits stripedCounter
+
+	// invalidator receives the keys of writes, and invalidates keys written by other processes, if set.
+	invalidator Invalidator
+
+	// generation is recorded by new entries. Entries of generations before oldestGeneration read as misses.
+	generation       atomic.Uint64
+	oldestGeneration atomic.Uint64
+
+	accessTracking bool
+
+	// wheel schedules the removal of expired entries, if enabled.
+	wheel *timingWheel[K]
+
+	// versions is the last version given to an entry. It starts at the creation time,
+	// so versions of a previous process are unlikely to match.
+	versions atomic.Uint64
 }
 
-type cacheEntry[V any] struct {
+// tableSlot is a slot of the table. Its sequence is incremented by every write, after the entry was swapped,
+// so readers can validate that the entry they read was not replaced meanwhile, and possibly reused for another key.
+type tableSlot[K comparable, V any] struct {
+	atomic.Pointer[cacheEntry[K, V]]
+	sequence atomic.Uint64
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key      K
 	keyHash  uint64
 	valueRef weak.Pointer[V]
+	written  int64
+	cost     int64
+
+	// expires is the time in Unix nanoseconds from which the entry is expired, or 0 if it never expires.
+	expires int64
+	// delta is the duration in nanoseconds it took to load the value, if it was loaded.
+	delta int64
+	// promoted is set for entries read back from the overflow tier, which need not be written to it again.
+	promoted bool
+	// generation is the generation of the cache when the entry was written.
+	generation uint64
+	// version identifies the write of the entry.
+	version uint64
+	// access tracks the accesses of the entry, if enabled. It is shared by the copies of an amended entry.
+	access *entryAccess
+
+	// pinned holds a strong reference to the value while the entry is pinned.
+	pinned *V
+	// owned holds the copy of the value which is owned by the cache, if WithOwnedValues is set.
+	owned *V
 }
 
-type Metrics struct {
-	ReadMisses, ReadHits uint64
+// NewLockFreeCache returns a cache of size slots. It does not report invalid options: options given invalid
+// arguments and invalid combinations of options are logged at debug level by the logger of WithLogger, and the
+// cache is created regardless. A size which is not positive returns an unusable cache. Use TryNewLockFreeCache
+// or MustNewLockFreeCache to check the size and options.
+func NewLockFreeCache[K comparable, V any](size int, opts ...Option[K, V]) *LockFreeCache[K, V] {
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		cfg.log().Debug("ignored invalid options", slog.Any("error", err))
+	}
 
-	FirstWrites, ProbeWrites      uint64
-	EmptyWrites                   uint64
-	RandomCASWrites, RandomWrites uint64
+	return newConfiguredLockFreeCache(size, cfg)
 }
 
-func NewLockFreeCache[K comparable, V any](size int) *LockFreeCache[K, V] {
+// newConfiguredLockFreeCache is like NewLockFreeCache, for an already built configuration.
+func newConfiguredLockFreeCache[K comparable, V any](size int, cfg config[K, V]) *LockFreeCache[K, V] {
 	if size <= 0 {
 		return &LockFreeCache[K, V]{}
 	}
 
+	return newLockFreeCache(size, maphash.MakeSeed(), cfg)
+}
+
+// TryNewLockFreeCache is like NewLockFreeCache, but returns an error wrapping ErrInvalidConfig if the size
+// is not positive or the options are invalid, instead of returning an unusable cache.
+func TryNewLockFreeCache[K comparable, V any](size int, opts ...Option[K, V]) (*LockFreeCache[K, V], error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: size must be positive, got %d", ErrInvalidConfig, size)
+	}
+
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return newLockFreeCache(size, maphash.MakeSeed(), cfg), nil
+}
+
+// MustNewLockFreeCache is like TryNewLockFreeCache, but panics with the error.
+func MustNewLockFreeCache[K comparable, V any](size int, opts ...Option[K, V]) *LockFreeCache[K, V] {
+	c, err := TryNewLockFreeCache(size, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+func newLockFreeCache[K comparable, V any](size int, seed maphash.Seed, cfg config[K, V]) *LockFreeCache[K, V] {
+	if cfg.groupProbing {
+		// Groups are whole, so round the size up to the next group.
+		size = (size + groupSize - 1) / groupSize * groupSize
+	}
+
 	lockFreeCache := &LockFreeCache[K, V]{
-		entries: make([]atomic.Pointer[cacheEntry[V]], size),
+		entries: make([]tableSlot[K, V], size*cfg.stride()),
+		stride:  cfg.stride(),
 		pool: sync.Pool{
 			New: func() any {
-				return any(&cacheEntry[V]{})
+				return any(&cacheEntry[K, V]{})
 			},
 		},
-		seed:           maphash.MakeSeed(),
-		size:           size,
-		hashProbeDepth: max(1, int(math.Log2(float64(size)))),
+		seed:                 seed,
+		life:                 newLifecycle(),
+		hashFunc:             cfg.hashFunc,
+		size:                 size,
+		prober:               NewProber(size),
+		config:               cfg,
+		logger:               cfg.log(),
+		ttl:                  cfg.ttl,
+		ttlJitter:            cfg.ttlJitter,
+		staleWhileRevalidate: cfg.staleWhileRevalidate,
+		beta:                 cfg.beta,
+		overflow:             cfg.overflow,
+		accessTracking:       cfg.accessTracking,
+		ownedValues:          cfg.ownedValues,
+		invalidator:          cfg.invalidator,
+		hooks:                cfg.hooks,
+		evictionEvents:       cfg.evictionEvents,
+		onReclaim:            cfg.onReclaim,
+		equal:                cfg.equal(),
+		weigh:                cfg.weigh(),
+		maxCost:              cfg.limit(),
+		pressure:             cfg.pressure.clone(),
+		probeOverflowWindow:  newWindowCounter(probeOverflowInterval, probeOverflowBuckets),
+		readMisses:           newStripedCounter(),
+		readHits:             newStripedCounter(),
+		firstWrites:          newStripedCounter(),
+		probeWrites:          newStripedCounter(),
+		emptyWrites:          newStripedCounter(),
+		overflowHits:         newStripedCounter(),
+	}
+
+	depth := max(1, int(math.Log2(float64(size))))
+
+	if cfg.groupProbing {
+		lockFreeCache.groups = newControlGroups(size)
+		// Probe whole groups, and at least groupProbes of them. Control bytes make a group about as cheap
+		// to probe as a single slot, so a deeper sequence lets full groups overflow instead of evicting.
+		depth = min(size, max(groupProbes*groupSize, lockFreeCache.wholeGroups(depth)))
+	}
+
+	// Probe stats are counted up to the deepest probe depth which may be tuned to.
+	minDepth, maxDepth := depth, depth
+
+	if cfg.probeDepth != nil {
+		minDepth = min(size, lockFreeCache.wholeGroups(cfg.probeDepth.minDepth))
+		maxDepth = min(size, lockFreeCache.wholeGroups(cfg.probeDepth.maxDepth))
+		depth = min(max(depth, minDepth), maxDepth)
+	}
+
+	lockFreeCache.hashProbeDepth.Store(int64(depth))
+
+	lockFreeCache.reclaim = newReclaimer(func(entry *cacheEntry[K, V]) {
+		*entry = cacheEntry[K, V]{}
+		lockFreeCache.pool.Put(any(entry))
+	})
+
+	lockFreeCache.hitDepths = make([]atomic.Uint64, maxDepth)
+	lockFreeCache.writeDepths = make([]atomic.Uint64, maxDepth)
+
+	lockFreeCache.logger.Debug("created lock-free cache",
+		slog.Int("size", size),
+		slog.Int("probeDepth", depth),
+		slog.Int("stride", lockFreeCache.stride),
+	)
+
+	if cfg.negativeTTL > 0 {
+		lockFreeCache.negatives = NewCounterCache[K](size)
+		lockFreeCache.negativeTTL = cfg.negativeTTL
 	}
 
-	slog.Info("DEBUG", slog.Int("probeDepth", lockFreeCache.hashProbeDepth))
+	lockFreeCache.loadTimeout = cfg.loadTimeout
 
-	seed := uint64(time.Now().UnixNano())
+	if cfg.doorkeeper > 0 {
+		lockFreeCache.doorkeeper = newDoorkeeper(cfg.doorkeeper)
+	}
+
+	if cfg.ghosts > 0 {
+		lockFreeCache.ghosts = newGhostList(cfg.ghosts)
+	}
+
+	if cfg.latency {
+		lockFreeCache.getLatency = newLatencyRecorder()
+		lockFreeCache.putLatency = newLatencyRecorder()
+	}
+
+	if cfg.hitRate {
+		lockFreeCache.trackHitRate = true
+		lockFreeCache.hitWindow = newWindowCounter(hitRateInterval, hitRateBuckets)
+		lockFreeCache.missWindow = newWindowCounter(hitRateInterval, hitRateBuckets)
+	}
+
+	if cfg.refreshAhead != nil {
+		lockFreeCache.startRefreshAhead(cfg.refreshAhead)
+	}
+
+	if cfg.invalidator != nil {
+		lockFreeCache.subscribe(cfg.invalidator)
+	}
+
+	if cfg.expirationTick > 0 {
+		lockFreeCache.startExpirationWheel(cfg.expirationTick)
+	}
+
+	if cfg.asyncPuts != nil {
+		lockFreeCache.startAsyncPuts(cfg.asyncPuts.queueSize, cfg.asyncPuts.policy)
+	}
+
+	if lockFreeCache.weigh != nil {
+		lockFreeCache.startCostSweep(cfg.costSweep)
+	}
+
+	if cfg.probeDepth != nil && cfg.probeDepth.interval > 0 {
+		lockFreeCache.startProbeDepthTuning(cfg.probeDepth.interval, minDepth, maxDepth)
+	}
 
-	lockFreeCache.rng.Store(rand.NewPCG(seed, uint64(uintptr(unsafe.Pointer(lockFreeCache)))^seed))
+	lockFreeCache.versions.Store(uint64(time.Now().UnixNano()))
 	lockFreeCache.initialized.Store(true)
 
+	// Stop the background workers once the cache is garbage collected, without waiting for them.
+	runtime.AddCleanup(lockFreeCache, func(life *lifecycle) { life.shutdown(false) }, lockFreeCache.life)
+
+	if cfg.ctx != nil {
+		lockFreeCache.closeWith(cfg.ctx)
+	}
+
 	return lockFreeCache
 }
 
+// hash returns the key hash of key, by the function set by WithInvalidator or the seed of the cache.
+func (c *LockFreeCache[K, V]) hash(key K) uint64 {
+	if c.hashFunc != nil {
+		return c.hashFunc(key)
+	}
+
+	return maphash.Comparable(c.seed, key)
+}
+
+// slot returns the slot at index. Slots are stride slots apart, which pads them if WithPaddedSlots is set.
+func (c *LockFreeCache[K, V]) slot(index int) *tableSlot[K, V] {
+	return &c.entries[index*c.stride]
+}
+
+// swapSlot replaces entry with newEntry in the slot at index, and reports whether it did.
+// Active snapshot views keep the replaced entry, as of their snapshot.
+func (c *LockFreeCache[K, V]) swapSlot(index int, entry, newEntry *cacheEntry[K, V]) bool {
+	slot := c.slot(index)
+	if !slot.CompareAndSwap(entry, newEntry) {
+		return false
+	}
+
+	// Increment the sequence only after the swap, as readers which loaded the replaced entry before
+	// must see the increment once it may be reused.
+	slot.sequence.Add(1)
+
+	if c.groups != nil {
+		c.updateControl(slot, index)
+	}
+
+	if snapshots := c.snapshots.Load(); snapshots != nil {
+		for _, snapshot := range *snapshots {
+			snapshot.preserve(index, entry)
+		}
+	}
+
+	return true
+}
+
 func (c *LockFreeCache[K, V]) Put(key K, value *V) {
 	if !c.initialized.Load() {
 		return
 	}
 
-	keyHash := maphash.Comparable(c.seed, key)
+	if c.putLatency != nil {
+		defer c.putLatency.record(time.Now())
+	}
+
+	c.admit(c.newEntry(key, value, time.Now().UnixNano()))
+	c.publish(key)
+}
+
+// putOwned is like Put, but the entry holds value strongly until it is removed, as with WithOwnedValues.
+// It is used for values which only the cache references, such as copies returned by another cache.
+func (c *LockFreeCache[K, V]) putOwned(key K, value *V) {
+	if !c.initialized.Load() {
+		return
+	}
+
+	if c.putLatency != nil {
+		defer c.putLatency.record(time.Now())
+	}
+
+	entry := c.newEntry(key, value, time.Now().UnixNano())
+	if entry.owned == nil {
+		entry.owned = value
+	}
+
+	c.admit(entry)
+	c.publish(key)
+}
+
+// newEntry gets a cache entry from the pool and fills it.
+func (c *LockFreeCache[K, V]) newEntry(key K, value *V, written int64) *cacheEntry[K, V] {
+	var owned *V
+	if c.ownedValues && value != nil {
+		owned = new(V)
+		*owned = *value
+		value = owned
+	}
+
+	newEntry, _ := c.pool.Get().(*cacheEntry[K, V])
+	*newEntry = cacheEntry[K, V]{
+		key:        key,
+		keyHash:    c.hash(key),
+		valueRef:   weak.Make(value),
+		written:    written,
+		generation: c.generation.Load(),
+		version:    c.versions.Add(1),
+		owned:      owned,
+	}
+
+	// A nil value has no cost, and cost functions are not called with it.
+	if c.weigh != nil && value != nil {
+		newEntry.cost = c.weigh(key, value)
+	}
+
+	if c.ttl > 0 {
+		ttl := int64(c.ttl)
+
+		if c.ttlJitter > 0 {
+			ttl += int64((2*rand.Float64() - 1) * c.ttlJitter * float64(ttl))
+		}
+
+		newEntry.expires = written + ttl
+	}
+
+	if c.accessTracking {
+		newEntry.access = &entryAccess{}
+	}
+
+	if c.onReclaim != nil && value != nil {
+		c.watch(value, newEntry.keyHash)
+	}
+
+	return newEntry
+}
+
+// put stores the entry.
+// If it replaced an entry for the same key, the replaced entry is returned.
+func (c *LockFreeCache[K, V]) put(newEntry *cacheEntry[K, V]) *cacheEntry[K, V] {
+	return c.write(newEntry, false)
+}
+
+// admit stores the entry as put, but if a doorkeeper is enabled, an entry for a new key is dropped
+// until the key was put before.
+func (c *LockFreeCache[K, V]) admit(newEntry *cacheEntry[K, V]) *cacheEntry[K, V] {
+	return c.write(newEntry, c.doorkeeper != nil)
+}
+
+func (c *LockFreeCache[K, V]) write(newEntry *cacheEntry[K, V], filter bool) *cacheEntry[K, V] {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	if c.negatives != nil {
+		c.negatives.Delete(newEntry.key)
+	}
+
+	if replaced := c.replace(newEntry); replaced != nil {
+		return replaced
+	}
+
+	if filter && !c.doorkeeper.admit(newEntry.keyHash) {
+		c.doorkeeperRejects.Add(1)
+		return nil
+	}
+
+	position, replaced := c.insert(newEntry)
+	if position != -1 {
+		c.deduplicate(newEntry, position)
+	}
+
+	return replaced
+}
+
+// putIfAbsent stores the entry only if no live entry exists for its key, and reports whether it did.
+func (c *LockFreeCache[K, V]) putIfAbsent(newEntry *cacheEntry[K, V]) bool {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	for {
+		if _, entry, _ := c.find(newEntry.key); entry != nil {
+			return false
+		}
+
+		position, _ := c.insert(newEntry)
+		if position == -1 {
+			return false
+		}
 
-	// Get cache entry from pool.
-	newEntry, _ := c.pool.Get().(*cacheEntry[V])
-	*newEntry = cacheEntry[V]{}
-	newEntry.keyHash = keyHash
-	newEntry.valueRef = weak.Make(value)
+		if !c.conflicts(newEntry, position) {
+			return true
+		}
+	}
+}
+
+// replace swaps the entry for the same key within the hash probe depth, and returns the replaced entry.
+func (c *LockFreeCache[K, V]) replace(newEntry *cacheEntry[K, V]) *cacheEntry[K, V] {
+	keyHash := newEntry.keyHash
+	// Entries restored from a snapshot may already be pinned.
+	pinned := newEntry.pinned
 
-	// Try to replace existing entry up to hash probe depth.
-	for i := range c.hashProbeDepth {
-		index := probeIndex(keyHash, i, c.size)
+	for i := range c.probeDepth() {
+		index := c.index(keyHash, i)
+
+		entry := c.slot(index).Load()
+		if entry != nil && entry.keyHash == keyHash && entry.key == newEntry.key {
+			// Found same key. A pinned key stays pinned.
+			newEntry.pinned = pinned
+			if pinned == nil && entry.pinned != nil {
+				newEntry.pinned = newEntry.valueRef.Value()
+			}
+
+			if c.swapSlot(index, entry, newEntry) {
+				c.account(newEntry, entry, EvictionReplaced)
+				c.writeDepths[i].Add(1)
 
-		entry := c.entries[index].Load()
-		if entry != nil && entry.keyHash == keyHash {
-			// Found same key hash.
-			if c.entries[index].CompareAndSwap(entry, newEntry) {
 				if i == 0 {
 					c.firstWrites.Add(1)
 				} else {
@@ -98,103 +586,700 @@ func (c *LockFreeCache[K, V]) Put(key K, value *V) {
 				}
 
 				// Same key was swapped, exit.
-				return
+				return entry
 			}
 		}
 	}
 
+	return nil
+}
+
+// insert stores the entry in a free slot, or otherwise in a random slot, within the hash probe depth.
+// Slots outside of the probe depth are never visited by Get.
+// It returns the probe position of the slot, and the replaced entry if it was a reclaimed entry for the same key.
+func (c *LockFreeCache[K, V]) insert(newEntry *cacheEntry[K, V]) (int, *cacheEntry[K, V]) {
+	keyHash := newEntry.keyHash
+
+	if c.pressure != nil && c.pressure.check() {
+		c.relieve(c.pressure.fraction)
+	}
+
 	// Try to reclaim empty cache slot.
-	for i := range c.size {
-		index := probeIndex(keyHash, i, c.size)
+	for i := range c.probeDepth() {
+		index := c.index(keyHash, i)
 
-		entry := c.entries[index].Load()
-		if entry == nil || entry.keyHash == keyHash ||
-			entry.keyHash == 0 || entry.valueRef.Value() == nil {
+		entry := c.slot(index).Load()
+		if entry == nil || (entry.keyHash == keyHash && entry.key == newEntry.key) ||
+			entry.keyHash == 0 || entry.valueRef.Value() == nil || c.expired(entry) {
 			// Empty slot was found.
-			if c.entries[index].CompareAndSwap(entry, newEntry) {
+			if c.swapSlot(index, entry, newEntry) {
+				reason := EvictionReplaced
+
+				switch {
+				case entry == nil:
+				case entry.valueRef.Value() == nil:
+					reason = EvictionReclaimed
+					c.reclaimedCost.Add(entry.cost)
+				case c.expired(entry) && (entry.keyHash != keyHash || entry.key != newEntry.key):
+					reason = EvictionExpired
+				}
+
+				c.account(newEntry, entry, reason)
 				c.emptyWrites.Add(1)
+				c.writeDepths[i].Add(1)
+
+				if entry != nil && entry.keyHash == keyHash && entry.key == newEntry.key {
+					return i, entry
+				}
 
 				// Empty slot was claimed, exit.
-				return
+				return i, nil
 			}
 		}
 	}
 
-	rng := c.rng.Load()
+	c.probeOverflows.Add(1)
+	c.probeOverflowWindow.add(time.Now(), 1)
 
-	// Overwrite random cache slot.
+	// Overwrite the best of a few random unpinned cache slots, so live and recently written entries are more likely
+	// to survive.
 	for range randomEntryRetries {
-		randomIndex := int(rng.Uint64() % uint64(c.size))
+		i, index, entry := c.overwriteCandidate(keyHash)
+		if i == -1 {
+			continue
+		}
 
-		if c.entries[randomIndex].CompareAndSwap(c.entries[randomIndex].Load(), newEntry) {
+		if c.swapSlot(index, entry, newEntry) {
+			reason := EvictionOverwritten
+			if entry != nil && entry.valueRef.Value() == nil {
+				reason = EvictionReclaimed
+				c.reclaimedCost.Add(entry.cost)
+				c.deadReclaims.Add(1)
+			}
+
+			c.account(newEntry, entry, reason)
 			c.randomCASWrites.Add(1)
+			c.writeDepths[i].Add(1)
+
+			return i, nil
+		}
+	}
+
+	// Fallback to the first unpinned cache slot.
+	for i := range c.probeDepth() {
+		index := c.index(keyHash, i)
+
+		entry := c.slot(index).Load()
+		if entry != nil && entry.pinned != nil {
+			continue
+		}
+
+		if c.swapSlot(index, entry, newEntry) {
+			c.account(newEntry, entry, EvictionOverwritten)
+			c.randomWrites.Add(1)
+			c.writeDepths[i].Add(1)
+
+			return i, nil
+		}
+	}
+
+	// All slots are pinned or contended, drop the new entry.
+	return -1, nil
+}
+
+// overwriteCandidate samples random probe positions of keyHash, and returns the one holding the best unpinned victim,
+// as chosen by preferVictim, with its slot index and entry. It returns -1 if all sampled entries are pinned.
+func (c *LockFreeCache[K, V]) overwriteCandidate(keyHash uint64) (int, int, *cacheEntry[K, V]) {
+	position, index := -1, 0
+
+	var victim *cacheEntry[K, V]
+
+	for range randomEntrySamples {
+		i := rand.IntN(c.probeDepth())
+		candidate := c.index(keyHash, i)
+
+		entry := c.slot(candidate).Load()
+		if entry != nil && entry.pinned != nil {
+			continue
+		}
+
+		if position == -1 || preferVictim(entry, victim) {
+			position, index, victim = i, candidate, entry
+		}
+	}
+
+	return position, index, victim
+}
+
+// preferVictim reports whether entry is a better victim to overwrite than current: an empty slot is best,
+// then an entry which was reclaimed by the garbage collector, and otherwise the entry which was written first.
+func preferVictim[K comparable, V any](entry, current *cacheEntry[K, V]) bool {
+	switch {
+	case current == nil:
+		return false
+	case entry == nil:
+		return true
+	}
+
+	if entryDead, currentDead := entry.valueRef.Value() == nil, current.valueRef.Value() == nil; entryDead != currentDead {
+		return entryDead
+	}
+
+	return entry.written < current.written
+}
+
+// deduplicate removes entries for the same key which were concurrently claimed at another probe position.
+// The entry at the lowest probe position wins, as that is the one found by Get.
+func (c *LockFreeCache[K, V]) deduplicate(newEntry *cacheEntry[K, V], position int) {
+	for i := range c.probeDepth() {
+		if i == position {
+			continue
+		}
+
+		index := c.index(newEntry.keyHash, i)
+
+		entry := c.slot(index).Load()
+		if entry == nil || entry == newEntry || entry.keyHash != newEntry.keyHash || entry.key != newEntry.key {
+			continue
+		}
+
+		if i > position {
+			// Remove duplicate at a higher probe position.
+			if c.swapSlot(index, entry, nil) {
+				c.account(nil, entry, EvictionReplaced)
+			}
+		} else {
+			// A duplicate exists at a lower probe position, remove own entry.
+			c.retract(newEntry, position)
 			return
 		}
 	}
+}
+
+// conflicts reports whether another entry for the same key exists within the hash probe depth,
+// in which case the new entry at position is retracted.
+// Conditional inserts always yield to existing entries, so an insert which was reported as successful is never undone.
+func (c *LockFreeCache[K, V]) conflicts(newEntry *cacheEntry[K, V], position int) bool {
+	for i := range c.probeDepth() {
+		if i == position {
+			continue
+		}
+
+		entry := c.slot(c.index(newEntry.keyHash, i)).Load()
+		if entry != nil && entry != newEntry && entry.keyHash == newEntry.keyHash &&
+			entry.key == newEntry.key && entry.valueRef.Value() != nil {
+			c.retract(newEntry, position)
+			return true
+		}
+	}
 
-	// Fallback to atomic store.
-	c.entries[rng.Uint64()%uint64(c.size)].Store(newEntry)
-	c.randomWrites.Add(1)
+	return false
+}
+
+// retract removes the new entry at position without recycling it, so it can be inserted again.
+func (c *LockFreeCache[K, V]) retract(newEntry *cacheEntry[K, V], position int) {
+	if c.swapSlot(c.index(newEntry.keyHash, position), newEntry, nil) {
+		c.account(nil, newEntry, evictionNone)
+	}
 }
 
 func (c *LockFreeCache[K, V]) Get(key K) (V, bool) {
+	value, ok := c.GetRef(key)
+	if !ok {
+		return *new(V), false
+	}
+
+	return *value, true
+}
+
+// GetRef is like Get, but returns the value by reference instead of copying it, which is cheaper for large values.
+// The value is shared with the cache and all other callers, so by convention it must not be modified.
+// Holding the pointer keeps the value alive, as any other reference to it.
+func (c *LockFreeCache[K, V]) GetRef(key K) (*V, bool) {
 	if !c.initialized.Load() {
 		// LockFreeCache was not initialized.
-		return *new(V), false
+		return nil, false
 	}
 
-	keyHash := maphash.Comparable(c.seed, key)
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	if c.getLatency != nil {
+		defer c.getLatency.record(time.Now())
+	}
 
-	for i := range c.hashProbeDepth {
-		index := probeIndex(keyHash, i, c.size)
+	keyHash := c.hash(key)
 
-		entry := c.entries[index].Load()
+	for i, index := range c.candidates(keyHash) {
+		entry, value, match := c.slot(index).read(keyHash, key)
 		if entry == nil || entry.keyHash == 0 {
 			continue
 		}
 
-		if entry.valueRef.Value() == nil {
+		if value == nil {
 			c.invalidate(entry, index)
 			continue
 		}
 
 		// Found entry, return value if still valid.
-		if entry.keyHash == keyHash {
-			if value := entry.valueRef.Value(); value != nil {
-				c.readHits.Add(1)
-				return *value, true
+		if match {
+			if c.expired(entry) {
+				c.expire(entry, index)
+				break
 			}
 
-			c.invalidate(entry, index)
+			c.readHits.Add(1)
+			c.hitDepths[i].Add(1)
+
+			if c.trackHitRate {
+				c.hitWindow.add(time.Now(), 1)
+			}
+
+			if c.refreshAhead != nil {
+				c.refreshAhead.heat.Add(key, 1)
+			}
+
+			if entry.access != nil {
+				entry.access.record()
+			}
+
+			if c.hooks.OnHit != nil {
+				c.hooks.OnHit(key)
+			}
+
+			return value, true
+		}
+	}
 
-			break
+	if c.overflow != nil {
+		if value, ok := c.promote(key); ok {
+			return value, true
 		}
 	}
 
 	c.readMisses.Add(1)
 
-	return *new(V), false
-}
+	if c.ghosts != nil && c.ghosts.remove(keyHash) {
+		c.ghostHits.Add(1)
+	}
 
-func (c *LockFreeCache[K, V]) Len() int {
-	count := 0
+	if c.trackHitRate {
+		c.missWindow.add(time.Now(), 1)
+	}
 
-	for i := range c.size {
-		entry := c.entries[i].Load()
-		if entry != nil && entry.valueRef.Value() != nil {
-			count++
-		}
+	if c.hooks.OnMiss != nil {
+		c.hooks.OnMiss(key)
 	}
 
-	return count
+	return nil, false
 }
 
-func (c *LockFreeCache[K, V]) Cap() int {
-	return c.size
+// GetE is like Get, but returns ErrNotInitialized, ErrNotFound or ErrExpired instead of a boolean.
+func (c *LockFreeCache[K, V]) GetE(key K) (V, error) {
+	if err := c.usable(); err != nil {
+		return *new(V), err
+	}
+
+	value, ok := c.Get(key)
+	if !ok {
+		if value, stale := c.findStale(key); value != nil && stale {
+			return *new(V), ErrExpired
+		}
+
+		return *new(V), ErrNotFound
+	}
+
+	return value, nil
+}
+
+// PutIfAbsent stores value for key only if no live entry exists for key, and reports whether it did.
+// Concurrent calls for the same key have a single winner.
+func (c *LockFreeCache[K, V]) PutIfAbsent(key K, value *V) bool {
+	if !c.initialized.Load() {
+		return false
+	}
+
+	if !c.putIfAbsent(c.newEntry(key, value, time.Now().UnixNano())) {
+		return false
+	}
+
+	c.publish(key)
+
+	return true
+}
+
+// Swap stores value for key and returns the value it replaced, if a live one existed.
+func (c *LockFreeCache[K, V]) Swap(key K, value *V) (V, bool) {
+	if !c.initialized.Load() {
+		return *new(V), false
+	}
+
+	replaced := c.put(c.newEntry(key, value, time.Now().UnixNano()))
+	c.publish(key)
+
+	if replaced == nil {
+		return *new(V), false
+	}
+
+	old := replaced.valueRef.Value()
+	if old == nil {
+		return *new(V), false
+	}
+
+	return *old, true
+}
+
+// Delete removes the entry for key.
+func (c *LockFreeCache[K, V]) Delete(key K) {
+	if !c.initialized.Load() {
+		return
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	c.publish(key)
+
+	for {
+		index, entry, _ := c.find(key)
+		if entry == nil || c.remove(entry, index, EvictionDeleted) {
+			return
+		}
+	}
+}
+
+// GetAndDelete removes the entry for key and returns its value, if a live one existed.
+// Concurrent calls for the same key return the value at most once.
+func (c *LockFreeCache[K, V]) GetAndDelete(key K) (V, bool) {
+	if !c.initialized.Load() {
+		return *new(V), false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	c.publish(key)
+
+	for {
+		index, entry, value := c.find(key)
+		if entry == nil {
+			return *new(V), false
+		}
+
+		if c.remove(entry, index, EvictionDeleted) {
+			return *value, true
+		}
+	}
+}
+
+// CompareAndSwap stores newValue for key if the current value is equal to old.
+// Values are compared with the function set by WithEqualFunc, with == if V is comparable,
+// or otherwise by pointer identity.
+func (c *LockFreeCache[K, V]) CompareAndSwap(key K, old, newValue *V) bool {
+	if !c.initialized.Load() {
+		return false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	newEntry := c.newEntry(key, newValue, time.Now().UnixNano())
+
+	for {
+		index, entry, value := c.find(key)
+		if entry == nil || !c.equal(value, old) {
+			return false
+		}
+
+		if c.swapSlot(index, entry, newEntry) {
+			c.account(newEntry, entry, EvictionReplaced)
+			c.publish(key)
+
+			return true
+		}
+	}
+}
+
+// CompareAndDelete deletes the entry for key if its value is equal to old.
+// Values are compared as in CompareAndSwap.
+func (c *LockFreeCache[K, V]) CompareAndDelete(key K, old *V) bool {
+	if !c.initialized.Load() {
+		return false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	for {
+		index, entry, value := c.find(key)
+		if entry == nil || !c.equal(value, old) {
+			return false
+		}
+
+		if c.remove(entry, index, EvictionDeleted) {
+			c.publish(key)
+			return true
+		}
+	}
+}
+
+// Update atomically replaces the value for key with the result of fn, which receives the current value
+// and whether it exists. If fn returns nil, the entry is deleted. Update returns the stored value.
+// On contention fn is retried, so it may be called more than once and must be free of side effects.
+func (c *LockFreeCache[K, V]) Update(key K, fn func(current V, ok bool) *V) (V, bool) {
+	if !c.initialized.Load() {
+		return *new(V), false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	for {
+		index, entry, value := c.find(key)
+
+		var current V
+		if value != nil {
+			current = *value
+		}
+
+		newValue := fn(current, value != nil)
+		if newValue == nil {
+			if entry == nil || c.remove(entry, index, EvictionDeleted) {
+				c.publish(key)
+				return *new(V), false
+			}
+
+			continue
+		}
+
+		newEntry := c.newEntry(key, newValue, time.Now().UnixNano())
+
+		if entry == nil {
+			if c.putIfAbsent(newEntry) {
+				c.publish(key)
+				return *newValue, true
+			}
+
+			continue
+		}
+
+		if c.swapSlot(index, entry, newEntry) {
+			c.account(newEntry, entry, EvictionReplaced)
+			c.publish(key)
+
+			return *newValue, true
+		}
+	}
+}
+
+// Pin holds a strong reference to the value of key, so it is never reclaimed by the garbage collector
+// nor overwritten to make room for other entries, until Unpin or Delete is called.
+// Overwriting a pinned key keeps it pinned. It reports whether a live entry was found.
+func (c *LockFreeCache[K, V]) Pin(key K) bool {
+	return c.setPinned(key, true)
+}
+
+// Unpin releases the strong reference held by Pin. It reports whether the key was pinned.
+func (c *LockFreeCache[K, V]) Unpin(key K) bool {
+	return c.setPinned(key, false)
+}
+
+func (c *LockFreeCache[K, V]) setPinned(key K, pin bool) bool {
+	return c.amend(key, func(entry *cacheEntry[K, V], value *V) bool {
+		if !pin && entry.pinned == nil {
+			return false
+		}
+
+		entry.pinned = nil
+
+		if pin {
+			entry.pinned = value
+		}
+
+		return true
+	})
+}
+
+// ExpireAt sets the entry for key to expire at, or never if at is the zero time, and reports whether
+// a live entry was found. It overrides the time to live set by WithTTL for this entry.
+func (c *LockFreeCache[K, V]) ExpireAt(key K, at time.Time) bool {
+	return c.amend(key, func(entry *cacheEntry[K, V], _ *V) bool {
+		entry.expires = expiry(at)
+		return true
+	})
+}
+
+// PutWithExpiry stores value for key, which expires at, or never if at is the zero time,
+// instead of after the time to live set by WithTTL. It suits entries tied to external expirations,
+// such as the expiry of a token or the deadline of a lease.
+func (c *LockFreeCache[K, V]) PutWithExpiry(key K, value *V, at time.Time) {
+	if !c.initialized.Load() {
+		return
+	}
+
+	if c.putLatency != nil {
+		defer c.putLatency.record(time.Now())
+	}
+
+	entry := c.newEntry(key, value, time.Now().UnixNano())
+	entry.expires = expiry(at)
+
+	c.admit(entry)
+	c.publish(key)
+}
+
+// expiry returns at in Unix nanoseconds, or 0 if at is the zero time.
+func expiry(at time.Time) int64 {
+	if at.IsZero() {
+		return 0
+	}
+
+	return at.UnixNano()
+}
+
+// amend swaps the live entry for key with a copy changed by fn, and reports whether it did.
+// fn receives the copy and the value of the entry, and reports false to leave the entry unchanged.
+func (c *LockFreeCache[K, V]) amend(key K, fn func(entry *cacheEntry[K, V], value *V) bool) bool {
+	if !c.initialized.Load() {
+		return false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	for {
+		index, entry, value := c.find(key)
+		if entry == nil {
+			return false
+		}
+
+		// Entries are immutable once stored, so swap in a copy.
+		amended := *entry
+		if !fn(&amended, value) {
+			return false
+		}
+
+		if c.swapSlot(index, entry, &amended) {
+			if c.wheel != nil && amended.expires != 0 && amended.expires != entry.expires {
+				c.wheel.add(key, amended.expires)
+			}
+
+			// Record the change, as puts record whether entries are pinned and when they expire.
+			if log := c.appendLog.Load(); log != nil {
+				log.put(&amended)
+			}
+
+			if replicator := c.replicator.Load(); replicator != nil {
+				replicator.put(&amended)
+			}
+
+			return true
+		}
+	}
+}
+
+// Peek returns the value for key without updating metrics or invalidating reclaimed entries.
+// It is intended for health checks and debugging.
+func (c *LockFreeCache[K, V]) Peek(key K) (V, bool) {
+	if !c.initialized.Load() {
+		return *new(V), false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	_, _, value := c.find(key)
+	if value == nil {
+		return *new(V), false
+	}
+
+	return *value, true
+}
+
+// Contains reports whether a live entry exists for key, without copying the value.
+func (c *LockFreeCache[K, V]) Contains(key K) bool {
+	if !c.initialized.Load() {
+		return false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	_, entry, _ := c.find(key)
+
+	return entry != nil
+}
+
+// GetMulti looks up multiple keys.
+// It returns the values which were found and the keys which were missing.
+func (c *LockFreeCache[K, V]) GetMulti(keys []K) (map[K]V, []K) {
+	found := make(map[K]V, len(keys))
+	missing := make([]K, 0)
+
+	for _, key := range keys {
+		value, ok := c.Get(key)
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+
+		found[key] = value
+	}
+
+	return found, missing
+}
+
+// PutMulti stores multiple entries.
+func (c *LockFreeCache[K, V]) PutMulti(values map[K]*V) {
+	if !c.initialized.Load() {
+		return
+	}
+
+	written := time.Now().UnixNano()
+
+	for key, value := range values {
+		c.admit(c.newEntry(key, value, written))
+		c.publish(key)
+	}
+}
+
+func (c *LockFreeCache[K, V]) Len() int {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	count := 0
+
+	for i := range c.size {
+		entry := c.slot(i).Load()
+		if entry != nil && entry.valueRef.Value() != nil {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ApproxLen estimates Len from a random sample of the given fraction of slots, but at least 1024 slots,
+// so it takes time proportional to the sample instead of the whole table.
+// With n sampled slots, the estimate is within Cap()/sqrt(n) of the number of live entries at the time of the
+// scan with a probability of 95%; for example within 1% of the capacity when sampling 10000 slots.
+// A fraction of 1, or a sample which covers the whole table, counts exactly as Len.
+func (c *LockFreeCache[K, V]) ApproxLen(fraction float64) int {
+	samples := max(approxLenMinSamples, int(math.Ceil(fraction*float64(c.size))))
+	if samples >= c.size || fraction >= 1 {
+		return c.Len()
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	count := 0
+
+	for range samples {
+		entry := c.slot(rand.IntN(c.size)).Load()
+		if entry != nil && entry.valueRef.Value() != nil {
+			count++
+		}
+	}
+
+	return int(math.Round(float64(count) * float64(c.size) / float64(samples)))
+}
+
+func (c *LockFreeCache[K, V]) Cap() int {
+	return c.size
 }
 
 func (c *LockFreeCache[K, V]) Metrics() Metrics {
-	return Metrics{
+	metrics := Metrics{
 		ReadMisses:      c.readMisses.Load(),
 		ReadHits:        c.readHits.Load(),
 		FirstWrites:     c.firstWrites.Load(),
@@ -202,19 +1287,477 @@ func (c *LockFreeCache[K, V]) Metrics() Metrics {
 		EmptyWrites:     c.emptyWrites.Load(),
 		RandomCASWrites: c.randomCASWrites.Load(),
 		RandomWrites:    c.randomWrites.Load(),
+		ProbeOverflows:  c.probeOverflows.Load(),
+		CurrentCost:     c.cost.Load(),
+		CostEvictions:   c.costEvictions.Load(),
+		ReclaimedCost:   c.reclaimedCost.Load(),
+
+		PressureEvictions:     c.pressureEvictions.Load(),
+		DroppedEvictionEvents: c.droppedEvictionEvents.Load(),
+		OverflowHits:          c.overflowHits.Load(),
+		LoaderPanics:          c.loaderPanics.Load(),
+		DroppedAsyncPuts:      c.droppedAsyncPuts.Load(),
+		DeadReclaims:          c.deadReclaims.Load(),
+		DoorkeeperRejects:     c.doorkeeperRejects.Load(),
+		GhostHits:             c.ghostHits.Load(),
+
+		GetLatency: c.getLatency.histogram(),
+		PutLatency: c.putLatency.histogram(),
+	}
+
+	for reason := range metrics.Evictions {
+		metrics.Evictions[reason] = c.evictions[reason].Load()
+	}
+
+	return metrics
+}
+
+// ResetMetrics sets all metric counters to zero. The current cost is not a counter and is kept.
+// Counters are reset one by one, so concurrent operations may be partially counted.
+func (c *LockFreeCache[K, V]) ResetMetrics() {
+	c.readMisses.reset()
+	c.readHits.reset()
+	c.firstWrites.reset()
+	c.probeWrites.reset()
+	c.emptyWrites.reset()
+	c.overflowHits.reset()
+	c.randomCASWrites.Store(0)
+	c.randomWrites.Store(0)
+	c.probeOverflows.Store(0)
+	c.probeOverflowWindow.reset()
+	c.hitWindow.reset()
+	c.missWindow.reset()
+	c.costEvictions.Store(0)
+	c.reclaimedCost.Store(0)
+	c.deadReclaims.Store(0)
+	c.doorkeeperRejects.Store(0)
+	c.ghostHits.Store(0)
+	c.pressureEvictions.Store(0)
+	c.loaderPanics.Store(0)
+	c.droppedAsyncPuts.Store(0)
+	c.droppedEvictionEvents.Store(0)
+	c.getLatency.reset()
+	c.putLatency.reset()
+
+	for reason := range c.evictions {
+		c.evictions[reason].Store(0)
+	}
+
+	for i := range c.hitDepths {
+		c.hitDepths[i].Store(0)
+		c.writeDepths[i].Store(0)
+	}
+}
+
+// ProbeStats returns the number of hits and writes at each probe index.
+func (c *LockFreeCache[K, V]) ProbeStats() ProbeStats {
+	stats := ProbeStats{
+		Hits:   make([]uint64, len(c.hitDepths)),
+		Writes: make([]uint64, len(c.writeDepths)),
+	}
+
+	for i := range c.hitDepths {
+		stats.Hits[i] = c.hitDepths[i].Load()
+		stats.Writes[i] = c.writeDepths[i].Load()
+	}
+
+	return stats
+}
+
+// PublishExpvar publishes the metrics of the cache as an expvar under name, encoded as JSON.
+// Published variables cannot be removed, so the cache is kept alive for the rest of the process.
+func (c *LockFreeCache[K, V]) PublishExpvar(name string) error {
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %q is already published", name)
+	}
+
+	expvar.Publish(name, expvar.Func(func() any {
+		return c.Metrics()
+	}))
+
+	return nil
+}
+
+// HitRate returns the ratio of reads which found a live entry within the given window,
+// which is capped at 15 minutes and rounded to 10 seconds. It returns 0 if there were no reads
+// within the window, or if hit rate tracking is not enabled with WithHitRateTracking.
+func (c *LockFreeCache[K, V]) HitRate(window time.Duration) float64 {
+	now := time.Now()
+
+	hits, _ := c.hitWindow.sum(now, window)
+	misses, _ := c.missWindow.sum(now, window)
+
+	if hits+misses == 0 {
+		return 0
+	}
+
+	return float64(hits) / float64(hits+misses)
+}
+
+// ProbeOverflowRate returns the number of probe overflows per second within the given window,
+// which is capped at one minute. See Metrics.ProbeOverflows.
+func (c *LockFreeCache[K, V]) ProbeOverflowRate(window time.Duration) float64 {
+	count, covered := c.probeOverflowWindow.sum(time.Now(), window)
+	if covered == 0 {
+		return 0
+	}
+
+	return float64(count) / covered.Seconds()
+}
+
+// Clone returns an independent cache with the same configuration and a copy of all live entries.
+// Metrics of the clone start at zero.
+// The clone does not share the overflow tier, invalidator, eviction events or context of the cache, and does not
+// refresh ahead, queue async puts or tune its probe depth. Hooks are shared, and expiries are tracked by its own
+// expiration wheel.
+func (c *LockFreeCache[K, V]) Clone() *LockFreeCache[K, V] {
+	if !c.initialized.Load() {
+		return &LockFreeCache[K, V]{}
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	clone := newLockFreeCache(c.size, c.seed, c.config.detached())
+	// Entries are copied to the same slots, so the clone probes as deep as the source does now.
+	clone.hashProbeDepth.Store(int64(c.probeDepth()))
+
+	for i := range c.size {
+		entry := c.slot(i).Load()
+		if entry == nil || entry.keyHash == 0 || entry.valueRef.Value() == nil || c.outdated(entry) {
+			continue
+		}
+
+		// Copy the entry, as the original entry may be recycled by the source cache.
+		copied := &cacheEntry[K, V]{
+			key:      entry.key,
+			keyHash:  entry.keyHash,
+			valueRef: entry.valueRef,
+			written:  entry.written,
+			cost:     entry.cost,
+			expires:  entry.expires,
+			delta:    entry.delta,
+			version:  entry.version,
+			pinned:   entry.pinned,
+			owned:    entry.owned,
+		}
+
+		if entry.access != nil {
+			// Accesses of the clone are tracked apart from the source, from the stats of the source so far.
+			copied.access = &entryAccess{}
+			copied.access.last.Store(entry.access.last.Load())
+			copied.access.hits.Store(entry.access.hits.Load())
+		}
+
+		clone.slot(i).Store(copied)
+
+		if clone.wheel != nil && copied.expires != 0 {
+			clone.wheel.add(copied.key, copied.expires)
+		}
+
+		if clone.groups != nil {
+			clone.updateControl(clone.slot(i), i)
+		}
+
+		clone.cost.Add(entry.cost)
+	}
+
+	return clone
+}
+
+// Merge inserts all live entries of other into the cache.
+// Conflicting keys are resolved according to the merge policy.
+// It returns the number of entries which were inserted.
+func (c *LockFreeCache[K, V]) Merge(other *LockFreeCache[K, V], policy MergePolicy) int {
+	if !c.initialized.Load() || !other.initialized.Load() {
+		return 0
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+	defer other.reclaim.exit(other.reclaim.enter())
+
+	merged := 0
+
+	for i := range other.size {
+		entry := other.slot(i).Load()
+		if entry == nil || entry.keyHash == 0 {
+			continue
+		}
+
+		// Copy fields, as the entry may be recycled by the other cache.
+		key, valueRef, written := entry.key, entry.valueRef, entry.written
+
+		value := valueRef.Value()
+		if value == nil {
+			continue
+		}
+
+		if _, existing, _ := c.find(key); existing != nil && !policy.replace(existing.written, written) {
+			continue
+		}
+
+		c.put(c.newEntry(key, value, written))
+		merged++
+	}
+
+	return merged
+}
+
+// find returns the slot index, live entry and value for key within the hash probe depth, or nil.
+// It has no side effects on metrics or entries. Callers must have entered the reclaimer,
+// and may only use the entry until they exit it.
+func (c *LockFreeCache[K, V]) find(key K) (int, *cacheEntry[K, V], *V) {
+	keyHash := c.hash(key)
+
+	for _, index := range c.candidates(keyHash) {
+		entry, value, match := c.slot(index).read(keyHash, key)
+		if match && value != nil && !c.expired(entry) {
+			return index, entry, value
+		}
+	}
+
+	return -1, nil, nil
+}
+
+// read returns the entry of the slot, its value, and whether it holds key. The read is retried if the slot was
+// written meanwhile, so the key and value are never those of an entry which was reused for another key.
+func (s *tableSlot[K, V]) read(keyHash uint64, key K) (*cacheEntry[K, V], *V, bool) {
+	for {
+		sequence := s.sequence.Load()
+
+		entry := s.Load()
+		if entry == nil {
+			return nil, nil, false
+		}
+
+		value := entry.valueRef.Value()
+		match := entry.keyHash == keyHash && entry.key == key
+
+		if s.sequence.Load() == sequence {
+			return entry, value, match
+		}
+	}
+}
+
+// promote reads the entry for key back from the overflow tier into the table.
+// Values are decoded into a new allocation, which is again only kept while it is referenced elsewhere.
+func (c *LockFreeCache[K, V]) promote(key K) (*V, bool) {
+	value, expires, ok := c.overflow.get(key)
+	if !ok {
+		return nil, false
+	}
+
+	c.overflowHits.Add(1)
+
+	entry := c.newEntry(key, value, time.Now().UnixNano())
+	entry.promoted = true
+
+	if expires != 0 {
+		entry.expires = expires
+	}
+	c.put(entry)
+
+	return value, true
+}
+
+// findStale is like find, but also returns expired entries, and whether the entry is expired.
+func (c *LockFreeCache[K, V]) findStale(key K) (*V, bool) {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	keyHash := c.hash(key)
+
+	for _, index := range c.candidates(keyHash) {
+		entry, value, match := c.slot(index).read(keyHash, key)
+		if match && value != nil && !c.outdated(entry) {
+			return value, c.expired(entry)
+		}
+	}
+
+	return nil, false
+}
+
+// expired reports whether the time to live of the entry has passed, or its generation was invalidated.
+func (c *LockFreeCache[K, V]) expired(entry *cacheEntry[K, V]) bool {
+	return c.outdated(entry) || (entry.expires != 0 && time.Now().UnixNano() >= entry.expires)
+}
+
+// expire removes an expired entry, unless expired entries are kept to be served stale.
+// Entries of invalidated generations are never served stale.
+func (c *LockFreeCache[K, V]) expire(entry *cacheEntry[K, V], index int) {
+	if !c.staleWhileRevalidate || c.outdated(entry) {
+		c.remove(entry, index, EvictionExpired)
 	}
 }
 
-func (c *LockFreeCache[K, V]) invalidate(entry *cacheEntry[V], index int) {
+func (c *LockFreeCache[K, V]) invalidate(entry *cacheEntry[K, V], index int) {
 	// Invalidate cache entry if underlying value was cleaned up by garbage collector.
-	if c.entries[index].CompareAndSwap(entry, nil) {
-		// Add invalidated cache entry back to the pool.
-		entry.keyHash = 0
-		entry.valueRef = weak.Pointer[V]{}
-		c.pool.Put(any(entry))
+	cost := entry.cost
+	if c.remove(entry, index, EvictionReclaimed) {
+		c.reclaimedCost.Add(cost)
+	}
+}
+
+// remove clears the slot if it still holds entry, and reports whether it did.
+func (c *LockFreeCache[K, V]) remove(entry *cacheEntry[K, V], index int, reason EvictionReason) bool {
+	if !c.detach(entry, index, reason) {
+		return false
+	}
+
+	// Add removed cache entry back to the pool, once concurrent readers are done with it.
+	c.reclaim.retire(entry)
+
+	return true
+}
+
+// detach clears the slot if it still holds entry without recycling the entry, and reports whether it did.
+func (c *LockFreeCache[K, V]) detach(entry *cacheEntry[K, V], index int, reason EvictionReason) bool {
+	if !c.swapSlot(index, entry, nil) {
+		return false
+	}
+
+	c.account(nil, entry, reason)
+
+	return true
+}
+
+// relieve drops the given fraction of unpinned entries, to relieve memory pressure.
+func (c *LockFreeCache[K, V]) relieve(fraction float64) {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	threshold := uint64(fraction * math.MaxUint64)
+	evicted := 0
+
+	for index := range c.size {
+		entry := c.slot(index).Load()
+		if entry == nil || entry.pinned != nil || rand.Uint64() > threshold {
+			continue
+		}
+
+		if c.detach(entry, index, EvictionCapacity) {
+			c.pressureEvictions.Add(1)
+			evicted++
+		}
+	}
+
+	c.logger.Debug("relieved memory pressure", slog.Int("evicted", evicted))
+}
+
+// account updates the total cost and invokes hooks after oldEntry was replaced by newEntry for the given reason.
+// Either entry may be nil. It must only be called by the goroutine which swapped the entries.
+func (c *LockFreeCache[K, V]) account(newEntry, oldEntry *cacheEntry[K, V], reason EvictionReason) {
+	if newEntry != nil && c.hooks.OnInsert != nil {
+		if value := newEntry.valueRef.Value(); value != nil {
+			c.hooks.OnInsert(newEntry.key, value)
+		}
+	}
+
+	if c.ghosts != nil {
+		// A key which is stored again is no longer a ghost. Keys evicted for room would be kept by a bigger cache.
+		if newEntry != nil {
+			c.ghosts.remove(newEntry.keyHash)
+		}
+
+		if oldEntry != nil && (reason == EvictionOverwritten || reason == EvictionCapacity) {
+			c.ghosts.add(oldEntry.keyHash)
+		}
+	}
+
+	if oldEntry != nil && oldEntry.keyHash != 0 && reason != evictionNone {
+		c.evictions[reason].Add(1)
+
+		if c.hooks.OnEvict != nil {
+			c.hooks.OnEvict(oldEntry.key, oldEntry.valueRef.Value(), reason)
+		}
+
+		if c.evictionEvents != nil {
+			c.notify(oldEntry.keyHash, reason)
+		}
+
+		if reason == EvictionReclaimed && c.onReclaim != nil {
+			c.onReclaim(oldEntry.keyHash)
+		}
+	}
+
+	if c.overflow != nil {
+		switch {
+		case newEntry != nil && !newEntry.promoted:
+			if value := newEntry.valueRef.Value(); value != nil {
+				c.overflow.put(newEntry.key, value, newEntry.expires)
+			}
+		case oldEntry != nil && reason == EvictionDeleted:
+			c.overflow.Delete(oldEntry.key)
+		}
+	}
+
+	if c.wheel != nil && newEntry != nil && newEntry.expires != 0 {
+		c.wheel.add(newEntry.key, newEntry.expires)
+	}
+
+	if newEntry != nil && c.waiting.Load() > 0 {
+		c.wake(newEntry)
+	}
+
+	if log := c.appendLog.Load(); log != nil {
+		switch {
+		case newEntry != nil:
+			log.put(newEntry)
+		case oldEntry != nil && reason == EvictionDeleted:
+			log.delete(oldEntry.key)
+		}
+	}
+
+	if replicator := c.replicator.Load(); replicator != nil {
+		switch {
+		case newEntry != nil:
+			replicator.put(newEntry)
+		case oldEntry != nil && reason == EvictionDeleted:
+			replicator.delete(oldEntry.key)
+		}
+	}
+
+	var delta int64
+
+	if newEntry != nil {
+		delta += newEntry.cost
+	}
+
+	if oldEntry != nil {
+		delta -= oldEntry.cost
+	}
+
+	if delta == 0 {
+		return
+	}
+
+	if cost := c.cost.Add(delta); delta > 0 && c.maxCost > 0 && cost > c.maxCost {
+		c.evict()
 	}
 }
 
-func probeIndex(keyHash uint64, i, size int) int {
-	return int((keyHash + uint64(i)*(keyHash>>32|keyHash<<32)) % uint64(size))
+// evict removes random unpinned entries until the total cost is within the max cost.
+// Entries reclaimed by the garbage collector are invalidated on the way.
+// Evicted entries are not recycled, as evict may run while a write still refers to its new entry.
+func (c *LockFreeCache[K, V]) evict() {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	// Bound the number of attempts, in case all remaining entries are pinned.
+	for attempt := 0; attempt < 2*c.size && c.cost.Load() > c.maxCost; attempt++ {
+		index := rand.IntN(c.size)
+
+		entry := c.slot(index).Load()
+		if entry == nil || entry.pinned != nil {
+			continue
+		}
+
+		if entry.valueRef.Value() == nil {
+			if c.detach(entry, index, EvictionReclaimed) {
+				c.reclaimedCost.Add(entry.cost)
+			}
+
+			continue
+		}
+
+		if c.detach(entry, index, EvictionCapacity) {
+			c.costEvictions.Add(1)
+		}
+	}
 }
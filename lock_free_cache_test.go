@@ -3,11 +3,26 @@ package cache_test
 import (
 	"context"
 	cryptorand "crypto/rand"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log/slog"
+	"math"
 	mathrand "math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
 )
 
 type keyValue struct {
@@ -68,7 +83,10 @@ func TestLockFreeCache(t *testing.T) {
 				testCache.Put(keyValue.key, &keyValue.value)
 
 				if mathrand.IntN(2) == 1 {
-					keyValues <- keyValue
+					select {
+					case keyValues <- keyValue:
+					case <-ctx.Done():
+					}
 				}
 			}
 		}
@@ -94,3 +112,1598 @@ func TestLockFreeCache(t *testing.T) {
 
 	t.Logf("metrics: %+v", testCache.Metrics())
 }
+
+func TestLockFreeCacheClone(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+
+	testCache.Put(key, &val)
+
+	clone := testCache.Clone()
+	check.Equal(t, clone.Cap(), testCache.Cap())
+
+	value, ok := clone.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, val)
+
+	key2 := cryptorand.Text()
+	val2 := mathrand.Uint64()
+
+	clone.Put(key2, &val2)
+
+	_, ok = testCache.Get(key2)
+	check.True(t, !ok)
+
+	runtime.KeepAlive(&val)
+	runtime.KeepAlive(&val2)
+}
+
+func TestLockFreeCacheCloneIndependent(t *testing.T) {
+	t.Parallel()
+
+	tier, err := cache.OpenDiskTier(filepath.Join(t.TempDir(), "tier"), 64, 64, cache.JSONCodec[string]{}, cache.JSONCodec[uint64]{})
+	check.True(t, err == nil)
+
+	defer tier.Close()
+
+	events := make(chan cache.EvictionEvent, 16)
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithOverflow(tier),
+		cache.WithAccessTracking[string, uint64](),
+		cache.WithEvictionEvents[string, uint64](events),
+		cache.WithExpirationWheel[string, uint64](time.Millisecond),
+	)
+	defer testCache.Close()
+
+	val, val2 := uint64(1), uint64(2)
+	testCache.Put("key", &val)
+	testCache.Put("hit", &val)
+	testCache.PutWithExpiry("short", &val, time.Now().Add(200*time.Millisecond))
+
+	clone := testCache.Clone()
+	defer clone.Close()
+
+	clone.Put("new", &val2)
+	clone.Delete("key")
+	clone.Get("hit")
+	clone.Get("hit")
+
+	// The source, its overflow tier and its eviction events are unaffected by the clone.
+	check.True(t, testCache.Contains("key"))
+	check.True(t, !testCache.Contains("new"))
+	check.Equal(t, len(events), 0)
+
+	_, ok := tier.Get("key")
+	check.True(t, ok)
+
+	_, ok = tier.Get("new")
+	check.True(t, !ok)
+
+	info, _ := testCache.GetEntryInfo("hit")
+	check.Equal(t, info.Hits, 0)
+
+	info, _ = clone.GetEntryInfo("hit")
+	check.Equal(t, info.Hits, 2)
+
+	// The expiration wheel of the clone removes the copied entry once it expires.
+	for range 1000 {
+		if clone.Len() == 2 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	check.Equal(t, clone.Len(), 2)
+
+	runtime.KeepAlive(&val)
+	runtime.KeepAlive(&val2)
+}
+
+func TestLockFreeCacheMerge(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+	other := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key, key2 := cryptorand.Text(), cryptorand.Text()
+	existing, incoming, val2 := mathrand.Uint64(), mathrand.Uint64(), mathrand.Uint64()
+
+	testCache.Put(key, &existing)
+	other.Put(key, &incoming)
+	other.Put(key2, &val2)
+
+	check.Equal(t, testCache.Merge(other, cache.MergeKeepExisting), 1)
+
+	value, ok := testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, existing)
+
+	value, ok = testCache.Get(key2)
+	check.True(t, ok)
+	check.Equal(t, value, val2)
+
+	check.Equal(t, testCache.Merge(other, cache.MergeKeepNewest), 1)
+
+	value, ok = testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, incoming)
+
+	runtime.KeepAlive(&existing)
+	runtime.KeepAlive(&incoming)
+	runtime.KeepAlive(&val2)
+}
+
+func TestLockFreeCacheMulti(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	val, val2 := mathrand.Uint64(), mathrand.Uint64()
+	values := map[string]*uint64{
+		cryptorand.Text(): &val,
+		cryptorand.Text(): &val2,
+	}
+
+	testCache.PutMulti(values)
+
+	keys := []string{cryptorand.Text()}
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	found, missing := testCache.GetMulti(keys)
+	check.Equal(t, len(found), len(values))
+	check.Equal(t, len(missing), 1)
+	check.Equal(t, missing[0], keys[0])
+
+	for key, value := range values {
+		check.Equal(t, found[key], *value)
+	}
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCachePeek(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+
+	testCache.Put(key, &val)
+
+	value, ok := testCache.Peek(key)
+	check.True(t, ok)
+	check.Equal(t, value, val)
+
+	_, ok = testCache.Peek(cryptorand.Text())
+	check.True(t, !ok)
+
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.ReadHits, 0)
+	check.Equal(t, metrics.ReadMisses, 0)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheCostFunc(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithCostFunc(func(_ string, value uint64) int64 {
+		return int64(value)
+	}))
+
+	key, key2 := cryptorand.Text(), cryptorand.Text()
+	val, val2, val3 := uint64(10), uint64(20), uint64(5)
+
+	testCache.Put(key, &val)
+	testCache.Put(key2, &val2)
+	check.Equal(t, testCache.Metrics().CurrentCost, 30)
+
+	// Cost is re-evaluated when a key is overwritten.
+	testCache.Put(key, &val3)
+	check.Equal(t, testCache.Metrics().CurrentCost, 25)
+
+	runtime.KeepAlive(&val)
+	runtime.KeepAlive(&val2)
+	runtime.KeepAlive(&val3)
+}
+
+func TestLockFreeCacheCostFuncNilValue(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithCostFunc(func(_ string, value uint64) int64 {
+		return int64(value)
+	}))
+
+	testCache.Put(cryptorand.Text(), nil)
+	check.Equal(t, testCache.Metrics().CurrentCost, 0)
+}
+
+func TestLockFreeCacheCostSweep(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithCostFunc(func(string, [4]uint64) int64 { return 10 }),
+		cache.WithCostSweepInterval[string, [4]uint64](10*time.Millisecond),
+	)
+
+	for range 10 {
+		testCache.Put(cryptorand.Text(), &[4]uint64{1, 2, 3, 4})
+	}
+
+	check.Equal(t, testCache.Metrics().CurrentCost, 100)
+
+	timeout := time.After(5 * time.Second)
+
+	// The sweep releases the cost of reclaimed values without any access to their keys.
+	for {
+		runtime.GC()
+
+		metrics := testCache.Metrics()
+		if metrics.CurrentCost < 100 {
+			check.Equal(t, metrics.CurrentCost+metrics.ReclaimedCost, 100)
+			return
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("cost of reclaimed values was not released")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestLockFreeCacheInvalidCostSweep(t *testing.T) {
+	t.Parallel()
+
+	// An invalid interval is ignored by NewLockFreeCache, so the sweep falls back to the default.
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithCostFunc(func(string, uint64) int64 { return 1 }),
+		cache.WithCostSweepInterval[string, uint64](-time.Second),
+	)
+	defer testCache.Close()
+
+	val := new(uint64)
+	testCache.Put(cryptorand.Text(), val)
+	check.Equal(t, testCache.Metrics().CurrentCost, 1)
+	runtime.KeepAlive(val)
+}
+
+func TestLockFreeCacheContains(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+
+	testCache.Put(key, &val)
+
+	check.True(t, testCache.Contains(key))
+	check.True(t, !testCache.Contains(cryptorand.Text()))
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheApproxLen(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](1 << 16)
+
+	values := make([]uint64, 1<<14)
+	for i := range values {
+		testCache.Put(cryptorand.Text(), &values[i])
+	}
+
+	length := testCache.Len()
+
+	check.Equal(t, testCache.ApproxLen(1), length)
+
+	// Allow three times the documented error bound, so the test practically never fails by chance.
+	samples := float64(testCache.Cap()) / 10
+	bound := 3 * float64(testCache.Cap()) / math.Sqrt(samples)
+	check.True(t, math.Abs(float64(testCache.ApproxLen(0.1)-length)) <= bound)
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheProbeOverflows(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](4)
+
+	values := make([]uint64, 100)
+	for i := range values {
+		testCache.Put(cryptorand.Text(), &values[i])
+	}
+
+	check.True(t, testCache.Metrics().ProbeOverflows > 0)
+	check.True(t, testCache.ProbeOverflowRate(time.Minute) > 0)
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheOverwritesOldest(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[int, int](1 << 8)
+
+	values := make([]int, 1<<12)
+	for i := range values {
+		values[i] = i
+		testCache.Put(i, &values[i])
+	}
+
+	age, count := 0, 0
+
+	for _, value := range testCache.All() {
+		age += len(values) - value
+		count++
+	}
+
+	// Overwriting uniformly random slots keeps entries for about the cache size in writes on average,
+	// while preferring the oldest sampled slots keeps recent entries instead.
+	check.True(t, count > 0 && age/count < 3*testCache.Cap()/4)
+
+	runtime.KeepAlive(values)
+}
+
+func TestMustNewLockFreeCache(t *testing.T) {
+	t.Parallel()
+
+	check.True(t, cache.MustNewLockFreeCache[string, uint64](1) != nil)
+
+	for _, newCache := range []func(){
+		func() { cache.MustNewLockFreeCache[string, uint64](0) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithCostFunc[string, uint64](nil)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithCostSweepInterval[string, uint64](0)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithMaxCost[string, uint64](100)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithInvalidator[string, uint64](nil, nil)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithTTLJitter[string, uint64](0.1)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithTTLJitter[string, uint64](1)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithLoadTimeout[string, uint64](0)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithAsyncPuts[string, uint64](0, cache.QueueBlock)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithAsyncPuts[string, uint64](1, cache.QueuePolicy(3))) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithAdaptiveProbeDepth[string, uint64](0, 1, 2)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithAdaptiveProbeDepth[string, uint64](time.Second, 0, 2)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithAdaptiveProbeDepth[string, uint64](time.Second, 2, 1)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithDoorkeeper[string, uint64](0)) },
+		func() { cache.MustNewLockFreeCache(1, cache.WithGhostTracking[string, uint64](0)) },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+			}()
+
+			newCache()
+		}()
+	}
+}
+
+func TestTryNewLockFreeCache(t *testing.T) {
+	t.Parallel()
+
+	testCache, err := cache.TryNewLockFreeCache[string, uint64](1)
+	check.True(t, err == nil)
+	check.True(t, testCache != nil)
+
+	testCache, err = cache.TryNewLockFreeCache(1, cache.WithMaxCost[string, uint64](100))
+	check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+	check.True(t, testCache == nil)
+
+	_, err = cache.TryNewLockFreeCache[string, uint64](0)
+	check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+}
+
+func TestLockFreeCacheGetRef(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+
+	testCache.Put(key, &val)
+
+	// The value is returned by reference, without a copy.
+	value, ok := testCache.GetRef(key)
+	check.True(t, ok)
+	check.True(t, value == &val)
+
+	value, ok = testCache.GetRef(cryptorand.Text())
+	check.True(t, !ok)
+	check.True(t, value == nil)
+
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.ReadHits, 1)
+	check.Equal(t, metrics.ReadMisses, 1)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheGetAllocations(t *testing.T) {
+	// Not parallel, as AllocsPerRun counts the allocations of all goroutines.
+
+	options := map[string][]cache.Option[string, uint64]{
+		"default":       nil,
+		"ttl":           {cache.WithTTL[string, uint64](time.Hour)},
+		"hitRate":       {cache.WithHitRateTracking[string, uint64]()},
+		"latency":       {cache.WithLatencyTracking[string, uint64]()},
+		"access":        {cache.WithAccessTracking[string, uint64]()},
+		"groupProbing":  {cache.WithGroupProbing[string, uint64]()},
+		"earlyExpiries": {cache.WithEarlyExpiration[string, uint64](1)},
+	}
+
+	for name, opts := range options {
+		t.Run(name, func(t *testing.T) {
+			testCache := cache.NewLockFreeCache(N/100, opts...)
+
+			key, missingKey := cryptorand.Text(), cryptorand.Text()
+			val := mathrand.Uint64()
+
+			testCache.Put(key, &val)
+
+			// Hits and misses do not allocate.
+			check.Equal(t, testing.AllocsPerRun(100, func() { testCache.Get(key) }), 0)
+			check.Equal(t, testing.AllocsPerRun(100, func() { testCache.Get(missingKey) }), 0)
+
+			runtime.KeepAlive(&val)
+		})
+	}
+}
+
+func BenchmarkLockFreeCacheGet(b *testing.B) {
+	testCache := cache.NewLockFreeCache[string, uint64](N)
+
+	keys := make([]string, N/2)
+	values := make([]uint64, len(keys))
+
+	for i := range keys {
+		keys[i] = cryptorand.Text()
+		values[i] = uint64(i)
+		testCache.Put(keys[i], &values[i])
+	}
+
+	b.Run("hit", func(b *testing.B) {
+		b.ReportAllocs()
+
+		i := 0
+		for b.Loop() {
+			testCache.Get(keys[i%len(keys)])
+			i++
+		}
+	})
+
+	b.Run("miss", func(b *testing.B) {
+		missingKey := cryptorand.Text()
+
+		b.ReportAllocs()
+
+		for b.Loop() {
+			testCache.Get(missingKey)
+		}
+	})
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheOwnedValues(t *testing.T) {
+	// Not parallel, as it relies on garbage collection.
+	testCache := cache.NewLockFreeCache(N/100, cache.WithOwnedValues[string, string]())
+
+	key, want := cryptorand.Text(), cryptorand.Text()
+
+	func() {
+		val := want
+		testCache.Put(key, &val)
+	}()
+
+	// The cache holds a copy, so the entry outlives the value of the caller.
+	runtime.GC()
+
+	value, ok := testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, want)
+
+	// Later changes by the caller are not seen.
+	val := "changed"
+	testCache.Put(key, &val)
+	val = "changed again"
+
+	value, _ = testCache.Get(key)
+	check.Equal(t, value, "changed")
+	check.True(t, value != val)
+}
+
+func TestLockFreeCacheSwap(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	val, val2 := mathrand.Uint64(), mathrand.Uint64()
+
+	_, existed := testCache.Swap(key, &val)
+	check.True(t, !existed)
+
+	old, existed := testCache.Swap(key, &val2)
+	check.True(t, existed)
+	check.Equal(t, old, val)
+
+	value, ok := testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, val2)
+
+	runtime.KeepAlive(&val)
+	runtime.KeepAlive(&val2)
+}
+
+func TestLockFreeCacheCompareAndSwap(t *testing.T) {
+	t.Parallel()
+
+	// Compare odd and even values as equal.
+	testCache := cache.NewLockFreeCache(N/100, cache.WithEqualFunc[string](func(a, b uint64) bool {
+		return a%2 == b%2
+	}))
+
+	key := cryptorand.Text()
+	val, val2, val3 := uint64(1), uint64(2), uint64(3)
+
+	check.True(t, !testCache.CompareAndSwap(key, &val, &val2))
+
+	testCache.Put(key, &val)
+
+	check.True(t, !testCache.CompareAndSwap(key, &val2, &val2))
+	check.True(t, !testCache.CompareAndDelete(key, &val2))
+	check.True(t, testCache.CompareAndSwap(key, &val3, &val2))
+
+	value, ok := testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, val2)
+
+	check.True(t, testCache.CompareAndDelete(key, &val2))
+	check.True(t, !testCache.Contains(key))
+
+	testCache.Put(key, &val)
+	testCache.Delete(key)
+	check.True(t, !testCache.Contains(key))
+
+	runtime.KeepAlive(&val)
+	runtime.KeepAlive(&val2)
+	runtime.KeepAlive(&val3)
+}
+
+func TestLockFreeCacheUpdate(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+
+	// Values are weakly referenced, so keep all of them alive.
+	var (
+		lock     sync.Mutex
+		retained []*uint64
+	)
+
+	increment := func(current uint64, _ bool) *uint64 {
+		next := current + 1
+
+		lock.Lock()
+		retained = append(retained, &next)
+		lock.Unlock()
+
+		return &next
+	}
+
+	var wg sync.WaitGroup
+
+	for range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 1000 {
+				testCache.Update(key, increment)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	value, ok := testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, 8000)
+
+	_, ok = testCache.Update(key, func(uint64, bool) *uint64 { return nil })
+	check.True(t, !ok)
+	check.True(t, !testCache.Contains(key))
+
+	runtime.KeepAlive(retained)
+}
+
+func TestLockFreeCachePutIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	values := make([]uint64, 16)
+
+	var (
+		wg      sync.WaitGroup
+		winners atomic.Int64
+	)
+
+	for i := range values {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			values[i] = uint64(i)
+
+			if testCache.PutIfAbsent(key, &values[i]) {
+				winners.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	check.Equal(t, winners.Load(), 1)
+	check.True(t, testCache.Contains(key))
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheGetAndDelete(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+
+	testCache.Put(key, &val)
+
+	var (
+		wg        sync.WaitGroup
+		consumers atomic.Int64
+	)
+
+	for range 16 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if value, ok := testCache.GetAndDelete(key); ok && value == val {
+				consumers.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	check.Equal(t, consumers.Load(), 1)
+	check.True(t, !testCache.Contains(key))
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCachePin(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](4)
+
+	key := cryptorand.Text()
+	val := uint64(1)
+
+	testCache.Put(key, &val)
+	check.True(t, testCache.Pin(key))
+
+	values := make([]uint64, 100)
+	for i := range values {
+		testCache.Put(cryptorand.Text(), &values[i])
+	}
+
+	runtime.GC()
+
+	// Pinned entries survive both garbage collection and random overwrites.
+	value, ok := testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, 1)
+
+	check.True(t, testCache.Unpin(key))
+	check.True(t, !testCache.Unpin(key))
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheMaxCost(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithWeigher(func(_ string, value *[]byte) int64 {
+			return int64(len(*value))
+		}),
+		cache.WithMaxCost[string, []byte](1000),
+	)
+
+	values := make([][]byte, 100)
+	for i := range values {
+		values[i] = make([]byte, 100)
+		testCache.Put(cryptorand.Text(), &values[i])
+	}
+
+	metrics := testCache.Metrics()
+	check.True(t, metrics.CurrentCost <= 1000)
+	check.Equal(t, metrics.CostEvictions, 90)
+	check.Equal(t, testCache.Len(), 10)
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheConcurrentEvictions(t *testing.T) {
+	t.Parallel()
+
+	// A small cache with a max cost makes concurrent writers overwrite random slots and evict random entries.
+	testCache := cache.NewLockFreeCache(8,
+		cache.WithCostFunc(func(string, uint64) int64 { return 1 }),
+		cache.WithMaxCost[string, uint64](4),
+	)
+
+	values := make([]uint64, 64)
+	for i := range values {
+		values[i] = uint64(i)
+	}
+
+	var wg sync.WaitGroup
+
+	for worker := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				value := &values[(worker+i)%len(values)]
+				key := strconv.Itoa(int(*value))
+
+				switch i % 4 {
+				case 0:
+					testCache.Put(key, value)
+				case 1:
+					testCache.PutIfAbsent(key, value)
+				case 2:
+					testCache.Update(key, func(uint64, bool) *uint64 { return value })
+				case 3:
+					testCache.Delete(key)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	check.True(t, testCache.Len() <= testCache.Cap())
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheMemoryBudget(t *testing.T) {
+	t.Parallel()
+
+	const budget = 1 << 16
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithMemoryBudget[string, []byte](budget))
+
+	values := make([][]byte, 1000)
+	for i := range values {
+		values[i] = make([]byte, 1024)
+		testCache.Put(cryptorand.Text(), &values[i])
+	}
+
+	// Each entry holds at least 1 KiB, so at most 64 entries fit.
+	metrics := testCache.Metrics()
+	check.True(t, metrics.CurrentCost <= budget)
+	check.True(t, metrics.CostEvictions > 0)
+	check.True(t, testCache.Len() <= budget/1024)
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheMemoryPressure(t *testing.T) {
+	// Not parallel, as the memory limit is global to the process.
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(1 << 20))
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithMemoryPressure[string, uint64](0.9, 0.5))
+
+	values := make([]uint64, 1000)
+	for i := range values {
+		testCache.Put(cryptorand.Text(), &values[i])
+	}
+
+	check.True(t, testCache.Metrics().PressureEvictions > 0)
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheHooks(t *testing.T) {
+	t.Parallel()
+
+	var inserts, hits, misses, deletes, replaces atomic.Int64
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithHooks(cache.Hooks[string, uint64]{
+		OnInsert: func(string, *uint64) { inserts.Add(1) },
+		OnEvict: func(_ string, _ *uint64, reason cache.EvictionReason) {
+			switch reason {
+			case cache.EvictionDeleted:
+				deletes.Add(1)
+			case cache.EvictionReplaced:
+				replaces.Add(1)
+			}
+		},
+		OnHit:  func(string) { hits.Add(1) },
+		OnMiss: func(string) { misses.Add(1) },
+	}))
+
+	key := cryptorand.Text()
+	val1, val2 := uint64(1), uint64(2)
+
+	testCache.Put(key, &val1)
+	testCache.Put(key, &val2)
+	testCache.Get(key)
+	testCache.Delete(key)
+	testCache.Get(key)
+
+	check.Equal(t, inserts.Load(), 2)
+	check.Equal(t, replaces.Load(), 1)
+	check.Equal(t, deletes.Load(), 1)
+	check.Equal(t, hits.Load(), 1)
+	check.Equal(t, misses.Load(), 1)
+	check.Equal(t, cache.EvictionCapacity.String(), "capacity")
+
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.Evictions[cache.EvictionReplaced], 1)
+	check.Equal(t, metrics.Evictions[cache.EvictionDeleted], 1)
+
+	runtime.KeepAlive(&val1)
+	runtime.KeepAlive(&val2)
+}
+
+func TestLockFreeCacheEvictionEvents(t *testing.T) {
+	t.Parallel()
+
+	events := make(chan cache.EvictionEvent, 1)
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithEvictionEvents[string, uint64](events))
+
+	keys := []string{cryptorand.Text(), cryptorand.Text()}
+	values := make([]uint64, len(keys))
+
+	for i, key := range keys {
+		testCache.Put(key, &values[i])
+	}
+
+	for _, key := range keys {
+		testCache.Delete(key)
+	}
+
+	event := <-events
+	check.Equal(t, event.Reason, cache.EvictionDeleted)
+	check.True(t, !event.Time.IsZero())
+	check.Equal(t, testCache.Metrics().DroppedEvictionEvents, 1)
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheReclaimCallback(t *testing.T) {
+	t.Parallel()
+
+	reclaimed := make(chan uint64, 1)
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithReclaimCallback[string, [4]uint64](func(keyHash uint64) {
+		reclaimed <- keyHash
+	}))
+
+	key := cryptorand.Text()
+	testCache.Put(key, &[4]uint64{1, 2, 3, 4})
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		runtime.GC()
+
+		select {
+		case keyHash := <-reclaimed:
+			check.True(t, keyHash != 0)
+			check.True(t, !testCache.Contains(key))
+
+			return
+		case <-timeout:
+			t.Fatal("reclaim callback was not called")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestLockFreeCachePublishExpvar(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+	testCache.Get(cryptorand.Text())
+
+	name := "cache_" + cryptorand.Text()
+	check.True(t, testCache.PublishExpvar(name) == nil)
+	check.True(t, testCache.PublishExpvar(name) != nil)
+
+	var metrics cache.Metrics
+	check.True(t, json.Unmarshal([]byte(expvar.Get(name).String()), &metrics) == nil)
+	check.Equal(t, metrics.ReadMisses, 1)
+}
+
+func TestLockFreeCacheGetOrLoad(t *testing.T) {
+	t.Parallel()
+
+	results := make(map[cache.LoadResult]int)
+
+	var resultsLock sync.Mutex
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithHooks(cache.Hooks[string, uint64]{
+		OnLoad: func(ctx context.Context, _ string) (context.Context, func(cache.LoadResult, error)) {
+			return ctx, func(result cache.LoadResult, _ error) {
+				resultsLock.Lock()
+				results[result]++
+				resultsLock.Unlock()
+			}
+		},
+	}))
+
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+
+	var (
+		wg    sync.WaitGroup
+		loads atomic.Int64
+	)
+
+	for range 16 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			value, err := testCache.GetOrLoad(t.Context(), key, func(context.Context, string) (*uint64, error) {
+				loads.Add(1)
+				time.Sleep(50 * time.Millisecond)
+
+				return &val, nil
+			})
+			check.True(t, err == nil)
+			check.Equal(t, value, val)
+		}()
+	}
+
+	wg.Wait()
+
+	check.Equal(t, loads.Load(), 1)
+	check.Equal(t, results[cache.LoadMiss], 1)
+	check.Equal(t, results[cache.LoadHit]+results[cache.LoadCoalesced], 15)
+
+	// Errors are not cached.
+	errLoad := errors.New("load failed")
+	otherKey := cryptorand.Text()
+
+	_, err := testCache.GetOrLoad(t.Context(), otherKey, func(context.Context, string) (*uint64, error) {
+		return nil, errLoad
+	})
+	check.True(t, errors.Is(err, errLoad))
+	check.True(t, !testCache.Contains(otherKey))
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheGetOrLoadCancel(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	load := func(ctx context.Context, _ string) (*uint64, error) {
+		close(started)
+
+		select {
+		case <-release:
+			return &val, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	leader := make(chan error, 1)
+
+	go func() {
+		_, err := testCache.GetOrLoad(t.Context(), key, load)
+		leader <- err
+	}()
+
+	<-started
+
+	// A waiter which gives up returns its context error, while the load continues for the others.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := testCache.GetOrLoad(ctx, key, load)
+	check.True(t, errors.Is(err, context.Canceled))
+
+	close(release)
+	check.True(t, <-leader == nil)
+
+	value, ok := testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, val)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheGetOrLoadAbandoned(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	canceled := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	// Once every waiter gave up, the load itself is canceled.
+	_, err := testCache.GetOrLoad(ctx, key, func(ctx context.Context, _ string) (*uint64, error) {
+		cancel()
+		<-ctx.Done()
+		canceled <- ctx.Err()
+
+		return nil, ctx.Err()
+	})
+	check.True(t, errors.Is(err, context.Canceled))
+	check.True(t, errors.Is(<-canceled, context.Canceled))
+
+	// A new call starts a new load.
+	val := mathrand.Uint64()
+
+	value, err := testCache.GetOrLoad(t.Context(), key, func(context.Context, string) (*uint64, error) {
+		return &val, nil
+	})
+	check.True(t, err == nil)
+	check.Equal(t, value, val)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheGetOrLoadPanic(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+
+	var wg sync.WaitGroup
+
+	for range 16 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := testCache.GetOrLoad(t.Context(), key, func(context.Context, string) (*uint64, error) {
+				time.Sleep(50 * time.Millisecond)
+				panic("load failed")
+			})
+			check.True(t, errors.Is(err, cache.ErrLoaderPanic))
+			check.True(t, strings.Contains(err.Error(), "load failed"))
+		}()
+	}
+
+	wg.Wait()
+
+	check.True(t, testCache.Metrics().LoaderPanics > 0)
+	check.True(t, !testCache.Contains(key))
+}
+
+func TestLockFreeCacheLoadTimeout(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithLoadTimeout[string, uint64](10*time.Millisecond))
+
+	_, err := testCache.GetOrLoad(t.Context(), cryptorand.Text(), func(ctx context.Context, _ string) (*uint64, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	check.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestLockFreeCacheDebugHandler(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	values := make([]uint64, 100)
+	for i := range values {
+		testCache.Put(cryptorand.Text(), &values[i])
+	}
+
+	handler := cache.DebugHandler(testCache)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/cache", nil))
+
+	var info struct {
+		Size        int   `json:"size"`
+		Live        int   `json:"live"`
+		Empty       int   `json:"empty"`
+		ProbeDepths []int `json:"probeDepths"`
+	}
+
+	check.True(t, json.Unmarshal(recorder.Body.Bytes(), &info) == nil)
+	check.Equal(t, info.Size, N/100)
+	check.Equal(t, info.Live, testCache.Len())
+	check.Equal(t, info.Live+info.Empty, N/100)
+	check.True(t, len(info.ProbeDepths) > 0)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/cache?format=html", nil))
+	check.True(t, strings.Contains(recorder.Body.String(), "<h2>Probe depths</h2>"))
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheResetMetrics(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	val := uint64(1)
+	testCache.Put(cryptorand.Text(), &val)
+	testCache.Get(cryptorand.Text())
+
+	check.Equal(t, testCache.Metrics().ReadMisses, 1)
+
+	testCache.ResetMetrics()
+
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.ReadMisses, 0)
+	check.Equal(t, metrics.TotalWrites(), 0)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheHitRate(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithHitRateTracking[string, uint64]())
+
+	key := cryptorand.Text()
+	val := uint64(1)
+
+	testCache.Put(key, &val)
+
+	for range 3 {
+		testCache.Get(key)
+	}
+
+	testCache.Get(cryptorand.Text())
+
+	check.Equal(t, testCache.HitRate(time.Minute), 0.75)
+
+	untracked := cache.NewLockFreeCache[string, uint64](N / 100)
+	untracked.Get(key)
+	check.Equal(t, untracked.HitRate(time.Minute), 0)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheLatencyTracking(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithLatencyTracking[string, uint64]())
+
+	key := cryptorand.Text()
+	val := uint64(1)
+
+	testCache.Put(key, &val)
+	testCache.Get(key)
+	testCache.Get(cryptorand.Text())
+
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.PutLatency.Count(), 1)
+	check.Equal(t, metrics.GetLatency.Count(), 2)
+
+	check.True(t, cache.NewLockFreeCache[string, uint64](N/100).Metrics().GetLatency.Counts == nil)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheProbeStats(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	keys := make([]string, 100)
+	values := make([]uint64, len(keys))
+
+	for i := range keys {
+		keys[i] = cryptorand.Text()
+		testCache.Put(keys[i], &values[i])
+	}
+
+	for _, key := range keys {
+		testCache.Get(key)
+	}
+
+	stats := testCache.ProbeStats()
+
+	var hits, writes uint64
+	for i := range stats.Hits {
+		hits += stats.Hits[i]
+		writes += stats.Writes[i]
+	}
+
+	check.Equal(t, hits, testCache.Metrics().ReadHits)
+	check.Equal(t, writes, testCache.Metrics().TotalWrites())
+	check.True(t, stats.Hits[0] > 0)
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCachePaddedSlots(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithPaddedSlots[string, uint64]())
+	check.Equal(t, testCache.Cap(), N/100)
+
+	keys := make([]string, 100)
+	values := make([]uint64, len(keys))
+
+	for i := range keys {
+		keys[i] = cryptorand.Text()
+		values[i] = uint64(i)
+		testCache.Put(keys[i], &values[i])
+	}
+
+	for i, key := range keys {
+		value, ok := testCache.Get(key)
+		check.True(t, ok)
+		check.Equal(t, value, values[i])
+	}
+
+	check.Equal(t, testCache.Len(), len(keys))
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheGroupProbing(t *testing.T) {
+	t.Parallel()
+
+	// The size is rounded up to whole groups.
+	testCache := cache.NewLockFreeCache(1000, cache.WithGroupProbing[string, uint64]())
+	check.Equal(t, testCache.Cap(), 1008)
+
+	keys := make([]string, 500)
+	values := make([]uint64, len(keys))
+
+	for i := range keys {
+		keys[i] = cryptorand.Text()
+		values[i] = uint64(i)
+		testCache.Put(keys[i], &values[i])
+	}
+
+	for i, key := range keys {
+		value, ok := testCache.Get(key)
+		check.True(t, ok)
+		check.Equal(t, value, values[i])
+	}
+
+	_, ok := testCache.Get(cryptorand.Text())
+	check.True(t, !ok)
+
+	check.Equal(t, testCache.Len(), len(keys))
+
+	// Deleted keys are no longer matched by their control bytes.
+	for _, key := range keys[:100] {
+		testCache.Delete(key)
+
+		_, ok := testCache.Get(key)
+		check.True(t, !ok)
+	}
+
+	check.Equal(t, testCache.Len(), len(keys)-100)
+
+	clone := testCache.Clone()
+
+	for i, key := range keys[100:] {
+		value, ok := clone.Peek(key)
+		check.True(t, ok)
+		check.Equal(t, value, values[100+i])
+	}
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheLogger(t *testing.T) {
+	t.Parallel()
+
+	var logs strings.Builder
+
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	cache.NewLockFreeCache(N/100, cache.WithLogger[string, uint64](logger))
+	check.True(t, strings.Contains(logs.String(), "probeDepth="))
+}
+
+func TestLockFreeCacheGetE(t *testing.T) {
+	t.Parallel()
+
+	_, err := cache.NewLockFreeCache[string, uint64](0).GetE("key")
+	check.True(t, errors.Is(err, cache.ErrNotInitialized))
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	_, err = testCache.GetE("key")
+	check.True(t, errors.Is(err, cache.ErrNotFound))
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+
+	value, err := testCache.GetE("key")
+	check.True(t, err == nil)
+	check.Equal(t, value, 1)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheTTL(t *testing.T) {
+	t.Parallel()
+
+	var expired atomic.Int64
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithTTL[string, uint64](10*time.Millisecond),
+		cache.WithHooks(cache.Hooks[string, uint64]{
+			OnEvict: func(_ string, _ *uint64, reason cache.EvictionReason) {
+				if reason == cache.EvictionExpired {
+					expired.Add(1)
+				}
+			},
+		}),
+	)
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+
+	value, ok := testCache.Get("key")
+	check.True(t, ok)
+	check.Equal(t, value, 1)
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, ok = testCache.Get("key")
+	check.True(t, !ok)
+	check.Equal(t, expired.Load(), 1)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheExpireAt(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithTTL[string, uint64](time.Hour))
+
+	val := uint64(1)
+	testCache.PutWithExpiry("key1", &val, time.Now().Add(10*time.Millisecond))
+	testCache.Put("key2", &val)
+	testCache.Put("key3", &val)
+
+	check.True(t, testCache.ExpireAt("key2", time.Now().Add(10*time.Millisecond)))
+	check.True(t, testCache.ExpireAt("key3", time.Time{}))
+	check.True(t, !testCache.ExpireAt("missing", time.Now()))
+
+	check.True(t, testCache.Contains("key1"))
+	check.True(t, testCache.Contains("key2"))
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, ok := testCache.Get("key1")
+	check.True(t, !ok)
+
+	_, ok = testCache.Get("key2")
+	check.True(t, !ok)
+
+	// Entries set to never expire outlive the TTL.
+	check.True(t, testCache.Contains("key3"))
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheTTLJitter(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithTTL[string, uint64](time.Hour),
+		cache.WithTTLJitter[string, uint64](0.1),
+	)
+
+	values := make([]uint64, 100)
+	expiries := make(map[time.Duration]struct{})
+
+	for i := range values {
+		key := strconv.Itoa(i)
+		testCache.Put(key, &values[i])
+
+		info, ok := testCache.GetEntryInfo(key)
+		if !ok {
+			continue
+		}
+
+		ttl := info.Expires.Sub(info.Written)
+		check.True(t, ttl >= 54*time.Minute && ttl <= 66*time.Minute)
+
+		expiries[ttl] = struct{}{}
+	}
+
+	// Entries stored together expire at different times.
+	check.True(t, len(expiries) > 1)
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCacheStaleWhileRevalidate(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithTTL[string, uint64](10*time.Millisecond),
+		cache.WithStaleWhileRevalidate[string, uint64](),
+	)
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, err := testCache.GetE("key")
+	check.True(t, errors.Is(err, cache.ErrExpired))
+
+	refreshed := make(chan struct{})
+	fresh := uint64(2)
+
+	result, err := testCache.GetOrLoadResult(context.Background(), "key", func(context.Context, string) (*uint64, error) {
+		defer close(refreshed)
+		return &fresh, nil
+	})
+	check.True(t, err == nil)
+	check.True(t, result.Stale)
+	check.Equal(t, result.Result, cache.LoadStale)
+	check.Equal(t, result.Value, 1)
+
+	<-refreshed
+
+	// The refresh stores its value after the load returns.
+	for range 100 {
+		if value, ok := testCache.Get("key"); ok && value == 2 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	result, err = testCache.GetOrLoadResult(context.Background(), "key", func(context.Context, string) (*uint64, error) {
+		return nil, errors.New("unexpected load")
+	})
+	check.True(t, err == nil)
+	check.True(t, !result.Stale)
+	check.Equal(t, result.Value, 2)
+
+	runtime.KeepAlive(&val)
+	runtime.KeepAlive(&fresh)
+}
+
+func TestLockFreeCacheEarlyExpiration(t *testing.T) {
+	t.Parallel()
+
+	var loads atomic.Int64
+
+	val := uint64(1)
+
+	load := func(context.Context, string) (*uint64, error) {
+		loads.Add(1)
+		time.Sleep(time.Millisecond)
+
+		return &val, nil
+	}
+
+	// With a huge beta, every load of the live entry refreshes it early.
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithTTL[string, uint64](time.Hour),
+		cache.WithEarlyExpiration[string, uint64](1e12),
+	)
+
+	result, err := testCache.GetOrLoadResult(context.Background(), "key", load)
+	check.True(t, err == nil)
+	check.Equal(t, result.Result, cache.LoadMiss)
+
+	result, err = testCache.GetOrLoadResult(context.Background(), "key", load)
+	check.True(t, err == nil)
+	check.Equal(t, result.Result, cache.LoadMiss)
+	check.Equal(t, result.Value, 1)
+	check.Equal(t, loads.Load(), 2)
+
+	// Entries far from their expiry are not refreshed with a small beta.
+	testCache = cache.NewLockFreeCache(N/100,
+		cache.WithTTL[string, uint64](time.Hour),
+		cache.WithEarlyExpiration[string, uint64](1),
+	)
+
+	_, _ = testCache.GetOrLoad(context.Background(), "key", load)
+
+	result, err = testCache.GetOrLoadResult(context.Background(), "key", load)
+	check.True(t, err == nil)
+	check.Equal(t, result.Result, cache.LoadHit)
+	check.Equal(t, loads.Load(), 3)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheRefreshAhead(t *testing.T) {
+	t.Parallel()
+
+	var loads atomic.Int64
+
+	val := uint64(1)
+
+	load := func(context.Context, string) (*uint64, error) {
+		loads.Add(1)
+		return &val, nil
+	}
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithTTL[string, uint64](20*time.Millisecond),
+		cache.WithRefreshAhead(5*time.Millisecond, 1, load),
+	)
+
+	_, err := testCache.GetOrLoad(context.Background(), "key", load)
+	check.True(t, err == nil)
+
+	// Keep the key hot until it was refreshed ahead of its expiry.
+	for range 1000 {
+		if loads.Load() > 1 {
+			break
+		}
+
+		_, _ = testCache.Get("key")
+
+		time.Sleep(time.Millisecond)
+	}
+
+	check.True(t, loads.Load() > 1)
+
+	value, ok := testCache.Get("key")
+	check.True(t, ok)
+	check.Equal(t, value, 1)
+
+	runtime.KeepAlive(&val)
+}
@@ -0,0 +1,231 @@
+// Package memcache serves a cache over the memcached text protocol, so existing memcached clients can use it.
+package memcache
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/samborkent/cache"
+)
+
+const (
+	// maxLineSize is the maximum size in bytes of a command line, which holds up to a few keys of 250 bytes.
+	maxLineSize = 4096
+	// maxValueSize is the maximum size in bytes of a stored value, as in memcached.
+	maxValueSize = 1 << 20
+	// maxKeySize is the maximum size in bytes of a key, as in memcached.
+	maxKeySize = 250
+)
+
+var crlf = []byte("\r\n")
+
+// Server implements the get, set, delete, stats and quit commands of the memcached text protocol.
+//
+// The cache should be created with cache.WithOwnedValues, so it holds the stored values until they are deleted,
+// overwritten, evicted, or expire if the cache has a TTL, instead of leaving them to the garbage collector.
+// The flags and expiration time of set are ignored, and get returns zero flags.
+type Server struct {
+	cache   *cache.LockFreeCache[string, []byte]
+	started time.Time
+
+	gets, sets atomic.Uint64
+}
+
+// NewServer returns a server for c, which should be created with cache.WithOwnedValues.
+func NewServer(c *cache.LockFreeCache[string, []byte]) *Server {
+	return &Server{
+		cache:   c,
+		started: time.Now(),
+	}
+}
+
+// Serve accepts connections on l and serves each in its own goroutine, until accepting fails.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReaderSize(conn, maxLineSize)
+	w := bufio.NewWriter(conn)
+
+	for {
+		line, err := r.ReadSlice('\n')
+		if err != nil {
+			if errors.Is(err, bufio.ErrBufferFull) {
+				_, _ = w.WriteString("CLIENT_ERROR line too long\r\n")
+				_ = w.Flush()
+			}
+
+			return
+		}
+
+		if !s.serve(r, w, bytes.Fields(line)) {
+			_ = w.Flush()
+			return
+		}
+
+		// Flush once all pipelined commands are handled.
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// serve handles a single command, and reports whether the connection should be kept open.
+func (s *Server) serve(r *bufio.Reader, w *bufio.Writer, fields [][]byte) bool {
+	if len(fields) == 0 {
+		_, _ = w.WriteString("ERROR\r\n")
+		return true
+	}
+
+	switch string(fields[0]) {
+	case "get":
+		s.get(w, fields[1:])
+	case "set":
+		return s.set(r, w, fields[1:])
+	case "delete":
+		s.delete(w, fields[1:])
+	case "stats":
+		s.stats(w)
+	case "quit":
+		return false
+	default:
+		_, _ = w.WriteString("ERROR\r\n")
+	}
+
+	return true
+}
+
+func (s *Server) get(w *bufio.Writer, keys [][]byte) {
+	if len(keys) == 0 {
+		_, _ = w.WriteString("ERROR\r\n")
+		return
+	}
+
+	for _, key := range keys {
+		s.gets.Add(1)
+
+		value, ok := s.cache.Get(string(key))
+		if !ok {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(w, "VALUE %s 0 %d\r\n", key, len(value))
+		_, _ = w.Write(value)
+		_, _ = w.Write(crlf)
+	}
+
+	_, _ = w.WriteString("END\r\n")
+}
+
+// set handles "set <key> <flags> <exptime> <bytes> [noreply]", followed by the data block.
+func (s *Server) set(r *bufio.Reader, w *bufio.Writer, args [][]byte) bool {
+	if len(args) != 4 && len(args) != 5 {
+		_, _ = w.WriteString("ERROR\r\n")
+		return true
+	}
+
+	key := string(args[0])
+	noreply := len(args) == 5 && string(args[4]) == "noreply"
+
+	_, flagsErr := strconv.ParseUint(string(args[1]), 10, 32)
+	_, exptimeErr := strconv.ParseInt(string(args[2]), 10, 64)
+	size, sizeErr := strconv.Atoi(string(args[3]))
+
+	if flagsErr != nil || exptimeErr != nil || sizeErr != nil || size < 0 || len(key) > maxKeySize {
+		_, _ = w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return false
+	}
+
+	if size > maxValueSize {
+		// Skip the data block, so the connection stays in sync.
+		if _, err := r.Discard(size + len(crlf)); err != nil {
+			return false
+		}
+
+		_, _ = w.WriteString("SERVER_ERROR object too large for cache\r\n")
+
+		return true
+	}
+
+	data := make([]byte, size+len(crlf))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return false
+	}
+
+	if !bytes.HasSuffix(data, crlf) {
+		_, _ = w.WriteString("CLIENT_ERROR bad data chunk\r\n")
+		return false
+	}
+
+	s.sets.Add(1)
+
+	value := data[:size:size]
+	s.cache.Put(key, &value)
+
+	if !noreply {
+		_, _ = w.WriteString("STORED\r\n")
+	}
+
+	return true
+}
+
+// delete handles "delete <key> [noreply]".
+func (s *Server) delete(w *bufio.Writer, args [][]byte) {
+	if len(args) != 1 && len(args) != 2 {
+		_, _ = w.WriteString("ERROR\r\n")
+		return
+	}
+
+	_, ok := s.cache.GetAndDelete(string(args[0]))
+
+	if len(args) == 2 && string(args[1]) == "noreply" {
+		return
+	}
+
+	if ok {
+		_, _ = w.WriteString("DELETED\r\n")
+	} else {
+		_, _ = w.WriteString("NOT_FOUND\r\n")
+	}
+}
+
+func (s *Server) stats(w *bufio.Writer) {
+	metrics := s.cache.Metrics()
+	now := time.Now()
+
+	stat := func(name string, value any) {
+		_, _ = fmt.Fprintf(w, "STAT %s %v\r\n", name, value)
+	}
+
+	stat("pid", os.Getpid())
+	stat("uptime", int64(now.Sub(s.started).Seconds()))
+	stat("time", now.Unix())
+	stat("curr_items", s.cache.Len())
+	stat("cmd_get", s.gets.Load())
+	stat("cmd_set", s.sets.Load())
+	stat("get_hits", metrics.ReadHits)
+	stat("get_misses", metrics.ReadMisses)
+	stat("evictions", metrics.Evictions[cache.EvictionOverwritten])
+
+	_, _ = w.WriteString("END\r\n")
+}
@@ -0,0 +1,66 @@
+package memcache_test
+
+import (
+	"bufio"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/memcache"
+	"github.com/samborkent/check"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	check.True(t, err == nil)
+
+	defer l.Close()
+
+	go func() {
+		_ = memcache.NewServer(cache.NewLockFreeCache(16, cache.WithOwnedValues[string, []byte]())).Serve(l)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	check.True(t, err == nil)
+
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+
+	do := func(command string, lines int) string {
+		_, err := conn.Write([]byte(command))
+		check.True(t, err == nil)
+
+		var reply strings.Builder
+
+		for range lines {
+			line, err := r.ReadString('\n')
+			check.True(t, err == nil)
+
+			reply.WriteString(line)
+		}
+
+		return reply.String()
+	}
+
+	check.Equal(t, do("get key\r\n", 1), "END\r\n")
+	check.Equal(t, do("set key 0 0 5\r\nvalue\r\n", 1), "STORED\r\n")
+
+	// The cache owns the stored values, so they survive garbage collection.
+	runtime.GC()
+
+	check.Equal(t, do("get key other\r\n", 3), "VALUE key 0 5\r\nvalue\r\nEND\r\n")
+	check.Equal(t, do("set other 0 0 1 noreply\r\nx\r\nget other\r\n", 3), "VALUE other 0 1\r\nx\r\nEND\r\n")
+	check.Equal(t, do("delete key\r\n", 1), "DELETED\r\n")
+	check.Equal(t, do("delete key\r\n", 1), "NOT_FOUND\r\n")
+	check.Equal(t, do("unknown\r\n", 1), "ERROR\r\n")
+
+	stats := do("stats\r\n", 10)
+	check.True(t, strings.Contains(stats, "STAT curr_items 1\r\n"))
+	check.True(t, strings.Contains(stats, "STAT cmd_set 2\r\n"))
+	check.True(t, strings.HasSuffix(stats, "END\r\n"))
+}
@@ -0,0 +1,34 @@
+package cache
+
+import "context"
+
+// Memoize returns a function which caches the results of fn in c. Concurrent calls for the same key
+// share a single call of fn, and errors are not cached. The expiry of results is configured on c,
+// for example with WithTTL. As the cache holds values weakly, results may be recomputed after a garbage collection,
+// unless c owns its values, see WithOwnedValues.
+func Memoize[K comparable, V any](c Loader[K, V], fn func(key K) (V, error)) func(key K) (V, error) {
+	memoized := MemoizeContext(c, func(_ context.Context, key K) (V, error) {
+		return fn(key)
+	})
+
+	return func(key K) (V, error) {
+		return memoized(context.Background(), key)
+	}
+}
+
+// MemoizeContext is like Memoize, for functions which take a context.
+// The context of the call which runs fn is passed to it.
+func MemoizeContext[K comparable, V any](c Loader[K, V], fn func(ctx context.Context, key K) (V, error)) func(ctx context.Context, key K) (V, error) {
+	load := func(ctx context.Context, key K) (*V, error) {
+		value, err := fn(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+
+		return &value, nil
+	}
+
+	return func(ctx context.Context, key K) (V, error) {
+		return c.GetOrLoad(ctx, key, load)
+	}
+}
@@ -0,0 +1,60 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestMemoize(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+
+	// The cache owns the results, so a garbage collection does not reclaim them.
+	length := cache.Memoize(cache.NewLockFreeCache(16, cache.WithOwnedValues[string, int]()), func(key string) (int, error) {
+		calls++
+
+		if key == "" {
+			return 0, errors.New("empty key")
+		}
+
+		return len(key), nil
+	})
+
+	value, err := length("key")
+	check.True(t, err == nil)
+	check.Equal(t, value, 3)
+
+	runtime.GC()
+
+	value, err = length("key")
+	check.True(t, err == nil)
+	check.Equal(t, value, 3)
+	check.Equal(t, calls, 1)
+
+	// Errors are not cached.
+	_, err = length("")
+	check.True(t, err != nil)
+
+	_, err = length("")
+	check.True(t, err != nil)
+	check.Equal(t, calls, 3)
+}
+
+func TestMemoizeContext(t *testing.T) {
+	type keyType struct{}
+
+	format := cache.MemoizeContext(cache.NewShardedCache[int, string](4, 16), func(ctx context.Context, key int) (string, error) {
+		return ctx.Value(keyType{}).(string) + strconv.Itoa(key), nil
+	})
+
+	value, err := format(context.WithValue(context.Background(), keyType{}, "key"), 1)
+	check.True(t, err == nil)
+	check.Equal(t, value, "key1")
+}
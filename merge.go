@@ -0,0 +1,21 @@
+package cache
+
+// MergePolicy determines how conflicting keys are resolved when merging caches.
+type MergePolicy int
+
+const (
+	// MergeKeepNewest keeps whichever entry was written most recently.
+	MergeKeepNewest MergePolicy = iota
+	// MergeKeepExisting keeps the entry already present in the destination cache.
+	MergeKeepExisting
+)
+
+// replace reports whether an existing entry should be replaced by an incoming entry.
+func (p MergePolicy) replace(existingWritten, incomingWritten int64) bool {
+	switch p {
+	case MergeKeepExisting:
+		return false
+	default:
+		return incomingWritten > existingWritten
+	}
+}
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Metrics is a snapshot of the counters of a cache.
+type Metrics struct {
+	ReadMisses, ReadHits uint64
+
+	FirstWrites, ProbeWrites      uint64
+	EmptyWrites                   uint64
+	RandomCASWrites, RandomWrites uint64
+
+	// ProbeOverflows counts the puts which found neither the same key nor a free slot
+	// within the hash probe depth, and had to evict a random entry.
+	// A rising rate is an early signal that the cache is undersized for its working set.
+	ProbeOverflows uint64
+
+	// CurrentCost is the summed cost of all entries, if a cost function or weigher is configured.
+	CurrentCost int64
+	// CostEvictions counts the entries which were evicted to stay within the max cost.
+	CostEvictions uint64
+	// ReclaimedCost is the total cost released from entries whose value was reclaimed by the garbage collector.
+	// Such entries are found by writes to their slot and by a periodic sweep, see WithCostSweepInterval,
+	// so CurrentCost overcounts by at most the cost reclaimed since the last sweep.
+	ReclaimedCost int64
+	// PressureEvictions counts the entries which were dropped because the process approached its memory limit.
+	PressureEvictions uint64
+	// DroppedEvictionEvents counts the eviction events which were discarded because the channel set by
+	// WithEvictionEvents was full.
+	DroppedEvictionEvents uint64
+	// OverflowHits counts the reads which missed the table, but were served from the tier set by WithOverflow.
+	// They are not counted in ReadHits or ReadMisses.
+	OverflowHits uint64
+	// LoaderPanics counts the loads of GetOrLoad which panicked. The panic is returned as ErrLoaderPanic.
+	LoaderPanics uint64
+	// DroppedAsyncPuts counts the puts of PutAsync which were discarded because the queue set by WithAsyncPuts was full.
+	DroppedAsyncPuts uint64
+	// DeadReclaims counts the writes to a full cache or probe window, which reused the slot of an entry reclaimed by
+	// the garbage collector instead of overwriting a live entry.
+	DeadReclaims uint64
+	// DoorkeeperRejects counts the puts of new keys which were dropped by the filter set by WithDoorkeeper,
+	// as the key was not put before.
+	DoorkeeperRejects uint64
+	// GhostHits counts the read misses of keys which were recently overwritten or evicted for capacity,
+	// which a bigger cache would likely have served, if enabled with WithGhostTracking.
+	GhostHits uint64
+
+	// Evictions counts the entries removed from the cache, indexed by EvictionReason.
+	Evictions [evictionReasons]uint64
+
+	// GetLatency and PutLatency are histograms of the latencies of Get and Put,
+	// if enabled with WithLatencyTracking.
+	GetLatency, PutLatency LatencyHistogram
+}
+
+// HitRatio returns the ratio of reads which found a live entry, or 0 if there were no reads.
+func (m Metrics) HitRatio() float64 {
+	reads := m.ReadHits + m.ReadMisses
+	if reads == 0 {
+		return 0
+	}
+
+	return float64(m.ReadHits) / float64(reads)
+}
+
+// TotalWrites returns the number of stored writes of all types.
+func (m Metrics) TotalWrites() uint64 {
+	return m.FirstWrites + m.ProbeWrites + m.EmptyWrites + m.RandomCASWrites + m.RandomWrites
+}
+
+// Delta returns the change of the counters since prev, an earlier snapshot of the same cache,
+// so periodic reporters can emit per-interval values. CurrentCost is not a counter and is kept as is.
+func (m Metrics) Delta(prev Metrics) Metrics {
+	delta := Metrics{
+		ReadMisses:      m.ReadMisses - prev.ReadMisses,
+		ReadHits:        m.ReadHits - prev.ReadHits,
+		FirstWrites:     m.FirstWrites - prev.FirstWrites,
+		ProbeWrites:     m.ProbeWrites - prev.ProbeWrites,
+		EmptyWrites:     m.EmptyWrites - prev.EmptyWrites,
+		RandomCASWrites: m.RandomCASWrites - prev.RandomCASWrites,
+		RandomWrites:    m.RandomWrites - prev.RandomWrites,
+		ProbeOverflows:  m.ProbeOverflows - prev.ProbeOverflows,
+		CurrentCost:     m.CurrentCost,
+		CostEvictions:   m.CostEvictions - prev.CostEvictions,
+		ReclaimedCost:   m.ReclaimedCost - prev.ReclaimedCost,
+
+		PressureEvictions:     m.PressureEvictions - prev.PressureEvictions,
+		DroppedEvictionEvents: m.DroppedEvictionEvents - prev.DroppedEvictionEvents,
+		OverflowHits:          m.OverflowHits - prev.OverflowHits,
+		LoaderPanics:          m.LoaderPanics - prev.LoaderPanics,
+		DroppedAsyncPuts:      m.DroppedAsyncPuts - prev.DroppedAsyncPuts,
+		DeadReclaims:          m.DeadReclaims - prev.DeadReclaims,
+		DoorkeeperRejects:     m.DoorkeeperRejects - prev.DoorkeeperRejects,
+		GhostHits:             m.GhostHits - prev.GhostHits,
+
+		GetLatency: m.GetLatency.delta(prev.GetLatency),
+		PutLatency: m.PutLatency.delta(prev.PutLatency),
+	}
+
+	for reason := range delta.Evictions {
+		delta.Evictions[reason] = m.Evictions[reason] - prev.Evictions[reason]
+	}
+
+	return delta
+}
+
+// add returns the sum of the metrics, for aggregating the metrics of multiple caches.
+func (m Metrics) add(other Metrics) Metrics {
+	sum := Metrics{
+		ReadMisses:      m.ReadMisses + other.ReadMisses,
+		ReadHits:        m.ReadHits + other.ReadHits,
+		FirstWrites:     m.FirstWrites + other.FirstWrites,
+		ProbeWrites:     m.ProbeWrites + other.ProbeWrites,
+		EmptyWrites:     m.EmptyWrites + other.EmptyWrites,
+		RandomCASWrites: m.RandomCASWrites + other.RandomCASWrites,
+		RandomWrites:    m.RandomWrites + other.RandomWrites,
+		ProbeOverflows:  m.ProbeOverflows + other.ProbeOverflows,
+		CurrentCost:     m.CurrentCost + other.CurrentCost,
+		CostEvictions:   m.CostEvictions + other.CostEvictions,
+		ReclaimedCost:   m.ReclaimedCost + other.ReclaimedCost,
+
+		PressureEvictions:     m.PressureEvictions + other.PressureEvictions,
+		DroppedEvictionEvents: m.DroppedEvictionEvents + other.DroppedEvictionEvents,
+		OverflowHits:          m.OverflowHits + other.OverflowHits,
+		LoaderPanics:          m.LoaderPanics + other.LoaderPanics,
+		DroppedAsyncPuts:      m.DroppedAsyncPuts + other.DroppedAsyncPuts,
+		DeadReclaims:          m.DeadReclaims + other.DeadReclaims,
+		DoorkeeperRejects:     m.DoorkeeperRejects + other.DoorkeeperRejects,
+		GhostHits:             m.GhostHits + other.GhostHits,
+
+		GetLatency: m.GetLatency.add(other.GetLatency),
+		PutLatency: m.PutLatency.add(other.PutLatency),
+	}
+
+	for reason := range sum.Evictions {
+		sum.Evictions[reason] = m.Evictions[reason] + other.Evictions[reason]
+	}
+
+	return sum
+}
+
+// plainMetrics has the fields of Metrics without its methods, to marshal them without recursion.
+type plainMetrics Metrics
+
+// MarshalJSON encodes the metrics including the derived HitRatio and TotalWrites.
+func (m Metrics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		plainMetrics
+		HitRatio    float64
+		TotalWrites uint64
+	}{
+		plainMetrics: plainMetrics(m),
+		HitRatio:     m.HitRatio(),
+		TotalWrites:  m.TotalWrites(),
+	})
+}
+
+// MarshalText encodes the metrics as in String.
+func (m Metrics) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// String formats the metrics as space separated key=value pairs, including the derived hit ratio and total writes.
+func (m Metrics) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "reads=%d hits=%d misses=%d hitRatio=%.4f", m.ReadHits+m.ReadMisses, m.ReadHits, m.ReadMisses, m.HitRatio())
+	fmt.Fprintf(&b, " writes=%d firstWrites=%d probeWrites=%d emptyWrites=%d randomCASWrites=%d randomWrites=%d",
+		m.TotalWrites(), m.FirstWrites, m.ProbeWrites, m.EmptyWrites, m.RandomCASWrites, m.RandomWrites)
+	fmt.Fprintf(&b, " probeOverflows=%d cost=%d costEvictions=%d reclaimedCost=%d pressureEvictions=%d droppedEvictionEvents=%d",
+		m.ProbeOverflows, m.CurrentCost, m.CostEvictions, m.ReclaimedCost, m.PressureEvictions, m.DroppedEvictionEvents)
+	fmt.Fprintf(&b, " overflowHits=%d loaderPanics=%d droppedAsyncPuts=%d deadReclaims=%d doorkeeperRejects=%d ghostHits=%d",
+		m.OverflowHits, m.LoaderPanics, m.DroppedAsyncPuts, m.DeadReclaims, m.DoorkeeperRejects, m.GhostHits)
+
+	for reason, count := range m.Evictions {
+		fmt.Fprintf(&b, " evictions.%s=%d", EvictionReason(reason), count)
+	}
+
+	if m.GetLatency.Counts != nil {
+		fmt.Fprintf(&b, " getP50=%s getP99=%s", m.GetLatency.Quantile(0.5), m.GetLatency.Quantile(0.99))
+	}
+
+	if m.PutLatency.Counts != nil {
+		fmt.Fprintf(&b, " putP50=%s putP99=%s", m.PutLatency.Quantile(0.5), m.PutLatency.Quantile(0.99))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package cache_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestMetricsMarshal(t *testing.T) {
+	t.Parallel()
+
+	metrics := cache.Metrics{
+		ReadHits:    3,
+		ReadMisses:  1,
+		FirstWrites: 2,
+		ProbeWrites: 1,
+	}
+
+	check.Equal(t, metrics.HitRatio(), 0.75)
+	check.Equal(t, metrics.TotalWrites(), 3)
+	check.Equal(t, cache.Metrics{}.HitRatio(), 0)
+
+	data, err := json.Marshal(metrics)
+	check.True(t, err == nil)
+
+	var decoded map[string]any
+	check.True(t, json.Unmarshal(data, &decoded) == nil)
+	check.Equal(t, decoded["HitRatio"], 0.75)
+	check.Equal(t, decoded["TotalWrites"], 3.0)
+	check.Equal(t, decoded["ReadHits"], 3.0)
+
+	text, err := metrics.MarshalText()
+	check.True(t, err == nil)
+	check.Equal(t, string(text), metrics.String())
+	check.True(t, strings.Contains(metrics.String(), "hitRatio=0.7500"))
+	check.True(t, strings.Contains(metrics.String(), "writes=3"))
+}
+
+func TestMetricsDelta(t *testing.T) {
+	t.Parallel()
+
+	prev := cache.Metrics{ReadHits: 2, CurrentCost: 10}
+	prev.Evictions[cache.EvictionDeleted] = 1
+
+	next := cache.Metrics{ReadHits: 5, ReadMisses: 1, CurrentCost: 7}
+	next.Evictions[cache.EvictionDeleted] = 4
+
+	delta := next.Delta(prev)
+	check.Equal(t, delta.ReadHits, 3)
+	check.Equal(t, delta.ReadMisses, 1)
+	check.Equal(t, delta.CurrentCost, 7)
+	check.Equal(t, delta.Evictions[cache.EvictionDeleted], 3)
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+func mmap(*os.File, int) ([]byte, error) {
+	return nil, fmt.Errorf("cache: mmap: %w", errors.ErrUnsupported)
+}
+
+func munmap([]byte) error {
+	return nil
+}
@@ -0,0 +1,105 @@
+package cache
+
+// Occupancy counts the slots of a cache by their state.
+type Occupancy struct {
+	// Live counts the slots holding a value which was not reclaimed by the garbage collector.
+	Live int
+	// Dead counts the slots holding an entry whose value was reclaimed, but which was not removed yet.
+	Dead int
+	// Empty counts the slots which hold no entry.
+	Empty int
+}
+
+// Slots returns the total number of slots.
+func (o Occupancy) Slots() int {
+	return o.Live + o.Dead + o.Empty
+}
+
+// LoadFactor returns the ratio of slots which are live, or 0 if there are no slots.
+func (o Occupancy) LoadFactor() float64 {
+	if o.Slots() == 0 {
+		return 0
+	}
+
+	return float64(o.Live) / float64(o.Slots())
+}
+
+func (o Occupancy) add(other Occupancy) Occupancy {
+	return Occupancy{
+		Live:  o.Live + other.Live,
+		Dead:  o.Dead + other.Dead,
+		Empty: o.Empty + other.Empty,
+	}
+}
+
+// Occupancy scans all slots and counts them by their state.
+func (c *LockFreeCache[K, V]) Occupancy() Occupancy {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	var occupancy Occupancy
+
+	for index := range c.size {
+		entry := c.slot(index).Load()
+
+		switch {
+		case entry == nil || entry.keyHash == 0:
+			occupancy.Empty++
+		case entry.valueRef.Value() == nil:
+			occupancy.Dead++
+		default:
+			occupancy.Live++
+		}
+	}
+
+	return occupancy
+}
+
+// Occupancy scans all slots and counts them by their state. If the cache has a max size, the slots it did not
+// allocate yet are counted as empty.
+func (c *Cache[K, V]) Occupancy() Occupancy {
+	if !c.initialized {
+		return Occupancy{}
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var occupancy Occupancy
+
+	for i, keyHash := range c.keyHashes {
+		switch {
+		case keyHash == 0:
+			occupancy.Empty++
+		case c.entries[i].valueRef.Value() == nil:
+			occupancy.Dead++
+		default:
+			occupancy.Live++
+		}
+	}
+
+	occupancy.Empty += max(0, c.maxSize-len(c.keyHashes))
+
+	return occupancy
+}
+
+// Occupancy returns the occupancy summed over all shards.
+func (c *ShardedCache[K, V]) Occupancy() Occupancy {
+	var occupancy Occupancy
+
+	for _, shard := range c.shards {
+		occupancy = occupancy.add(shard.Occupancy())
+	}
+
+	return occupancy
+}
+
+// Occupancy returns the occupancy summed over all sub-tables.
+func (c *PerProcCache[K, V]) Occupancy() Occupancy {
+	var occupancy Occupancy
+
+	for _, table := range c.tables {
+		occupancy = occupancy.add(table.Occupancy())
+	}
+
+	return occupancy
+}
@@ -0,0 +1,67 @@
+package cache_test
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheOccupancy(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewLockFreeCache[string, Object](100)
+
+	check.Equal(t, store.Occupancy(), cache.Occupancy{Empty: 100})
+	check.Equal(t, store.Occupancy().LoadFactor(), 0.0)
+
+	retained := make([]*Object, 10)
+
+	for i := range retained {
+		retained[i] = &Object{Field2: i}
+		store.Put(strconv.Itoa(i), retained[i])
+	}
+
+	for i := range 5 {
+		store.Put("dead"+strconv.Itoa(i), &Object{})
+	}
+
+	runtime.GC()
+
+	occupancy := store.Occupancy()
+	check.Equal(t, occupancy, cache.Occupancy{Live: 10, Dead: 5, Empty: 85})
+	check.Equal(t, occupancy.Slots(), store.Cap())
+	check.Equal(t, occupancy.LoadFactor(), 0.1)
+
+	runtime.KeepAlive(retained)
+}
+
+func TestCacheOccupancy(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewCache[string, Object](0, 20)
+
+	retained := make([]*Object, 10)
+
+	for i := range retained {
+		retained[i] = &Object{Field2: i}
+		store.Put(strconv.Itoa(i), retained[i])
+	}
+
+	store.Delete("0")
+	store.Put("dead", &Object{})
+
+	runtime.GC()
+
+	// The dead entry reused the deleted slot, which is emptied again once the cleanup of its value ran.
+	// The slots which were not allocated up to the max size are empty.
+	occupancy := store.Occupancy()
+	check.Equal(t, occupancy.Live, 9)
+	check.Equal(t, occupancy.Dead+occupancy.Empty, 11)
+	check.Equal(t, occupancy.LoadFactor(), 0.45)
+	check.Equal(t, new(cache.Cache[string, Object]).Occupancy(), cache.Occupancy{})
+
+	runtime.KeepAlive(retained)
+}
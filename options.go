@@ -0,0 +1,671 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// Option configures a cache. Unless noted otherwise, options only apply to LockFreeCache.
+type Option[K comparable, V any] func(*config[K, V])
+
+type config[K comparable, V any] struct {
+	costFunc  func(K, V) int64
+	weigher   func(K, *V) int64
+	maxCost   int64
+	budget    int64
+	costSweep time.Duration
+	pressure  *memoryPressure
+	equalFunc func(a, b V) bool
+	hooks     Hooks[K, V]
+
+	evictionEvents chan<- EvictionEvent
+	onReclaim      func(keyHash uint64)
+	hitRate        bool
+	latency        bool
+	accessTracking bool
+	ownedValues    bool
+	padded         bool
+	groupProbing   bool
+	probeDepth     *probeDepthConfig
+	doorkeeper     int
+	ghosts         int
+	logger         *slog.Logger
+	writeThrough   bool
+	writeBehind    *writeBehindConfig
+	asyncPuts      *asyncPutConfig
+	negativeTTL    time.Duration
+	loadTimeout    time.Duration
+
+	ttl                  time.Duration
+	ttlJitter            float64
+	expirationTick       time.Duration
+	ctx                  context.Context
+	staleWhileRevalidate bool
+	beta                 float64
+	refreshAhead         *refreshAheadConfig[K, V]
+	overflow             *DiskTier[K, V]
+	invalidator          Invalidator
+	hashFunc             func(K) uint64
+
+	// errs collects errors of options which were given invalid arguments.
+	errs []error
+	// lockFreeOnly collects the names of the options given which Cache does not support.
+	lockFreeOnly []string
+}
+
+func newConfig[K comparable, V any](opts []Option[K, V]) config[K, V] {
+	var cfg config[K, V]
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	return cfg
+}
+
+// detached returns the configuration without the resources which the cache would share with other caches:
+// the overflow tier, invalidator, eviction event channel and context, and without the background workers
+// which load or write on behalf of the cache, so a clone neither affects nor depends on its source.
+func (cfg config[K, V]) detached() config[K, V] {
+	cfg.overflow = nil
+	cfg.invalidator = nil
+	cfg.evictionEvents = nil
+	cfg.ctx = nil
+	cfg.refreshAhead = nil
+	cfg.asyncPuts = nil
+
+	if cfg.probeDepth != nil {
+		fixed := *cfg.probeDepth
+		fixed.interval = 0
+		cfg.probeDepth = &fixed
+	}
+
+	return cfg
+}
+
+// validate checks the configuration for invalid arguments and option combinations.
+func (cfg *config[K, V]) validate() error {
+	errs := cfg.errs
+
+	if cfg.costFunc != nil && cfg.weigher != nil {
+		errs = append(errs, fmt.Errorf("%w: both a cost function and a weigher are set", ErrInvalidConfig))
+	}
+
+	if cfg.maxCost > 0 && cfg.costFunc == nil && cfg.weigher == nil {
+		errs = append(errs, fmt.Errorf("%w: max cost requires a cost function or weigher", ErrInvalidConfig))
+	}
+
+	if cfg.maxCost > 0 && cfg.budget > 0 {
+		errs = append(errs, fmt.Errorf("%w: both a max cost and a memory budget are set", ErrInvalidConfig))
+	}
+
+	if cfg.staleWhileRevalidate && cfg.ttl == 0 {
+		errs = append(errs, fmt.Errorf("%w: stale-while-revalidate requires a TTL", ErrInvalidConfig))
+	}
+
+	if cfg.ttlJitter > 0 && cfg.ttl == 0 {
+		errs = append(errs, fmt.Errorf("%w: TTL jitter requires a TTL", ErrInvalidConfig))
+	}
+
+	if cfg.beta > 0 && cfg.ttl == 0 {
+		errs = append(errs, fmt.Errorf("%w: early expiration requires a TTL", ErrInvalidConfig))
+	}
+
+	if err := cfg.validateWriteMode(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateWriteMode rejects setting both write-through and write-behind.
+func (cfg *config[K, V]) validateWriteMode() error {
+	if cfg.writeThrough && cfg.writeBehind != nil {
+		return fmt.Errorf("%w: both write-through and write-behind are set", ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+// validateCache is like validate, but also rejects the options which only apply to LockFreeCache.
+func (cfg *config[K, V]) validateCache() error {
+	return errors.Join(cfg.validate(), cfg.validateCacheOptions())
+}
+
+// validateCacheOptions rejects the options which Cache does not support, as it only applies WithEqualFunc.
+func (cfg *config[K, V]) validateCacheOptions() error {
+	if len(cfg.lockFreeOnly) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: options which only apply to LockFreeCache are set: %s",
+		ErrInvalidConfig, strings.Join(cfg.lockFreeOnly, ", "))
+}
+
+// weigh returns the function computing entry costs, or nil if cost accounting is disabled.
+func (cfg *config[K, V]) weigh() func(K, *V) int64 {
+	switch {
+	case cfg.weigher != nil:
+		return cfg.weigher
+	case cfg.costFunc != nil:
+		return func(key K, value *V) int64 {
+			return cfg.costFunc(key, *value)
+		}
+	case cfg.budget > 0:
+		return new(sizeEstimator[K, V]).estimate
+	default:
+		return nil
+	}
+}
+
+// log returns the logger for internal diagnostics, which discards by default.
+func (cfg *config[K, V]) log() *slog.Logger {
+	if cfg.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+
+	return cfg.logger
+}
+
+// stride returns the distance between slots, in slots.
+func (cfg *config[K, V]) stride() int {
+	if cfg.padded {
+		return cacheLineSize / int(unsafe.Sizeof(tableSlot[K, V]{}))
+	}
+
+	return 1
+}
+
+// equal returns a function comparing values for CompareAndSwap and CompareAndDelete.
+func (cfg *config[K, V]) equal() func(a, b *V) bool {
+	switch {
+	case cfg.equalFunc != nil:
+		return func(a, b *V) bool {
+			return a == b || (a != nil && b != nil && cfg.equalFunc(*a, *b))
+		}
+	case reflect.TypeFor[V]().Comparable():
+		return func(a, b *V) bool {
+			return a == b || (a != nil && b != nil && any(*a) == any(*b))
+		}
+	default:
+		return func(a, b *V) bool {
+			return a == b
+		}
+	}
+}
+
+// limit returns the maximum total cost, or zero if unbounded.
+func (cfg *config[K, V]) limit() int64 {
+	if cfg.budget > 0 {
+		return cfg.budget
+	}
+
+	return cfg.maxCost
+}
+
+func (cfg *config[K, V]) invalid(format string, args ...any) {
+	cfg.errs = append(cfg.errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+}
+
+// lockFreeOnly returns an option which runs apply and records its name, so Cache can reject it.
+func lockFreeOnly[K comparable, V any](name string, apply func(cfg *config[K, V])) Option[K, V] {
+	return func(cfg *config[K, V]) {
+		cfg.lockFreeOnly = append(cfg.lockFreeOnly, name)
+		apply(cfg)
+	}
+}
+
+// WithEqualFunc sets the function used to compare values in CompareAndSwap and CompareAndDelete.
+// It applies to both Cache and LockFreeCache.
+func WithEqualFunc[K comparable, V any](equalFunc func(a, b V) bool) Option[K, V] {
+	return func(cfg *config[K, V]) {
+		if equalFunc == nil {
+			cfg.invalid("nil equal function")
+		}
+
+		cfg.equalFunc = equalFunc
+	}
+}
+
+// WithCostFunc sets a function which computes the cost of an entry.
+// The cost is evaluated on every Put, including when the key already exists,
+// and is summed into Metrics.CurrentCost. Nil values have zero cost. It cannot be combined with WithWeigher.
+func WithCostFunc[K comparable, V any](costFunc func(K, V) int64) Option[K, V] {
+	return lockFreeOnly("WithCostFunc", func(cfg *config[K, V]) {
+		if costFunc == nil {
+			cfg.invalid("nil cost function")
+		}
+
+		cfg.costFunc = costFunc
+	})
+}
+
+// WithWeigher sets a function which computes the weight of an entry, for example its size in bytes.
+// It behaves like WithCostFunc, but receives the value pointer so large values are not copied.
+func WithWeigher[K comparable, V any](weigher func(K, *V) int64) Option[K, V] {
+	return lockFreeOnly("WithWeigher", func(cfg *config[K, V]) {
+		if weigher == nil {
+			cfg.invalid("nil weigher")
+		}
+
+		cfg.weigher = weigher
+	})
+}
+
+// WithMaxCost bounds the total cost of all entries, as computed by WithCostFunc or WithWeigher.
+// Once a write exceeds the bound, random unpinned entries are evicted until the total is within it again.
+// An entry whose own cost exceeds the bound is evicted as well.
+func WithMaxCost[K comparable, V any](maxCost int64) Option[K, V] {
+	return lockFreeOnly("WithMaxCost", func(cfg *config[K, V]) {
+		if maxCost <= 0 {
+			cfg.invalid("max cost must be positive, got %d", maxCost)
+		}
+
+		cfg.maxCost = maxCost
+	})
+}
+
+// WithCostSweepInterval sets how often the table is swept for entries whose value was reclaimed by the garbage
+// collector, so their cost is released from Metrics.CurrentCost. The sweep runs whenever costs are tracked,
+// by default every second, and visits every slot of the table.
+func WithCostSweepInterval[K comparable, V any](interval time.Duration) Option[K, V] {
+	return lockFreeOnly("WithCostSweepInterval", func(cfg *config[K, V]) {
+		if interval <= 0 {
+			cfg.invalid("cost sweep interval must be positive, got %s", interval)
+			return
+		}
+
+		cfg.costSweep = interval
+	})
+}
+
+// WithMemoryBudget caps the cache at an approximate number of bytes, evicting random unpinned entries on write.
+// Entry sizes are computed by WithCostFunc or WithWeigher if set, and are otherwise estimated
+// by measuring a sample of entries with reflection. It cannot be combined with WithMaxCost.
+func WithMemoryBudget[K comparable, V any](bytes int64) Option[K, V] {
+	return lockFreeOnly("WithMemoryBudget", func(cfg *config[K, V]) {
+		if bytes <= 0 {
+			cfg.invalid("memory budget must be positive, got %d", bytes)
+		}
+
+		cfg.budget = bytes
+	})
+}
+
+// WithMemoryPressure proactively evicts entries when the process memory exceeds the threshold fraction
+// of its memory limit, as set by debug.SetMemoryLimit or GOMEMLIMIT. On every check above the threshold,
+// the given fraction of unpinned entries is dropped, instead of waiting for the garbage collector
+// to reclaim weakly referenced values. Without a memory limit the option has no effect.
+func WithMemoryPressure[K comparable, V any](threshold, fraction float64) Option[K, V] {
+	return lockFreeOnly("WithMemoryPressure", func(cfg *config[K, V]) {
+		if threshold <= 0 || threshold > 1 {
+			cfg.invalid("memory pressure threshold must be within (0, 1], got %f", threshold)
+		}
+
+		if fraction <= 0 || fraction > 1 {
+			cfg.invalid("memory pressure eviction fraction must be within (0, 1], got %f", fraction)
+		}
+
+		cfg.pressure = &memoryPressure{threshold: threshold, fraction: fraction}
+	})
+}
+
+// WithHooks sets callbacks which are invoked on inserts, evictions, hits and misses.
+func WithHooks[K comparable, V any](hooks Hooks[K, V]) Option[K, V] {
+	return lockFreeOnly("WithHooks", func(cfg *config[K, V]) {
+		cfg.hooks = hooks
+	})
+}
+
+// WithEvictionEvents sends an event for every evicted entry on events, so they can be consumed
+// by a separate goroutine. Sends never block: if the channel is full, the event is dropped and
+// counted in Metrics.DroppedEvictionEvents, so the channel should be buffered.
+func WithEvictionEvents[K comparable, V any](events chan<- EvictionEvent) Option[K, V] {
+	return lockFreeOnly("WithEvictionEvents", func(cfg *config[K, V]) {
+		cfg.evictionEvents = events
+	})
+}
+
+// WithReclaimCallback sets a callback which is invoked with the key hash of every entry whose value
+// was reclaimed by the garbage collector, for example to refetch it proactively.
+// Reclaimed entries are swept from a cleanup registered for each stored value, so the callback usually
+// fires soon after the collection, from a runtime goroutine.
+func WithReclaimCallback[K comparable, V any](fn func(keyHash uint64)) Option[K, V] {
+	return lockFreeOnly("WithReclaimCallback", func(cfg *config[K, V]) {
+		cfg.onReclaim = fn
+	})
+}
+
+// WithHitRateTracking enables tracking hits and misses over the last 15 minutes,
+// as reported by HitRate. It adds a clock read to every Get.
+func WithHitRateTracking[K comparable, V any]() Option[K, V] {
+	return lockFreeOnly("WithHitRateTracking", func(cfg *config[K, V]) {
+		cfg.hitRate = true
+	})
+}
+
+// WithLatencyTracking enables recording the latencies of Get and Put in histograms,
+// as reported by Metrics. It adds two clock reads to every Get and Put.
+func WithLatencyTracking[K comparable, V any]() Option[K, V] {
+	return lockFreeOnly("WithLatencyTracking", func(cfg *config[K, V]) {
+		cfg.latency = true
+	})
+}
+
+// WithAccessTracking enables tracking the last access time and number of hits of every entry,
+// as reported by GetEntryInfo. It adds an allocation to every write, and a clock read to every hit of Get.
+func WithAccessTracking[K comparable, V any]() Option[K, V] {
+	return lockFreeOnly("WithAccessTracking", func(cfg *config[K, V]) {
+		cfg.accessTracking = true
+	})
+}
+
+// WithOwnedValues makes the cache store a copy of every value it is given, and hold the copy strongly until the entry
+// is removed, so entries no longer depend on callers keeping their values alive. Values are copied shallowly,
+// so the memory referenced by a value, such as the contents of slices and maps, is still shared with the caller.
+// Values which are read by reference, as by GetRef, are the copies.
+func WithOwnedValues[K comparable, V any]() Option[K, V] {
+	return lockFreeOnly("WithOwnedValues", func(cfg *config[K, V]) {
+		cfg.ownedValues = true
+	})
+}
+
+// WithPaddedSlots pads every slot to a cache line, so concurrent writes to neighbouring slots
+// do not contend on the same cache line. It multiplies the memory used by the slots by 4 on 64-bit platforms.
+func WithPaddedSlots[K comparable, V any]() Option[K, V] {
+	return lockFreeOnly("WithPaddedSlots", func(cfg *config[K, V]) {
+		cfg.padded = true
+	})
+}
+
+// WithGroupProbing lays out the slots in groups of 16 with a control byte each, in the style of SwissTable.
+// Lookups compare the control bytes of a group at once, and only load the entries of slots which likely hold their
+// key, which saves most pointer loads of misses and deep hits in big tables.
+// The size is rounded up to a multiple of 16, and the hash probe depth to whole groups, of at least four.
+func WithGroupProbing[K comparable, V any]() Option[K, V] {
+	return lockFreeOnly("WithGroupProbing", func(cfg *config[K, V]) {
+		cfg.groupProbing = true
+	})
+}
+
+type probeDepthConfig struct {
+	// interval is 0 for clones, which keep the depth within the range without tuning it.
+	interval           time.Duration
+	minDepth, maxDepth int
+}
+
+// WithAdaptiveProbeDepth tunes the hash probe depth every interval within minDepth and maxDepth, instead of
+// deriving it from the size only. The depth grows while writes overflow their probe sequence and evict random
+// entries, and shrinks while writes rarely claim the deepest probe, so lookups stay short. Entries which are
+// beyond a shrunk probe depth are evicted. With WithGroupProbing, the depth is tuned in whole groups.
+func WithAdaptiveProbeDepth[K comparable, V any](interval time.Duration, minDepth, maxDepth int) Option[K, V] {
+	return lockFreeOnly("WithAdaptiveProbeDepth", func(cfg *config[K, V]) {
+		if interval <= 0 || minDepth <= 0 {
+			cfg.invalid("probe depth interval %s and minimum depth %d must be positive", interval, minDepth)
+			return
+		}
+
+		if maxDepth < minDepth {
+			cfg.invalid("maximum probe depth %d must not be below the minimum %d", maxDepth, minDepth)
+			return
+		}
+
+		cfg.probeDepth = &probeDepthConfig{interval: interval, minDepth: minDepth, maxDepth: maxDepth}
+	})
+}
+
+// WithDoorkeeper only admits a new key into the cache on its second put among the last keys distinct new keys,
+// so scans of keys which are written once do not flush the entries which are used repeatedly. Puts of keys which
+// are in the cache are always admitted, and loads of GetOrLoad are filtered like puts, while merges, snapshots and
+// replication bypass the filter. Rejected puts are counted in Metrics.DoorkeeperRejects.
+// The filter takes 10 bits per key, and keys of about the cache size is a reasonable default.
+func WithDoorkeeper[K comparable, V any](keys int) Option[K, V] {
+	return lockFreeOnly("WithDoorkeeper", func(cfg *config[K, V]) {
+		if keys <= 0 {
+			cfg.invalid("doorkeeper keys %d must be positive", keys)
+			return
+		}
+
+		cfg.doorkeeper = keys
+	})
+}
+
+// WithGhostTracking remembers the hashes of the last keys entries which were overwritten or evicted for capacity,
+// and counts the misses of such keys in Metrics.GhostHits. These are misses a bigger cache would likely have served,
+// so remembering about as many keys as the cache holds estimates the benefit of doubling its size.
+// It takes 8 bytes per remembered key.
+func WithGhostTracking[K comparable, V any](keys int) Option[K, V] {
+	return lockFreeOnly("WithGhostTracking", func(cfg *config[K, V]) {
+		if keys <= 0 {
+			cfg.invalid("ghost keys %d must be positive", keys)
+			return
+		}
+
+		cfg.ghosts = keys
+	})
+}
+
+// WithLogger sets the logger for internal diagnostics, which are logged at debug level.
+// By default diagnostics are discarded.
+func WithLogger[K comparable, V any](logger *slog.Logger) Option[K, V] {
+	return lockFreeOnly("WithLogger", func(cfg *config[K, V]) {
+		cfg.logger = logger
+	})
+}
+
+// WithWriteThrough makes a ReadThroughCache save every Put and Delete to its store before updating
+// the cache, so the store must implement WriteStore. It only applies to ReadThroughCache.
+func WithWriteThrough[K comparable, V any]() Option[K, V] {
+	return lockFreeOnly("WithWriteThrough", func(cfg *config[K, V]) {
+		cfg.writeThrough = true
+	})
+}
+
+type writeBehindConfig struct {
+	queueSize int
+	workers   int
+	interval  time.Duration
+}
+
+// WithWriteBehind makes a ReadThroughCache update the cache immediately on Put and Delete, and save the writes
+// to its store asynchronously. Up to queueSize writes are queued; workers save them in batches, at least every
+// interval. Each key is saved by the same worker, so its writes are saved in order, and repeated writes
+// of a key within a batch are coalesced. The store must implement WriteStore,
+// and batches are saved with SaveBatch if it implements BatchWriteStore. It only applies to ReadThroughCache,
+// and cannot be combined with WithWriteThrough: NewReadThroughCache panics if both are given.
+func WithWriteBehind[K comparable, V any](queueSize, workers int, interval time.Duration) Option[K, V] {
+	return lockFreeOnly("WithWriteBehind", func(cfg *config[K, V]) {
+		if queueSize <= 0 || workers <= 0 || interval <= 0 {
+			cfg.invalid("write-behind queue size %d, workers %d and interval %s must be positive", queueSize, workers, interval)
+			return
+		}
+
+		cfg.writeBehind = &writeBehindConfig{queueSize: queueSize, workers: workers, interval: interval}
+	})
+}
+
+type asyncPutConfig struct {
+	queueSize int
+	policy    QueuePolicy
+}
+
+// WithAsyncPuts makes PutAsync queue up to queueSize puts for a background writer, which applies them in batches.
+// The policy sets what PutAsync does when the queue is full.
+func WithAsyncPuts[K comparable, V any](queueSize int, policy QueuePolicy) Option[K, V] {
+	return lockFreeOnly("WithAsyncPuts", func(cfg *config[K, V]) {
+		if queueSize <= 0 {
+			cfg.invalid("async put queue size %d must be positive", queueSize)
+			return
+		}
+
+		if policy < QueueBlock || policy > QueueDropOldest {
+			cfg.invalid("unknown queue policy %d", policy)
+			return
+		}
+
+		cfg.asyncPuts = &asyncPutConfig{queueSize: queueSize, policy: policy}
+	})
+}
+
+// WithNegativeTTL caches loads which returned ErrNotFound for ttl, so GetOrLoad returns ErrNotFound
+// without calling the loader again until then. Negative results are kept in a fixed-size table of the same
+// size as the cache, and are removed when a value is stored for the key.
+func WithNegativeTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
+	return lockFreeOnly("WithNegativeTTL", func(cfg *config[K, V]) {
+		if ttl <= 0 {
+			cfg.invalid("negative TTL %s must be positive", ttl)
+			return
+		}
+
+		cfg.negativeTTL = ttl
+	})
+}
+
+// WithLoadTimeout cancels the context of every load of GetOrLoad after timeout, including background refreshes,
+// so a hanging backend does not hold up the calls waiting for a key.
+func WithLoadTimeout[K comparable, V any](timeout time.Duration) Option[K, V] {
+	return lockFreeOnly("WithLoadTimeout", func(cfg *config[K, V]) {
+		if timeout <= 0 {
+			cfg.invalid("load timeout %s must be positive", timeout)
+			return
+		}
+
+		cfg.loadTimeout = timeout
+	})
+}
+
+// WithTTL sets the time to live of entries, after which they are no longer returned.
+// Expired entries are removed when they are found, or overwritten by new entries.
+func WithTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
+	return lockFreeOnly("WithTTL", func(cfg *config[K, V]) {
+		if ttl <= 0 {
+			cfg.invalid("TTL %s must be positive", ttl)
+			return
+		}
+
+		cfg.ttl = ttl
+	})
+}
+
+// WithTTLJitter randomizes the time to live of every entry by up to plus or minus fraction of it,
+// so entries stored together, such as during a warmup, do not all expire at the same instant.
+// The fraction must be between 0 and 1. It requires WithTTL, and does not apply to PutWithExpiry.
+func WithTTLJitter[K comparable, V any](fraction float64) Option[K, V] {
+	return lockFreeOnly("WithTTLJitter", func(cfg *config[K, V]) {
+		if !(fraction > 0 && fraction < 1) {
+			cfg.invalid("TTL jitter %g must be between 0 and 1", fraction)
+			return
+		}
+
+		cfg.ttlJitter = fraction
+	})
+}
+
+// WithExpirationWheel removes expired entries in the background, instead of only when their slot is next read
+// or written, so the memory of expired keys is released in time. Expirations are scheduled on a timing wheel with
+// the resolution of tick, so every expiration costs constant time, and the worker only touches the entries which
+// are due. Entries kept by WithStaleWhileRevalidate are not removed.
+func WithExpirationWheel[K comparable, V any](tick time.Duration) Option[K, V] {
+	return lockFreeOnly("WithExpirationWheel", func(cfg *config[K, V]) {
+		if tick <= 0 {
+			cfg.invalid("expiration tick %s must be positive", tick)
+			return
+		}
+
+		cfg.expirationTick = tick
+	})
+}
+
+// WithContext closes the cache once ctx is done, as Close, for example to stop its background workers
+// on shutdown of the process. Errors of closing are reported by the Err methods of an AppendLog or Replicator.
+func WithContext[K comparable, V any](ctx context.Context) Option[K, V] {
+	return lockFreeOnly("WithContext", func(cfg *config[K, V]) {
+		if ctx == nil {
+			cfg.invalid("nil context")
+			return
+		}
+
+		cfg.ctx = ctx
+	})
+}
+
+// WithStaleWhileRevalidate keeps expired entries, as long as their values are alive, so GetOrLoad can return them
+// immediately while refreshing them in the background. It requires WithTTL.
+func WithStaleWhileRevalidate[K comparable, V any]() Option[K, V] {
+	return lockFreeOnly("WithStaleWhileRevalidate", func(cfg *config[K, V]) {
+		cfg.staleWhileRevalidate = true
+	})
+}
+
+// WithRefreshAhead reloads the keys with the most hits every interval, if they would expire before the next
+// refresh or were reclaimed. The values of these keys are kept alive until the next refresh, so they stay warm.
+func WithRefreshAhead[K comparable, V any](interval time.Duration, keys int, load func(ctx context.Context, key K) (*V, error)) Option[K, V] {
+	return lockFreeOnly("WithRefreshAhead", func(cfg *config[K, V]) {
+		switch {
+		case interval <= 0:
+			cfg.invalid("refresh interval %s must be positive", interval)
+			return
+		case keys <= 0:
+			cfg.invalid("number of keys to refresh %d must be positive", keys)
+			return
+		case load == nil:
+			cfg.invalid("nil refresh loader")
+			return
+		}
+
+		cfg.refreshAhead = &refreshAheadConfig[K, V]{interval: interval, keys: keys, load: load}
+	})
+}
+
+// WithOverflow writes every stored entry through to tier, and serves reads which miss the table from it,
+// promoting the entry back into the table. Entries are written on every store rather than on eviction,
+// as values reclaimed by the garbage collector no longer exist to be spilled.
+func WithOverflow[K comparable, V any](tier *DiskTier[K, V]) Option[K, V] {
+	return lockFreeOnly("WithOverflow", func(cfg *config[K, V]) {
+		if tier == nil {
+			cfg.invalid("nil overflow tier")
+			return
+		}
+
+		cfg.overflow = tier
+	})
+}
+
+// WithInvalidator publishes the keys written by Put, Delete and the other write methods of the cache to invalidator,
+// and evicts the keys of invalidations it receives. Writes by GetOrLoad and evictions are not published.
+// Keys are hashed by hash instead of a random seed, so hash must return the same hash for a key in all processes,
+// for example FNV-1a of its encoding.
+func WithInvalidator[K comparable, V any](invalidator Invalidator, hash func(key K) uint64) Option[K, V] {
+	return lockFreeOnly("WithInvalidator", func(cfg *config[K, V]) {
+		if invalidator == nil || hash == nil {
+			cfg.invalid("nil invalidator or hash function")
+			return
+		}
+
+		cfg.invalidator = invalidator
+		cfg.hashFunc = hash
+	})
+}
+
+// WithEarlyExpiration lets GetOrLoad refresh loaded entries before they expire, to prevent a stampede of loads
+// at their expiry. The chance of an early refresh rises as the expiry nears, scaled by how long the value took
+// to load and by beta, where 1 is a good default and larger values refresh earlier. It requires WithTTL.
+func WithEarlyExpiration[K comparable, V any](beta float64) Option[K, V] {
+	return lockFreeOnly("WithEarlyExpiration", func(cfg *config[K, V]) {
+		if !(beta > 0) || math.IsInf(beta, 1) {
+			cfg.invalid("early expiration beta %g must be positive and finite", beta)
+			return
+		}
+
+		cfg.beta = beta
+	})
+}
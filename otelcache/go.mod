@@ -0,0 +1,22 @@
+module github.com/samborkent/cache/otelcache
+
+go 1.24.0
+
+require (
+	github.com/samborkent/cache v0.0.0-00010101000000-000000000000
+	github.com/samborkent/check v0.0.0-20250216103840-eaa4d4426057
+	go.opentelemetry.io/otel v1.32.0
+	go.opentelemetry.io/otel/metric v1.32.0
+	go.opentelemetry.io/otel/sdk v1.32.0
+	go.opentelemetry.io/otel/sdk/metric v1.32.0
+	go.opentelemetry.io/otel/trace v1.32.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	golang.org/x/sys v0.27.0 // indirect
+)
+
+replace github.com/samborkent/cache => ../
@@ -0,0 +1,65 @@
+// Package otelcache instruments caches with OpenTelemetry.
+package otelcache
+
+import (
+	"context"
+
+	"github.com/samborkent/cache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Source is a cache whose metrics can be observed.
+type Source interface {
+	Metrics() cache.Metrics
+	Len() int
+}
+
+// RegisterMetrics registers asynchronous instruments for the hit ratio, live entries and evictions by reason
+// of source against meter. Every observation carries a "cache.name" attribute with the given name.
+// Call Unregister on the returned registration to stop observing the cache.
+func RegisterMetrics(meter metric.Meter, name string, source Source) (metric.Registration, error) {
+	hitRatio, err := meter.Float64ObservableGauge("cache.hit_ratio",
+		metric.WithDescription("Ratio of reads which found a live entry."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := meter.Int64ObservableGauge("cache.entries",
+		metric.WithDescription("Number of live entries."),
+		metric.WithUnit("{entry}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	evictions, err := meter.Int64ObservableCounter("cache.evictions",
+		metric.WithDescription("Number of entries removed from the cache, by reason."),
+		metric.WithUnit("{entry}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheName := attribute.String("cache.name", name)
+
+	return meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		metrics := source.Metrics()
+
+		if reads := metrics.ReadHits + metrics.ReadMisses; reads > 0 {
+			observer.ObserveFloat64(hitRatio, float64(metrics.ReadHits)/float64(reads), metric.WithAttributes(cacheName))
+		}
+
+		observer.ObserveInt64(entries, int64(source.Len()), metric.WithAttributes(cacheName))
+
+		for reason, count := range metrics.Evictions {
+			observer.ObserveInt64(evictions, int64(count), metric.WithAttributes(
+				cacheName,
+				attribute.String("reason", cache.EvictionReason(reason).String()),
+			))
+		}
+
+		return nil
+	}, hitRatio, entries, evictions)
+}
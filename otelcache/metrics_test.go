@@ -0,0 +1,46 @@
+package otelcache_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/otelcache"
+	"github.com/samborkent/check"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	t.Parallel()
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	testCache := cache.NewLockFreeCache[string, uint64](16)
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+	testCache.Get("key")
+
+	registration, err := otelcache.RegisterMetrics(meter, "test", testCache)
+	check.True(t, err == nil)
+
+	defer registration.Unregister()
+
+	var data metricdata.ResourceMetrics
+	check.True(t, reader.Collect(context.Background(), &data) == nil)
+	check.Equal(t, len(data.ScopeMetrics), 1)
+
+	names := make(map[string]bool)
+	for _, m := range data.ScopeMetrics[0].Metrics {
+		names[m.Name] = true
+	}
+
+	check.True(t, names["cache.hit_ratio"])
+	check.True(t, names["cache.entries"])
+	check.True(t, names["cache.evictions"])
+
+	runtime.KeepAlive(&val)
+}
@@ -0,0 +1,32 @@
+package otelcache
+
+import (
+	"context"
+
+	"github.com/samborkent/cache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LoadTracing returns a cache.Hooks OnLoad function, which traces every GetOrLoad call with a span.
+// Spans are annotated with whether the call was a hit, a miss which called the loader, or coalesced
+// with a concurrent load, and record loader errors.
+func LoadTracing[K comparable](tracer trace.Tracer, name string) func(context.Context, K) (context.Context, func(cache.LoadResult, error)) {
+	cacheName := attribute.String("cache.name", name)
+
+	return func(ctx context.Context, _ K) (context.Context, func(cache.LoadResult, error)) {
+		ctx, span := tracer.Start(ctx, "cache.GetOrLoad", trace.WithAttributes(cacheName))
+
+		return ctx, func(result cache.LoadResult, err error) {
+			span.SetAttributes(attribute.String("cache.result", result.String()))
+
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			span.End()
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package otelcache_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/otelcache"
+	"github.com/samborkent/check"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestLoadTracing(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	testCache := cache.NewLockFreeCache(16, cache.WithHooks(cache.Hooks[string, uint64]{
+		OnLoad: otelcache.LoadTracing[string](tracer, "test"),
+	}))
+
+	val := uint64(1)
+	load := func(context.Context, string) (*uint64, error) {
+		return &val, nil
+	}
+
+	_, err := testCache.GetOrLoad(t.Context(), "key", load)
+	check.True(t, err == nil)
+
+	_, err = testCache.GetOrLoad(t.Context(), "key", load)
+	check.True(t, err == nil)
+
+	spans := recorder.Ended()
+	check.Equal(t, len(spans), 2)
+
+	var results []string
+
+	for _, span := range spans {
+		for _, kv := range span.Attributes() {
+			if kv.Key == "cache.result" {
+				results = append(results, kv.Value.AsString())
+			}
+		}
+	}
+
+	check.Equal(t, len(results), 2)
+	check.Equal(t, results[0], "miss")
+	check.Equal(t, results[1], "hit")
+
+	runtime.KeepAlive(&val)
+}
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"errors"
+	"hash/maphash"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PerProcCache is an experimental cache for ingest-heavy workloads, which has a LockFreeCache sub-table per
+// processor (P) of the Go scheduler. Writes go to the sub-table of the processor they run on, so concurrent
+// writers on different cores do not contend on the same slots, while reads search all sub-tables on a miss.
+//
+// Go does not expose the current processor, so it is approximated with a sync.Pool, which keeps its items per
+// processor: a goroutine usually gets back the sub-table index which was last put on its processor. Goroutines
+// which migrate, or a garbage collection which clears the pool, merely send some writes to other sub-tables.
+//
+// Every write is numbered by the cache, and a sub-table only replaces an entry by a later write. After storing
+// its entry, a write removes the earlier entries for the key from the other sub-tables, or its own entry if
+// another sub-table holds a later one, so every key has a single entry once concurrent writes of it completed,
+// as in the other caches. Until then, Len may count a key more than once.
+//
+// As a single writer fills only the sub-table of its processor, every sub-table has room for the given size
+// divided by GOMAXPROCS. Options are applied to every sub-table, so limits such as WithMaxCost apply per sub-table.
+type PerProcCache[K comparable, V any] struct {
+	tables []*LockFreeCache[K, V]
+	hints  sync.Pool
+	next   atomic.Uint64
+	// writes numbers the writes across sub-tables, to order concurrent writes of a key.
+	writes atomic.Uint64
+}
+
+// NewPerProcCache returns a cache of the given total size, split across a sub-table per processor,
+// as set by GOMAXPROCS. If the size is not positive, an empty cache is returned.
+func NewPerProcCache[K comparable, V any](size int, opts ...Option[K, V]) *PerProcCache[K, V] {
+	c := &PerProcCache[K, V]{}
+
+	c.hints.New = func() any {
+		hint := int(c.next.Add(1) % uint64(len(c.tables)))
+		return &hint
+	}
+
+	if size <= 0 {
+		// Uninitialized sub-tables are safe to use, but never store anything.
+		c.tables = []*LockFreeCache[K, V]{{}}
+		return c
+	}
+
+	cfg := newConfig(opts)
+	tables := runtime.GOMAXPROCS(0)
+	tableSize := (size + tables - 1) / tables
+
+	c.tables = make([]*LockFreeCache[K, V], tables)
+	for i := range c.tables {
+		c.tables[i] = newLockFreeCache(tableSize, maphash.MakeSeed(), cfg)
+	}
+
+	return c
+}
+
+// local returns the index of the sub-table of the current processor.
+func (c *PerProcCache[K, V]) local() int {
+	hint, _ := c.hints.Get().(*int)
+	index := *hint
+	c.hints.Put(hint)
+
+	return index
+}
+
+// Put stores value in the sub-table of the current processor, and removes key from the other sub-tables.
+// Of concurrent writes of key, the one which was numbered last is kept.
+func (c *PerProcCache[K, V]) Put(key K, value *V) {
+	write := c.writes.Add(1)
+	local := c.local()
+	table := c.tables[local]
+
+	// A later write of key to the same sub-table removes the earlier entries itself.
+	if !table.putNewer(key, value, write) {
+		return
+	}
+
+	for i, other := range c.tables {
+		if i == local {
+			continue
+		}
+
+		theirs, ok := other.versionOf(key)
+		if !ok {
+			continue
+		}
+
+		if theirs > write {
+			table.deleteVersion(key, write)
+			return
+		}
+
+		other.deleteVersion(key, theirs)
+	}
+}
+
+// Get returns the value for key, searching the sub-table of the current processor first.
+// Only the sub-table which holds key, or the last one searched, counts the read in its metrics.
+func (c *PerProcCache[K, V]) Get(key K) (V, bool) {
+	local := c.local()
+
+	for i := range c.tables {
+		table := c.tables[(local+i)%len(c.tables)]
+
+		if i == len(c.tables)-1 || table.Contains(key) {
+			return table.Get(key)
+		}
+	}
+
+	return *new(V), false
+}
+
+// Delete removes key from all sub-tables.
+func (c *PerProcCache[K, V]) Delete(key K) {
+	for _, table := range c.tables {
+		table.Delete(key)
+	}
+}
+
+func (c *PerProcCache[K, V]) Len() int {
+	count := 0
+
+	for _, table := range c.tables {
+		count += table.Len()
+	}
+
+	return count
+}
+
+func (c *PerProcCache[K, V]) Cap() int {
+	size := 0
+
+	for _, table := range c.tables {
+		size += table.Cap()
+	}
+
+	return size
+}
+
+// Metrics returns the metrics aggregated over all sub-tables.
+func (c *PerProcCache[K, V]) Metrics() Metrics {
+	var metrics Metrics
+
+	for _, table := range c.tables {
+		metrics = metrics.add(table.Metrics())
+	}
+
+	return metrics
+}
+
+// ResetMetrics sets the metric counters of all sub-tables to zero.
+func (c *PerProcCache[K, V]) ResetMetrics() {
+	for _, table := range c.tables {
+		table.ResetMetrics()
+	}
+}
+
+// Close closes all sub-tables, as LockFreeCache.Close, and returns their errors.
+func (c *PerProcCache[K, V]) Close() error {
+	errs := make([]error, 0, len(c.tables))
+
+	for _, table := range c.tables {
+		errs = append(errs, table.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// putNewer stores value for key with the given version, unless the live entry for key has a later version,
+// and reports whether it did.
+func (c *LockFreeCache[K, V]) putNewer(key K, value *V, version uint64) bool {
+	if !c.initialized.Load() {
+		return false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	newEntry := c.newEntry(key, value, time.Now().UnixNano())
+	newEntry.version = version
+
+	for {
+		index, entry, _ := c.find(key)
+
+		switch {
+		case entry == nil:
+			if c.putIfAbsent(newEntry) {
+				c.publish(key)
+				return true
+			}
+
+			// Without a concurrent write of key, all probed slots are pinned or contended.
+			if _, entry, _ := c.find(key); entry == nil {
+				return false
+			}
+		case entry.version > version:
+			return false
+		case c.swapSlot(index, entry, newEntry):
+			c.account(newEntry, entry, EvictionReplaced)
+			c.publish(key)
+
+			return true
+		}
+	}
+}
+
+// versionOf returns the version of the live entry for key.
+func (c *LockFreeCache[K, V]) versionOf(key K) (uint64, bool) {
+	if !c.initialized.Load() {
+		return 0, false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	_, entry, _ := c.find(key)
+	if entry == nil {
+		return 0, false
+	}
+
+	return entry.version, true
+}
+
+// deleteVersion removes the entry for key, if it has the given version, as it was replaced by a later write
+// elsewhere.
+func (c *LockFreeCache[K, V]) deleteVersion(key K, version uint64) {
+	if !c.initialized.Load() {
+		return
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	index, entry, _ := c.find(key)
+	if entry != nil && entry.version == version {
+		c.remove(entry, index, EvictionReplaced)
+	}
+}
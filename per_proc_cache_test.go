@@ -0,0 +1,90 @@
+package cache_test
+
+import (
+	cryptorand "crypto/rand"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestPerProcCache(t *testing.T) {
+	t.Parallel()
+
+	keys := make([]string, 1000)
+
+	// A single writer fills only the sub-table of its processor, so every sub-table needs room for all keys.
+	size := 4 * len(keys) * runtime.GOMAXPROCS(0)
+
+	testCache := cache.NewPerProcCache[string, uint64](size)
+	check.True(t, testCache.Cap() >= size)
+
+	values := make([]uint64, len(keys))
+
+	for i := range keys {
+		keys[i] = cryptorand.Text()
+		values[i] = uint64(i)
+	}
+
+	// Concurrent writers of the same keys leave every key with a single entry.
+	var wg sync.WaitGroup
+
+	for range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i, key := range keys {
+				testCache.Put(key, &values[i])
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	check.Equal(t, testCache.Len(), len(keys))
+
+	for i, key := range keys {
+		value, ok := testCache.Get(key)
+		check.True(t, ok)
+		check.Equal(t, value, values[i])
+	}
+
+	_, ok := testCache.Get(cryptorand.Text())
+	check.True(t, !ok)
+
+	// Every read is counted once.
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.ReadHits, uint64(len(keys)))
+	check.Equal(t, metrics.ReadMisses, 1)
+
+	for _, key := range keys[:100] {
+		testCache.Delete(key)
+	}
+
+	check.Equal(t, testCache.Len(), len(keys)-100)
+
+	_, ok = testCache.Get(keys[0])
+	check.True(t, !ok)
+
+	check.True(t, testCache.Close() == nil)
+
+	runtime.KeepAlive(values)
+}
+
+func TestPerProcCacheEmpty(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewPerProcCache[string, uint64](0)
+	check.Equal(t, testCache.Cap(), 0)
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+
+	_, ok := testCache.Get("key")
+	check.True(t, !ok)
+	check.Equal(t, testCache.Len(), 0)
+}
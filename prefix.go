@@ -0,0 +1,53 @@
+package cache
+
+import "strings"
+
+// DeleteFunc deletes all entries whose key matches fn, including pinned entries, and returns their number.
+// It scans the whole table, so it takes time proportional to the size of the cache.
+// Entries which only remain in an overflow tier are not deleted.
+func (c *LockFreeCache[K, V]) DeleteFunc(fn func(key K) bool) int {
+	if !c.initialized.Load() {
+		return 0
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	deleted := 0
+
+	for index := range c.size {
+		entry := c.slot(index).Load()
+		if entry == nil || entry.keyHash == 0 || !fn(entry.key) {
+			continue
+		}
+
+		// Removed entries are recycled, so keep the key.
+		key := entry.key
+
+		if c.remove(entry, index, EvictionDeleted) {
+			c.publish(key)
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// DeleteFunc deletes all entries whose key matches fn from all shards, as LockFreeCache.DeleteFunc.
+func (c *ShardedCache[K, V]) DeleteFunc(fn func(key K) bool) int {
+	deleted := 0
+
+	for _, shard := range c.shards {
+		deleted += shard.DeleteFunc(fn)
+	}
+
+	return deleted
+}
+
+// InvalidatePrefix deletes all entries of c whose key starts with prefix, such as all keys of a user with
+// the prefix "user:123:", and returns their number. Keys are retained in the entries, so no index is needed,
+// but it scans the whole cache, as DeleteFunc.
+func InvalidatePrefix[K ~string](c interface{ DeleteFunc(fn func(key K) bool) int }, prefix string) int {
+	return c.DeleteFunc(func(key K) bool {
+		return strings.HasPrefix(string(key), prefix)
+	})
+}
@@ -0,0 +1,40 @@
+package cache_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestInvalidatePrefix(t *testing.T) {
+	t.Parallel()
+
+	lockFree := cache.NewLockFreeCache[string, uint64](N / 100)
+	sharded := cache.NewShardedCache[string, uint64](4, N/100)
+
+	values := make([]uint64, 4)
+	keys := []string{"user:1:name", "user:1:email", "user:12:name", "user:2:name"}
+
+	for i, key := range keys {
+		lockFree.Put(key, &values[i])
+		sharded.Put(key, &values[i])
+	}
+
+	check.True(t, lockFree.Pin("user:1:email"))
+
+	check.Equal(t, cache.InvalidatePrefix(lockFree, "user:1:"), 2)
+	check.Equal(t, cache.InvalidatePrefix(sharded, "user:1:"), 2)
+
+	for _, c := range []cache.Interface[string, uint64]{lockFree, sharded} {
+		for i, key := range keys {
+			_, ok := c.Get(key)
+			check.Equal(t, ok, i >= 2)
+		}
+	}
+
+	check.Equal(t, cache.InvalidatePrefix(lockFree, "none:"), 0)
+
+	runtime.KeepAlive(values)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"math"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Memory pressure is checked at most once per pressureCheckInterval, and only every pressureCheckWrites writes.
+	pressureCheckInterval = 100 * time.Millisecond
+	pressureCheckWrites   = 256
+)
+
+// memoryPressure detects when the process approaches its memory limit, as set by debug.SetMemoryLimit or GOMEMLIMIT.
+type memoryPressure struct {
+	threshold float64
+	fraction  float64
+
+	writes    atomic.Uint64
+	lastCheck atomic.Int64
+}
+
+// clone returns a copy with fresh counters, so every cache checks independently.
+func (p *memoryPressure) clone() *memoryPressure {
+	if p == nil {
+		return nil
+	}
+
+	return &memoryPressure{threshold: p.threshold, fraction: p.fraction}
+}
+
+var pressureMetrics = []string{
+	"/gc/gomemlimit:bytes",
+	"/memory/classes/total:bytes",
+	"/memory/classes/heap/released:bytes",
+}
+
+// check reports whether memory usage exceeds the threshold fraction of the memory limit.
+// It is cheap to call on every write, as the runtime metrics are only read occasionally.
+func (p *memoryPressure) check() bool {
+	if p.writes.Add(1)%pressureCheckWrites != 0 {
+		return false
+	}
+
+	now := time.Now().UnixNano()
+
+	last := p.lastCheck.Load()
+	if now-last < int64(pressureCheckInterval) || !p.lastCheck.CompareAndSwap(last, now) {
+		return false
+	}
+
+	samples := make([]metrics.Sample, len(pressureMetrics))
+	for i, name := range pressureMetrics {
+		samples[i].Name = name
+	}
+
+	metrics.Read(samples)
+
+	for _, sample := range samples {
+		if sample.Value.Kind() != metrics.KindUint64 {
+			return false
+		}
+	}
+
+	limit := samples[0].Value.Uint64()
+	if limit == 0 || limit == math.MaxInt64 {
+		// No memory limit is set.
+		return false
+	}
+
+	// This matches the memory accounting of the runtime for its memory limit.
+	used := samples[1].Value.Uint64() - samples[2].Value.Uint64()
+
+	return float64(used) > p.threshold*float64(limit)
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// The high proberStepBits of a hash select one of proberSteps precomputed probe steps.
+const (
+	proberStepBits = 6
+	proberSteps    = 1 << proberStepBits
+)
+
+// Prober generates probe sequences for open-addressed tables of a fixed size.
+// It uses double hashing: the low bits of a hash select the first slot,
+// the high bits select a step which is coprime with the table size.
+// The first size probes of a sequence therefore visit every slot exactly once.
+//
+// A Prober is immutable and safe for concurrent use.
+type Prober struct {
+	size  uint64
+	steps [proberSteps]uint64
+}
+
+// NewProber returns a Prober for a table with size slots. The size must be positive.
+func NewProber(size int) Prober {
+	if size <= 0 {
+		panic("cache: prober size must be positive")
+	}
+
+	p := Prober{size: uint64(size)}
+
+	if size == 1 {
+		return p
+	}
+
+	// Spread the steps over the table, and round each one up to the nearest coprime step.
+	for k := range proberSteps {
+		step := 1 + uint64(k)*uint64(size-1)/proberSteps
+		for gcd(step, p.size) != 1 {
+			step = step%(p.size-1) + 1
+		}
+
+		p.steps[k] = step
+	}
+
+	return p
+}
+
+// Size returns the table size of the prober.
+func (p Prober) Size() int {
+	return int(p.size)
+}
+
+// Index returns the slot index of the i-th probe for hash.
+func (p Prober) Index(hash uint64, i int) int {
+	hi, lo := bits.Mul64(uint64(i), p.step(hash))
+
+	return int((hash%p.size + bits.Rem64(hi, lo, p.size)) % p.size)
+}
+
+// Sequence returns the probe sequence for hash, which yields every slot index exactly once.
+func (p Prober) Sequence(hash uint64) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		index, step := hash%p.size, p.step(hash)
+
+		for range p.size {
+			if !yield(int(index)) {
+				return
+			}
+
+			// Both index and step are smaller than size, so this cannot overflow.
+			index += step
+			if index >= p.size {
+				index -= p.size
+			}
+		}
+	}
+}
+
+func (p Prober) step(hash uint64) uint64 {
+	return p.steps[hash>>(64-proberStepBits)]
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+// ProbeStats counts cache operations by the probe index at which they found or claimed their slot.
+// Most hits and writes at index 0 mean the table is large enough for its working set;
+// a long tail towards the probe depth means probes are close to overflowing.
+type ProbeStats struct {
+	Hits   []uint64
+	Writes []uint64
+}
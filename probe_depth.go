@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"log/slog"
+	"time"
+	"weak"
+)
+
+const (
+	// probeDepthMinWrites is the number of writes of new keys in an interval, below which the probe depth
+	// is not tuned, as the ratios are not meaningful.
+	probeDepthMinWrites = 1000
+	// probeDepthGrowRatio is the ratio of writes which overflowed their probe sequence, above which the probe
+	// depth grows.
+	probeDepthGrowRatio = 0.01
+	// probeDepthShrinkRatio is the ratio of writes which claimed one of the deepest probes, below which the probe
+	// depth shrinks, if no write overflowed.
+	probeDepthShrinkRatio = 0.001
+)
+
+// probeDepthSample holds the write counters of the cache at the end of a tuning interval.
+type probeDepthSample struct {
+	depth     int
+	inserts   uint64
+	overflows uint64
+	deepest   uint64
+}
+
+// ProbeDepth returns the current hash probe depth, which changes over time with WithAdaptiveProbeDepth.
+func (c *LockFreeCache[K, V]) ProbeDepth() int {
+	return c.probeDepth()
+}
+
+func (c *LockFreeCache[K, V]) probeDepth() int {
+	return int(c.hashProbeDepth.Load())
+}
+
+// wholeGroups rounds depth up to whole groups, if group probing is enabled.
+func (c *LockFreeCache[K, V]) wholeGroups(depth int) int {
+	if c.groups == nil {
+		return depth
+	}
+
+	return (depth + groupSize - 1) / groupSize * groupSize
+}
+
+func (c *LockFreeCache[K, V]) startProbeDepthTuning(interval time.Duration, minDepth, maxDepth int) {
+	cache, stop, sample := weak.Make(c), c.life.stop, c.sampleProbeDepth()
+	c.life.goroutine(func() { probeDepthLoop(cache, interval, minDepth, maxDepth, sample, stop) })
+}
+
+// probeDepthLoop tunes the probe depth of the cache every interval. It only holds a weak reference between
+// intervals, so it does not keep the cache alive.
+func probeDepthLoop[K comparable, V any](
+	cache weak.Pointer[LockFreeCache[K, V]], interval time.Duration, minDepth, maxDepth int,
+	sample probeDepthSample, stop <-chan struct{},
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c := cache.Value()
+			if c == nil {
+				return
+			}
+
+			sample = c.tuneProbeDepth(sample, minDepth, maxDepth)
+		}
+	}
+}
+
+// sampleProbeDepth returns the current write counters.
+func (c *LockFreeCache[K, V]) sampleProbeDepth() probeDepthSample {
+	depth := c.probeDepth()
+	overflows := c.randomCASWrites.Load() + c.randomWrites.Load()
+
+	sample := probeDepthSample{
+		depth:     depth,
+		inserts:   c.emptyWrites.Load() + overflows,
+		overflows: overflows,
+	}
+
+	for i := depth - c.probeDepthStep(); i < depth; i++ {
+		sample.deepest += c.writeDepths[i].Load()
+	}
+
+	return sample
+}
+
+// probeDepthStep returns the amount by which the probe depth is tuned at once.
+func (c *LockFreeCache[K, V]) probeDepthStep() int {
+	if c.groups != nil {
+		return groupSize
+	}
+
+	return 1
+}
+
+// tuneProbeDepth grows or shrinks the probe depth by a step, based on the writes since the previous sample,
+// and returns the new sample.
+func (c *LockFreeCache[K, V]) tuneProbeDepth(previous probeDepthSample, minDepth, maxDepth int) probeDepthSample {
+	current := c.sampleProbeDepth()
+
+	// Skip intervals in which the metrics were reset, or the depth changed, as their deltas are meaningless.
+	if current.depth != previous.depth || current.inserts < previous.inserts ||
+		current.overflows < previous.overflows || current.deepest < previous.deepest {
+		return current
+	}
+
+	inserts := current.inserts - previous.inserts
+	if inserts < probeDepthMinWrites {
+		return previous
+	}
+
+	overflows := float64(current.overflows-previous.overflows) / float64(inserts)
+	deepest := float64(current.deepest-previous.deepest) / float64(inserts)
+	depth, step := current.depth, c.probeDepthStep()
+
+	switch {
+	case overflows > probeDepthGrowRatio && depth+step <= maxDepth:
+		c.hashProbeDepth.Store(int64(depth + step))
+	case overflows == 0 && deepest < probeDepthShrinkRatio && depth-step >= minDepth:
+		c.hashProbeDepth.Store(int64(depth - step))
+		c.trimProbes(depth, depth-step)
+	default:
+		return current
+	}
+
+	c.logger.Debug("tuned probe depth", slog.Int("from", depth), slog.Int("to", c.probeDepth()))
+
+	return c.sampleProbeDepth()
+}
+
+// trimProbes evicts the unpinned entries at probe positions from depth up to previousDepth, which Get no longer
+// visits once the probe depth shrank to depth. Writes which are in progress may still claim such positions.
+func (c *LockFreeCache[K, V]) trimProbes(previousDepth, depth int) {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	for index := range c.size {
+		entry := c.slot(index).Load()
+		if entry == nil || entry.keyHash == 0 || entry.pinned != nil {
+			continue
+		}
+
+		for i := depth; i < previousDepth; i++ {
+			if c.index(entry.keyHash, i) == index {
+				c.remove(entry, index, EvictionCapacity)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package cache_test
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheAdaptiveProbeDepth(t *testing.T) {
+	t.Parallel()
+
+	t.Run("grow", func(t *testing.T) {
+		t.Parallel()
+
+		testCache := cache.NewLockFreeCache(1024, cache.WithAdaptiveProbeDepth[string, uint64](10*time.Millisecond, 1, 20))
+		defer testCache.Close()
+
+		check.Equal(t, testCache.ProbeDepth(), 10)
+
+		values := make([]uint64, 1<<16)
+
+		// Writing far more keys than fit overflows the probe sequences, so the depth grows.
+		deadline := time.Now().Add(5 * time.Second)
+		for i := 0; testCache.ProbeDepth() <= 10 && time.Now().Before(deadline); i++ {
+			testCache.Put(strconv.Itoa(i), &values[i%len(values)])
+		}
+
+		check.True(t, testCache.ProbeDepth() > 10)
+		check.True(t, testCache.ProbeDepth() <= 20)
+
+		runtime.KeepAlive(values)
+	})
+
+	t.Run("shrink", func(t *testing.T) {
+		t.Parallel()
+
+		testCache := cache.NewLockFreeCache(1<<16, cache.WithAdaptiveProbeDepth[string, uint64](10*time.Millisecond, 4, 16))
+		defer testCache.Close()
+
+		check.Equal(t, testCache.ProbeDepth(), 16)
+
+		values := make([]uint64, 100)
+
+		// Few live keys in a big table rarely need the deepest probes, so the depth shrinks to the minimum.
+		deadline := time.Now().Add(5 * time.Second)
+		for i := 0; testCache.ProbeDepth() > 4 && time.Now().Before(deadline); i++ {
+			testCache.Put(strconv.Itoa(i), &values[i%len(values)])
+
+			if i >= len(values) {
+				testCache.Delete(strconv.Itoa(i - len(values)))
+			}
+		}
+
+		check.Equal(t, testCache.ProbeDepth(), 4)
+
+		// Keys written at the minimum depth are still found.
+		testCache.Put("key", &values[0])
+
+		value, ok := testCache.Get("key")
+		check.True(t, ok)
+		check.Equal(t, value, values[0])
+
+		runtime.KeepAlive(values)
+	})
+}
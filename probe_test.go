@@ -0,0 +1,72 @@
+package cache_test
+
+import (
+	mathrand "math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestProberSequence(t *testing.T) {
+	t.Parallel()
+
+	for size := 1; size <= 300; size++ {
+		prober := cache.NewProber(size)
+		check.Equal(t, prober.Size(), size)
+
+		for range 20 {
+			hash := mathrand.Uint64()
+			seen := make([]bool, size)
+			i := 0
+
+			for index := range prober.Sequence(hash) {
+				check.True(t, index >= 0 && index < size)
+				check.True(t, !seen[index])
+				check.Equal(t, prober.Index(hash, i), index)
+
+				seen[index] = true
+				i++
+			}
+
+			check.Equal(t, i, size)
+			check.True(t, !slices.Contains(seen, false))
+		}
+	}
+}
+
+func TestProberIndexLarge(t *testing.T) {
+	t.Parallel()
+
+	// Large probe numbers must not overflow.
+	prober := cache.NewProber(1<<40 + 7)
+
+	for range 1000 {
+		index := prober.Index(mathrand.Uint64(), mathrand.Int())
+		check.True(t, index >= 0 && index < prober.Size())
+	}
+}
+
+func TestProberDistribution(t *testing.T) {
+	t.Parallel()
+
+	const perSlot = 1000
+
+	for _, size := range []int{7, 97, 1024} {
+		prober := cache.NewProber(size)
+
+		for probe := range 3 {
+			counts := make([]int, size)
+
+			for range size * perSlot {
+				counts[prober.Index(mathrand.Uint64(), probe)]++
+			}
+
+			// Allow a deviation of about six standard deviations.
+			for _, count := range counts {
+				check.True(t, count > perSlot*8/10 && count < perSlot*12/10)
+			}
+		}
+	}
+}
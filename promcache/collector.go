@@ -0,0 +1,70 @@
+// Package promcache exports cache metrics to Prometheus.
+package promcache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samborkent/cache"
+)
+
+// Source is a cache whose metrics can be collected.
+type Source interface {
+	Metrics() cache.Metrics
+	Len() int
+	Cap() int
+}
+
+// Collector is a prometheus.Collector for a single cache instance.
+// Every metric carries a "cache" label with the name given to NewCollector.
+type Collector struct {
+	source Source
+
+	readHits, readMisses *prometheus.Desc
+	writes               *prometheus.Desc
+	entries, capacity    *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a collector for source. Register it with prometheus.Register.
+func NewCollector(name string, source Source) *Collector {
+	labels := prometheus.Labels{"cache": name}
+
+	return &Collector{
+		source:     source,
+		readHits:   prometheus.NewDesc("cache_read_hits_total", "Number of reads which found a live entry.", nil, labels),
+		readMisses: prometheus.NewDesc("cache_read_misses_total", "Number of reads which found no live entry.", nil, labels),
+		writes:     prometheus.NewDesc("cache_writes_total", "Number of writes by type.", []string{"type"}, labels),
+		entries:    prometheus.NewDesc("cache_entries", "Number of live entries.", nil, labels),
+		capacity:   prometheus.NewDesc("cache_capacity", "Maximum number of entries.", nil, labels),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.readHits
+	ch <- c.readMisses
+	ch <- c.writes
+	ch <- c.entries
+	ch <- c.capacity
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	metrics := c.source.Metrics()
+
+	ch <- prometheus.MustNewConstMetric(c.readHits, prometheus.CounterValue, float64(metrics.ReadHits))
+	ch <- prometheus.MustNewConstMetric(c.readMisses, prometheus.CounterValue, float64(metrics.ReadMisses))
+
+	for writeType, count := range map[string]uint64{
+		"first":      metrics.FirstWrites,
+		"probe":      metrics.ProbeWrites,
+		"empty":      metrics.EmptyWrites,
+		"random_cas": metrics.RandomCASWrites,
+		"random":     metrics.RandomWrites,
+	} {
+		ch <- prometheus.MustNewConstMetric(c.writes, prometheus.CounterValue, float64(count), writeType)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(c.source.Len()))
+	ch <- prometheus.MustNewConstMetric(c.capacity, prometheus.GaugeValue, float64(c.source.Cap()))
+}
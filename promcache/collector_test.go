@@ -0,0 +1,40 @@
+package promcache_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/promcache"
+	"github.com/samborkent/check"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](16)
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+
+	collector := promcache.NewCollector("test", testCache)
+
+	// Two read counters, five write types, entries and capacity.
+	check.Equal(t, testutil.CollectAndCount(collector), 9)
+
+	expected := `
+# HELP cache_capacity Maximum number of entries.
+# TYPE cache_capacity gauge
+cache_capacity{cache="test"} 16
+# HELP cache_entries Number of live entries.
+# TYPE cache_entries gauge
+cache_entries{cache="test"} 1
+`
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "cache_capacity", "cache_entries")
+	check.True(t, err == nil)
+
+	runtime.KeepAlive(&val)
+}
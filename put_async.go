@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"weak"
+)
+
+// QueuePolicy sets what PutAsync does when the queue set by WithAsyncPuts is full.
+type QueuePolicy int
+
+const (
+	// QueueBlock makes PutAsync wait until the queue has room.
+	QueueBlock QueuePolicy = iota
+	// QueueDropNew discards the new put.
+	QueueDropNew
+	// QueueDropOldest discards the oldest queued put, to make room for the new one.
+	QueueDropOldest
+)
+
+// asyncPutBatch is the maximum number of queued puts which the writer applies at once.
+const asyncPutBatch = 256
+
+// asyncPut is a queued put.
+type asyncPut[K comparable, V any] struct {
+	key   K
+	value *V
+}
+
+// PutAsync queues a put of value for key, which is applied by a single background writer, so concurrent
+// producers do not contend on the slots of the table. Until it is applied, the queue holds value strongly.
+// It reports whether the put was queued, which it is not if it was dropped by the QueuePolicy, or the cache
+// is closed. Without WithAsyncPuts, it puts synchronously.
+func (c *LockFreeCache[K, V]) PutAsync(key K, value *V) bool {
+	if !c.initialized.Load() || value == nil {
+		return false
+	}
+
+	if c.asyncPuts == nil {
+		c.Put(key, value)
+		return true
+	}
+
+	put := asyncPut[K, V]{key: key, value: value}
+
+	switch c.asyncPolicy {
+	case QueueDropNew:
+		select {
+		case c.asyncPuts <- put:
+			return true
+		default:
+		}
+	case QueueDropOldest:
+		for {
+			select {
+			case c.asyncPuts <- put:
+				return true
+			default:
+			}
+
+			select {
+			case <-c.asyncPuts:
+				c.droppedAsyncPuts.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case c.asyncPuts <- put:
+			return true
+		case <-c.life.stop:
+			return false
+		}
+	}
+
+	c.droppedAsyncPuts.Add(1)
+
+	return false
+}
+
+// Flush waits until all puts queued by PutAsync before the call are applied, and then syncs the attached
+// AppendLog to disk, so recent writes survive a crash. It returns the error of ctx once it is done, ErrClosed
+// once the cache is closed, and the error of syncing the AppendLog.
+func (c *LockFreeCache[K, V]) Flush(ctx context.Context) error {
+	if err := c.usable(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if c.asyncPuts != nil {
+		done := make(chan struct{})
+
+		select {
+		case c.asyncFlushes <- done:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.life.stop:
+			return ErrClosed
+		}
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if log := c.appendLog.Load(); log != nil {
+		return log.sync()
+	}
+
+	return nil
+}
+
+func (c *LockFreeCache[K, V]) startAsyncPuts(queueSize int, policy QueuePolicy) {
+	c.asyncPuts = make(chan asyncPut[K, V], queueSize)
+	c.asyncFlushes = make(chan chan struct{})
+	c.asyncPolicy = policy
+
+	cache, queue, flushes, stop := weak.Make(c), c.asyncPuts, c.asyncFlushes, c.life.stop
+	c.life.goroutine(func() { asyncPutLoop(cache, queue, flushes, stop) })
+}
+
+// asyncPutLoop applies queued puts in batches, and on flush all puts queued before it. It only holds a weak reference
+// to the cache while the queue is empty, so it does not keep the cache alive. Puts which are still queued when
+// the cache is closed are applied before it returns.
+func asyncPutLoop[K comparable, V any](cache weak.Pointer[LockFreeCache[K, V]], queue <-chan asyncPut[K, V], flushes <-chan chan struct{}, stop <-chan struct{}) {
+	batch := make([]asyncPut[K, V], 0, asyncPutBatch)
+
+	for {
+		select {
+		case done := <-flushes:
+			// Bound the flush by the current length of the queue, so concurrent puts cannot extend it.
+			for queued := len(queue); queued > 0; queued -= len(batch) {
+				batch = receiveAsyncPuts(queue, batch[:0], min(queued, cap(batch)))
+				if len(batch) == 0 {
+					break
+				}
+
+				if !applyAsyncPuts(cache, batch) {
+					return
+				}
+			}
+
+			batch = batch[:0]
+			close(done)
+		case <-stop:
+			batch = receiveAsyncPuts(queue, batch, cap(batch))
+			for len(batch) > 0 {
+				if !applyAsyncPuts(cache, batch) {
+					return
+				}
+
+				batch = receiveAsyncPuts(queue, batch[:0], cap(batch))
+			}
+
+			return
+		case put := <-queue:
+			batch = receiveAsyncPuts(queue, append(batch, put), cap(batch))
+
+			if !applyAsyncPuts(cache, batch) {
+				return
+			}
+
+			batch = batch[:0]
+		}
+	}
+}
+
+// receiveAsyncPuts appends the queued puts to batch, without waiting, until batch has size puts.
+func receiveAsyncPuts[K comparable, V any](queue <-chan asyncPut[K, V], batch []asyncPut[K, V], size int) []asyncPut[K, V] {
+	for len(batch) < size {
+		select {
+		case put := <-queue:
+			batch = append(batch, put)
+		default:
+			return batch
+		}
+	}
+
+	return batch
+}
+
+// applyAsyncPuts puts the batch, and clears it so it no longer holds the values. It reports false if the cache
+// was garbage collected.
+func applyAsyncPuts[K comparable, V any](cache weak.Pointer[LockFreeCache[K, V]], batch []asyncPut[K, V]) bool {
+	defer clear(batch)
+
+	c := cache.Value()
+	if c == nil {
+		return false
+	}
+
+	for _, put := range batch {
+		c.Put(put.key, put.value)
+	}
+
+	return true
+}
+
+// Flush is like LockFreeCache.Flush, for every shard.
+func (c *ShardedCache[K, V]) Flush(ctx context.Context) error {
+	for _, shard := range c.shards {
+		if err := shard.Flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PutAsync is like LockFreeCache.PutAsync. Every shard has its own queue and writer.
+func (c *ShardedCache[K, V]) PutAsync(key K, value *V) bool {
+	return c.shard(key).PutAsync(key, value)
+}
@@ -0,0 +1,106 @@
+package cache_test
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"errors"
+	mathrand "math/rand/v2"
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCachePutAsync(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []cache.QueuePolicy{cache.QueueBlock, cache.QueueDropNew, cache.QueueDropOldest} {
+		testCache := cache.NewLockFreeCache(N/100, cache.WithAsyncPuts[string, uint64](16, policy))
+
+		keys := make([]string, 1000)
+		values := make([]uint64, len(keys))
+
+		queued := 0
+
+		for i := range keys {
+			keys[i] = cryptorand.Text()
+			values[i] = mathrand.Uint64()
+
+			if testCache.PutAsync(keys[i], &values[i]) {
+				queued++
+			}
+		}
+
+		// The last put is never dropped, except by QueueDropNew.
+		last := len(keys) - 1
+		if policy != cache.QueueDropNew {
+			value, err := testCache.GetOrWait(t.Context(), keys[last])
+			check.True(t, err == nil)
+			check.Equal(t, value, values[last])
+		}
+
+		dropped := testCache.Metrics().DroppedAsyncPuts
+
+		switch policy {
+		case cache.QueueBlock:
+			check.Equal(t, queued, len(keys))
+			check.Equal(t, dropped, 0)
+		case cache.QueueDropNew:
+			check.Equal(t, uint64(queued)+dropped, uint64(len(keys)))
+		case cache.QueueDropOldest:
+			check.Equal(t, queued, len(keys))
+		}
+
+		runtime.KeepAlive(values)
+	}
+}
+
+func TestLockFreeCachePutAsyncSync(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+
+	// Without async puts, PutAsync puts synchronously.
+	check.True(t, testCache.PutAsync(key, &val))
+
+	value, ok := testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, val)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheFlush(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithAsyncPuts[string, uint64](1024, cache.QueueBlock))
+
+	keys := make([]string, 1000)
+	values := make([]uint64, len(keys))
+
+	for i := range keys {
+		keys[i] = cryptorand.Text()
+		values[i] = mathrand.Uint64()
+
+		check.True(t, testCache.PutAsync(keys[i], &values[i]))
+	}
+
+	check.True(t, testCache.Flush(t.Context()) == nil)
+
+	// All puts are applied, though some may have been evicted by later ones.
+	check.Equal(t, testCache.Metrics().TotalWrites(), uint64(len(keys)))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	check.True(t, errors.Is(testCache.Flush(ctx), context.Canceled))
+
+	check.True(t, testCache.Close() == nil)
+	check.True(t, errors.Is(testCache.Flush(t.Context()), cache.ErrClosed))
+
+	runtime.KeepAlive(values)
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// KeyValue is a key and its value, for PutBatch.
+type KeyValue[K comparable, V any] struct {
+	Key   K
+	Value *V
+}
+
+// batchPut is an entry of PutBatch, with the first slot index of its probe sequence.
+type batchPut[K comparable, V any] struct {
+	home  int
+	entry *cacheEntry[K, V]
+}
+
+// PutBatch stores multiple entries, such as when ingesting the results of a loader. The writes are applied in the
+// order of their slot index, rather than in random order, so neighbouring writes share cache lines and probe
+// sequences are rarely contended. Writes to the same key are applied in order, so the last one wins.
+func (c *LockFreeCache[K, V]) PutBatch(entries []KeyValue[K, V]) {
+	if !c.initialized.Load() || len(entries) == 0 {
+		return
+	}
+
+	if c.putLatency != nil {
+		defer c.putLatency.record(time.Now())
+	}
+
+	written := time.Now().UnixNano()
+
+	batch := make([]batchPut[K, V], len(entries))
+	for i, kv := range entries {
+		entry := c.newEntry(kv.Key, kv.Value, written)
+		batch[i] = batchPut[K, V]{home: c.index(entry.keyHash, 0), entry: entry}
+	}
+
+	slices.SortStableFunc(batch, func(a, b batchPut[K, V]) int {
+		return cmp.Compare(a.home, b.home)
+	})
+
+	for _, put := range batch {
+		c.admit(put.entry)
+		c.publish(put.entry.key)
+	}
+}
+
+// PutBatch is like LockFreeCache.PutBatch. The entries are split by shard, and every shard applies its own
+// entries in order of their slot index.
+func (c *ShardedCache[K, V]) PutBatch(entries []KeyValue[K, V]) {
+	if len(c.shards) == 1 {
+		c.shards[0].PutBatch(entries)
+		return
+	}
+
+	batches := make(map[*LockFreeCache[K, V]][]KeyValue[K, V], len(c.shards))
+	for _, kv := range entries {
+		shard := c.shard(kv.Key)
+		batches[shard] = append(batches[shard], kv)
+	}
+
+	for shard, batch := range batches {
+		shard.PutBatch(batch)
+	}
+}
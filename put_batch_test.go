@@ -0,0 +1,71 @@
+package cache_test
+
+import (
+	cryptorand "crypto/rand"
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCachePutBatch(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 10)
+
+	values := make([]uint64, 1000)
+	entries := make([]cache.KeyValue[string, uint64], len(values))
+
+	for i := range entries {
+		values[i] = uint64(i)
+		entries[i] = cache.KeyValue[string, uint64]{Key: cryptorand.Text(), Value: &values[i]}
+	}
+
+	// The last write to a key wins.
+	last := uint64(len(values))
+	entries = append(entries, cache.KeyValue[string, uint64]{Key: entries[0].Key, Value: &last})
+
+	testCache.PutBatch(entries)
+
+	check.Equal(t, testCache.Len(), len(values))
+
+	value, ok := testCache.Get(entries[0].Key)
+	check.True(t, ok)
+	check.Equal(t, value, last)
+
+	for i, entry := range entries[1:len(values)] {
+		value, ok := testCache.Get(entry.Key)
+		check.True(t, ok)
+		check.Equal(t, value, values[i+1])
+	}
+
+	runtime.KeepAlive(values)
+	runtime.KeepAlive(&last)
+}
+
+func TestShardedCachePutBatch(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewShardedCache[string, uint64](4, N/10)
+
+	values := make([]uint64, 1000)
+	entries := make([]cache.KeyValue[string, uint64], len(values))
+
+	for i := range entries {
+		values[i] = uint64(i)
+		entries[i] = cache.KeyValue[string, uint64]{Key: cryptorand.Text(), Value: &values[i]}
+	}
+
+	testCache.PutBatch(entries)
+
+	check.Equal(t, testCache.Len(), len(values))
+
+	for i, entry := range entries {
+		value, ok := testCache.Get(entry.Key)
+		check.True(t, ok)
+		check.Equal(t, value, values[i])
+	}
+
+	runtime.KeepAlive(values)
+}
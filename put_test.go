@@ -0,0 +1,63 @@
+package cache_test
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCachePutProbeWindow(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](64)
+
+	values := make([]uint64, 1000)
+	for i := range values {
+		testCache.Put(strconv.Itoa(i), &values[i])
+	}
+
+	// Every live entry is found by Get, so no put stored its entry outside the probe window of its key.
+	found := 0
+
+	for i := range values {
+		if _, ok := testCache.Get(strconv.Itoa(i)); ok {
+			found++
+		}
+	}
+
+	check.Equal(t, found, testCache.Len())
+
+	runtime.KeepAlive(values)
+}
+
+func TestLockFreeCachePutDuplicates(t *testing.T) {
+	t.Parallel()
+
+	for range 100 {
+		testCache := cache.NewLockFreeCache[string, uint64](64)
+
+		values := make([]uint64, 8)
+
+		var wg sync.WaitGroup
+
+		for i := range values {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				testCache.Put("key", &values[i])
+			}()
+		}
+
+		wg.Wait()
+
+		// Concurrent puts of a new key may claim different slots, but only one entry remains.
+		check.Equal(t, testCache.Len(), 1)
+
+		runtime.KeepAlive(values)
+	}
+}
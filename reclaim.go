@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// reclaimEpochs is the number of epochs with their own reader counts and retired entries.
+	// Entries retired in an epoch are recycled two epochs later, so three are in use at any time.
+	reclaimEpochs = 3
+	// reclaimBatch is the number of retired entries of the current epoch, from which the epoch is advanced.
+	reclaimBatch = 64
+)
+
+// reclaimer recycles removed entries with epoch-based reclamation, so an entry is only reused once no reader
+// which may have loaded it from a slot remains. Readers enter the current epoch before loading slots,
+// and exit once they no longer use the loaded entries. Entries which are retired in an epoch are recycled
+// once the epoch was advanced twice, which requires all readers of the epoch before to have exited.
+type reclaimer[K comparable, V any] struct {
+	epoch atomic.Uint64
+	// readers counts the readers of every epoch, striped to avoid contention.
+	readers [reclaimEpochs][]paddedCounter
+	mask    uint32
+
+	lock sync.Mutex
+	// retired holds the entries which were retired in every epoch, until they are recycled.
+	retired [reclaimEpochs][]*cacheEntry[K, V]
+	recycle func(*cacheEntry[K, V])
+}
+
+func newReclaimer[K comparable, V any](recycle func(*cacheEntry[K, V])) *reclaimer[K, V] {
+	stripes := stripeCount()
+
+	r := &reclaimer[K, V]{
+		mask:    uint32(stripes - 1),
+		recycle: recycle,
+	}
+
+	for epoch := range r.readers {
+		r.readers[epoch] = make([]paddedCounter, stripes)
+	}
+
+	// Start beyond the first epochs, so the epoch before the current one never underflows.
+	r.epoch.Store(reclaimEpochs)
+
+	return r
+}
+
+// enter registers a reader in the current epoch, and returns the counter to exit with.
+// Entries loaded from slots may only be used until exit.
+func (r *reclaimer[K, V]) enter() *atomic.Uint64 {
+	if r == nil {
+		return nil
+	}
+
+	stripe := rand.Uint32() & r.mask
+
+	for {
+		epoch := r.epoch.Load()
+
+		counter := &r.readers[epoch%reclaimEpochs][stripe].count
+		counter.Add(1)
+
+		// The epoch may have advanced before the reader was counted, and then the epoch before it may no longer
+		// be waited for.
+		if r.epoch.Load() == epoch {
+			return counter
+		}
+
+		counter.Add(^uint64(0))
+	}
+}
+
+// exit unregisters a reader which entered with counter.
+func (r *reclaimer[K, V]) exit(counter *atomic.Uint64) {
+	if counter != nil {
+		counter.Add(^uint64(0))
+	}
+}
+
+// retire recycles entry, once all readers which may still use it exited.
+func (r *reclaimer[K, V]) retire(entry *cacheEntry[K, V]) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	epoch := r.epoch.Load()
+	index := epoch % reclaimEpochs
+
+	r.retired[index] = append(r.retired[index], entry)
+
+	if len(r.retired[index]) >= reclaimBatch {
+		r.advance(epoch)
+	}
+}
+
+// advance moves to the next epoch if all readers of the previous epoch exited, and recycles the entries which
+// were retired in it, as no reader can use them anymore. Readers of the current epoch may still use the entries
+// retired in it, so they are recycled once the epoch advances again.
+// It must be called with the lock held.
+func (r *reclaimer[K, V]) advance(epoch uint64) {
+	previous := (epoch - 1) % reclaimEpochs
+
+	var readers uint64
+	for i := range r.readers[previous] {
+		readers += r.readers[previous][i].count.Load()
+	}
+
+	if readers != 0 {
+		return
+	}
+
+	r.epoch.Store(epoch + 1)
+
+	for i, entry := range r.retired[previous] {
+		r.recycle(entry)
+		r.retired[previous][i] = nil
+	}
+
+	r.retired[previous] = r.retired[previous][:0]
+}
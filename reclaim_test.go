@@ -0,0 +1,64 @@
+package cache_test
+
+import (
+	mathrand "math/rand/v2"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheReclamation(t *testing.T) {
+	t.Parallel()
+
+	const (
+		keys       = 64
+		workers    = 8
+		operations = 10_000
+	)
+
+	testCache := cache.NewLockFreeCache[int, string](keys * 2)
+
+	values := make([]string, keys)
+	for key := range values {
+		values[key] = strconv.Itoa(key)
+	}
+
+	// Removed entries are recycled while other goroutines read, so a reader must never see an entry
+	// which was reused for another key.
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range operations {
+				key := mathrand.IntN(keys)
+
+				switch mathrand.IntN(3) {
+				case 0:
+					testCache.Put(key, &values[key])
+				case 1:
+					testCache.Delete(key)
+				default:
+					if value, ok := testCache.Get(key); ok {
+						check.Equal(t, value, values[key])
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for key, value := range testCache.All() {
+		check.Equal(t, value, values[key])
+	}
+
+	runtime.KeepAlive(values)
+}
@@ -0,0 +1,95 @@
+// Package rediscache provides a cache.WriteStore backed by Redis, without depending on a specific client library.
+package rediscache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samborkent/cache"
+)
+
+// Client is the subset of a Redis client used by Store. Most client libraries can be adapted in a few lines,
+// for example for go-redis:
+//
+//	func (c goRedis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+//		value, err := c.Client.Get(ctx, key).Bytes()
+//		if errors.Is(err, redis.Nil) {
+//			return nil, false, nil
+//		}
+//		return value, err == nil, err
+//	}
+type Client interface {
+	// Get returns the value of key, and whether it exists.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set sets the value of key, which expires after ttl, or never if ttl is 0.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Del deletes key.
+	Del(ctx context.Context, key string) error
+}
+
+// Store is a cache.WriteStore which stores values encoded by a codec in Redis.
+// Keys are formatted with fmt.Sprint, and prefixed to share a Redis database between caches.
+type Store[K comparable, V any] struct {
+	client Client
+	codec  cache.Codec[V]
+	prefix string
+	ttl    time.Duration
+}
+
+var _ cache.WriteStore[string, any] = (*Store[string, any])(nil)
+
+// NewStore returns a store of values encoded by codec in client, under keys with prefix, which expire after ttl,
+// or never if ttl is 0.
+func NewStore[K comparable, V any](client Client, codec cache.Codec[V], prefix string, ttl time.Duration) *Store[K, V] {
+	return &Store[K, V]{
+		client: client,
+		codec:  codec,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Load returns the value for key, or cache.ErrNotFound if it does not exist.
+func (s *Store[K, V]) Load(ctx context.Context, key K) (*V, error) {
+	data, ok, err := s.client.Get(ctx, s.key(key))
+	if err != nil {
+		return nil, fmt.Errorf("rediscache: get: %w", err)
+	}
+
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+
+	value, err := s.codec.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("rediscache: decode: %w", err)
+	}
+
+	return value, nil
+}
+
+func (s *Store[K, V]) Save(ctx context.Context, key K, value *V) error {
+	data, err := s.codec.Encode(value)
+	if err != nil {
+		return fmt.Errorf("rediscache: encode: %w", err)
+	}
+
+	if err := s.client.Set(ctx, s.key(key), data, s.ttl); err != nil {
+		return fmt.Errorf("rediscache: set: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store[K, V]) Delete(ctx context.Context, key K) error {
+	if err := s.client.Del(ctx, s.key(key)); err != nil {
+		return fmt.Errorf("rediscache: del: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store[K, V]) key(key K) string {
+	return s.prefix + fmt.Sprint(key)
+}
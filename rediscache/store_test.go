@@ -0,0 +1,72 @@
+package rediscache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/rediscache"
+	"github.com/samborkent/check"
+)
+
+// fakeRedis is an in-memory Client.
+type fakeRedis struct {
+	lock   sync.Mutex
+	values map[string][]byte
+}
+
+func (r *fakeRedis) Get(_ context.Context, key string) ([]byte, bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	value, ok := r.values[key]
+
+	return value, ok, nil
+}
+
+func (r *fakeRedis) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.values[key] = value
+
+	return nil
+}
+
+func (r *fakeRedis) Del(_ context.Context, key string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.values, key)
+
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	redis := &fakeRedis{values: make(map[string][]byte)}
+	store := rediscache.NewStore[string](redis, cache.JSONCodec[uint64]{}, "test:", 0)
+
+	_, err := store.Load(context.Background(), "key")
+	check.True(t, errors.Is(err, cache.ErrNotFound))
+
+	val := uint64(1)
+	check.True(t, store.Save(context.Background(), "key", &val) == nil)
+	check.Equal(t, string(redis.values["test:key"]), "1")
+
+	// Redis is the shared L2 of a tiered cache.
+	tiered := cache.NewTieredCache(cache.NewLockFreeCache[string, uint64](16), cache.NewStoreTier(store, time.Second))
+
+	value, ok := tiered.Get("key")
+	check.True(t, ok)
+	check.Equal(t, value, 1)
+
+	tiered.Delete("key")
+
+	_, ok = redis.values["test:key"]
+	check.True(t, !ok)
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+	"weak"
+)
+
+type refreshAheadConfig[K comparable, V any] struct {
+	interval time.Duration
+	keys     int
+	load     func(ctx context.Context, key K) (*V, error)
+}
+
+// refreshAhead keeps the hottest keys of a cache warm.
+type refreshAhead[K comparable, V any] struct {
+	refreshAheadConfig[K, V]
+
+	// heat counts the hits per key, halved on every refresh so it follows recent traffic.
+	heat *CounterCache[K]
+	// warm holds strong references to the values of the hottest keys, so they are not reclaimed.
+	warm atomic.Pointer[[]*V]
+}
+
+type hotKey[K comparable] struct {
+	key  K
+	hits int64
+}
+
+// startRefreshAhead starts the refresh worker, which stops once the cache is closed or garbage collected.
+func (c *LockFreeCache[K, V]) startRefreshAhead(cfg *refreshAheadConfig[K, V]) {
+	c.refreshAhead = &refreshAhead[K, V]{
+		refreshAheadConfig: *cfg,
+		heat:               NewCounterCache[K](c.size),
+	}
+
+	cache, interval, stop := weak.Make(c), cfg.interval, c.life.stop
+	c.life.goroutine(func() { refreshLoop(cache, interval, stop) })
+}
+
+// refreshLoop refreshes the cache every interval. It only holds a weak reference between refreshes,
+// so it does not keep the cache alive.
+func refreshLoop[K comparable, V any](cache weak.Pointer[LockFreeCache[K, V]], interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c := cache.Value()
+			if c == nil {
+				return
+			}
+
+			c.refreshHot()
+		}
+	}
+}
+
+// refreshHot reloads the hottest keys which expire before the next refresh or were reclaimed,
+// and keeps strong references to the values of all hottest keys until the next refresh.
+func (c *LockFreeCache[K, V]) refreshHot() {
+	r := c.refreshAhead
+	hot := make([]hotKey[K], 0, r.keys)
+
+	// Keep the hottest keys sorted by descending hits.
+	counter := c.reclaim.enter()
+
+	for index := range c.size {
+		entry := c.slot(index).Load()
+		if entry == nil || entry.keyHash == 0 {
+			continue
+		}
+
+		hits, ok := r.heat.Load(entry.key)
+		if !ok || hits == 0 {
+			continue
+		}
+
+		r.heat.Store(entry.key, hits/2)
+
+		if len(hot) == r.keys && hits <= hot[len(hot)-1].hits {
+			continue
+		}
+
+		if len(hot) < r.keys {
+			hot = append(hot, hotKey[K]{})
+		}
+
+		i := len(hot) - 1
+		for ; i > 0 && hot[i-1].hits < hits; i-- {
+			hot[i] = hot[i-1]
+		}
+
+		hot[i] = hotKey[K]{key: entry.key, hits: hits}
+	}
+
+	c.reclaim.exit(counter)
+
+	deadline := time.Now().Add(r.interval).UnixNano()
+	warm := make([]*V, 0, len(hot))
+
+	for _, h := range hot {
+		// Loads may take long, so do not hold up the reclamation of entries while loading.
+		counter := c.reclaim.enter()
+		_, entry, value := c.find(h.key)
+		fresh := value != nil && (entry.expires == 0 || entry.expires > deadline)
+		c.reclaim.exit(counter)
+
+		if fresh {
+			warm = append(warm, value)
+			continue
+		}
+
+		call, started := c.start(h.key)
+		if !started {
+			continue
+		}
+
+		c.run(context.Background(), h.key, r.load, call)
+
+		if call.err != nil {
+			c.logger.Debug("refresh ahead failed", slog.Any("key", h.key), slog.Any("error", call.err))
+			continue
+		}
+
+		if call.value != nil {
+			warm = append(warm, call.value)
+		}
+	}
+
+	r.warm.Store(&warm)
+}
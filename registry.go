@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ErrAlreadyRegistered is returned when registering a cache under a name which is already taken.
+var ErrAlreadyRegistered = errors.New("cache: already registered")
+
+// Registered is a cache which can be registered in a Registry. It is implemented by all caches of this package.
+type Registered interface {
+	Metrics() Metrics
+	Len() int
+	Cap() int
+}
+
+// Registry holds caches by name, so metrics exporters and debug handlers can enumerate all caches of a process.
+type Registry struct {
+	lock   sync.RWMutex
+	caches map[string]Registered
+}
+
+// DefaultRegistry is the registry used by Register, Unregister and Lookup.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{caches: make(map[string]Registered)}
+}
+
+// Register registers c under name, or returns ErrAlreadyRegistered if the name is taken.
+func (r *Registry) Register(name string, c Registered) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.caches[name]; ok {
+		return fmt.Errorf("%w: %q", ErrAlreadyRegistered, name)
+	}
+
+	r.caches[name] = c
+
+	return nil
+}
+
+// Unregister removes the cache registered under name.
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.caches, name)
+}
+
+// Lookup returns the cache registered under name.
+func (r *Registry) Lookup(name string) (Registered, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	c, ok := r.caches[name]
+
+	return c, ok
+}
+
+// Names returns the names of all registered caches, sorted.
+func (r *Registry) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return slices.Sorted(maps.Keys(r.caches))
+}
+
+// Caches returns a copy of all registered caches by name.
+func (r *Registry) Caches() map[string]Registered {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return maps.Clone(r.caches)
+}
+
+// Metrics returns the sum of the metrics of all registered caches.
+func (r *Registry) Metrics() Metrics {
+	var metrics Metrics
+
+	for _, c := range r.Caches() {
+		metrics = metrics.add(c.Metrics())
+	}
+
+	return metrics
+}
+
+// Register registers c under name in DefaultRegistry.
+func Register(name string, c Registered) error {
+	return DefaultRegistry.Register(name, c)
+}
+
+// Unregister removes the cache registered under name from DefaultRegistry.
+func Unregister(name string) {
+	DefaultRegistry.Unregister(name)
+}
+
+// Lookup returns the cache registered under name in DefaultRegistry.
+func Lookup(name string) (Registered, bool) {
+	return DefaultRegistry.Lookup(name)
+}
@@ -0,0 +1,49 @@
+package cache_test
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := cache.NewRegistry()
+
+	sessions := cache.NewLockFreeCache[string, uint64](N / 100)
+	bytes := cache.NewByteCache(1 << 16)
+
+	check.True(t, registry.Register("sessions", sessions) == nil)
+	check.True(t, registry.Register("bytes", bytes) == nil)
+	check.True(t, errors.Is(registry.Register("sessions", bytes), cache.ErrAlreadyRegistered))
+
+	names := registry.Names()
+	check.Equal(t, len(names), 2)
+	check.Equal(t, names[0], "bytes")
+	check.Equal(t, names[1], "sessions")
+
+	found, ok := registry.Lookup("sessions")
+	check.True(t, ok)
+	check.Equal(t, found.Cap(), sessions.Cap())
+
+	val := uint64(1)
+	sessions.Put("key", &val)
+	_, _ = sessions.Get("key")
+	_, _ = bytes.Get("key")
+
+	metrics := registry.Metrics()
+	check.Equal(t, metrics.ReadHits, 1)
+	check.Equal(t, metrics.ReadMisses, 1)
+
+	registry.Unregister("bytes")
+
+	_, ok = registry.Lookup("bytes")
+	check.True(t, !ok)
+	check.Equal(t, len(registry.Caches()), 1)
+
+	runtime.KeepAlive(&val)
+}
@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultReplicationBuffer is the default number of records a Replicator buffers for its transports.
+const DefaultReplicationBuffer = 1024
+
+// ReplicaTransport carries replication records from a leader cache to a follower, for example over a TCP
+// connection or a message queue. At the follower, every record must be passed to Follower.Apply,
+// in the order it was sent.
+type ReplicaTransport interface {
+	Send(record []byte) error
+}
+
+// Replicator streams the puts and deletes of a leader cache to followers, such as warm standby processes
+// which must take over with a hot cache. Records are encoded like the entries of snapshots, and sent
+// in the background, so writes do not wait for the transports. Evictions are not replicated.
+//
+// Only writes after Attach are replicated, so a follower which joins later should first Load a snapshot.
+type Replicator[K comparable, V any] struct {
+	keys       Codec[K]
+	values     Codec[V]
+	transports []ReplicaTransport
+
+	lock    sync.RWMutex
+	records chan []byte
+	closed  bool
+
+	dropped atomic.Uint64
+
+	errLock sync.Mutex
+	// err is the first error of encoding or sending a record.
+	err error
+
+	done chan struct{}
+}
+
+// NewReplicator returns a replicator which sends records encoded by the codecs to all transports.
+// Up to buffer records are queued, or DefaultReplicationBuffer if it is not positive, after which
+// records are dropped until the transports catch up.
+func NewReplicator[K comparable, V any](keys Codec[K], values Codec[V], buffer int, transports ...ReplicaTransport) *Replicator[K, V] {
+	if buffer <= 0 {
+		buffer = DefaultReplicationBuffer
+	}
+
+	r := &Replicator[K, V]{
+		keys:       keys,
+		values:     values,
+		transports: transports,
+		records:    make(chan []byte, buffer),
+		done:       make(chan struct{}),
+	}
+
+	go r.sendLoop()
+
+	return r
+}
+
+// Attach replicates all later puts and deletes of c.
+func (r *Replicator[K, V]) Attach(c *LockFreeCache[K, V]) error {
+	if err := c.usable(); err != nil {
+		return err
+	}
+
+	c.replicator.Store(r)
+
+	return nil
+}
+
+// put queues a put record of entry.
+func (r *Replicator[K, V]) put(entry *cacheEntry[K, V]) {
+	record, ok, err := appendEncodedEntry(nil, entry, r.keys, r.values)
+	if ok || err != nil {
+		r.queue(record, err)
+	}
+}
+
+// delete queues a delete record of key.
+func (r *Replicator[K, V]) delete(key K) {
+	r.queue(appendDeleteRecord(nil, key, r.keys))
+}
+
+// queue queues record without blocking, or records err if encoding the record failed.
+func (r *Replicator[K, V]) queue(record []byte, err error) {
+	if err != nil {
+		r.fail(err)
+		return
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.records <- record:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+func (r *Replicator[K, V]) sendLoop() {
+	defer close(r.done)
+
+	for record := range r.records {
+		for _, transport := range r.transports {
+			if err := transport.Send(record); err != nil {
+				r.fail(fmt.Errorf("cache: replicate: %w", err))
+			}
+		}
+	}
+}
+
+func (r *Replicator[K, V]) fail(err error) {
+	r.errLock.Lock()
+	defer r.errLock.Unlock()
+
+	r.err = firstError(r.err, err)
+}
+
+// Dropped returns the number of records which were dropped because the buffer was full.
+// Followers miss the dropped writes, so they should be given a fresh snapshot.
+func (r *Replicator[K, V]) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Err returns the first error of encoding or sending a record.
+func (r *Replicator[K, V]) Err() error {
+	r.errLock.Lock()
+	defer r.errLock.Unlock()
+
+	return r.err
+}
+
+// Close sends the queued records, and stops replicating. Later writes of the attached cache are not replicated.
+// It returns the first error of encoding or sending a record, if any.
+func (r *Replicator[K, V]) Close() error {
+	r.lock.Lock()
+
+	if !r.closed {
+		r.closed = true
+		close(r.records)
+	}
+
+	r.lock.Unlock()
+
+	<-r.done
+
+	return r.Err()
+}
+
+// Follower applies the records of a Replicator to a follower cache.
+//
+// The values of the leader are referenced by its application, but nothing references them at the follower.
+// So pinned entries are pinned again, and the follower holds strong references to the values of the most recent
+// unpinned records, up to the size of the cache, until Release is called.
+type Follower[K comparable, V any] struct {
+	cache  *LockFreeCache[K, V]
+	keys   Codec[K]
+	values Codec[V]
+
+	lock sync.Mutex
+	// retained holds the values of the most recent records, in a ring starting at next.
+	retained []*V
+	next     int
+}
+
+// NewFollower returns a follower which applies records decoded by the codecs to c.
+func NewFollower[K comparable, V any](c *LockFreeCache[K, V], keys Codec[K], values Codec[V]) *Follower[K, V] {
+	return &Follower[K, V]{
+		cache:    c,
+		keys:     keys,
+		values:   values,
+		retained: make([]*V, c.size),
+	}
+}
+
+// Apply applies a record sent by a Replicator. Records of entries which expired since are skipped.
+// A corrupt record returns ErrInvalidSnapshot.
+func (f *Follower[K, V]) Apply(record []byte) error {
+	if err := f.cache.usable(); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(bytes.NewReader(record))
+
+	tag, err := br.ReadByte()
+	if err != nil {
+		return fmt.Errorf("%w: empty record", ErrInvalidSnapshot)
+	}
+
+	switch tag {
+	case snapshotEntry:
+		entry, value, err := f.cache.readEntry(br, f.keys, f.values)
+		if err != nil || entry == nil {
+			return err
+		}
+
+		if entry.pinned == nil {
+			f.retain(value)
+		}
+
+		f.cache.put(entry)
+	case appendLogDelete:
+		err = f.cache.loadDelete(br, f.keys)
+	default:
+		err = fmt.Errorf("%w: unknown record %d", ErrInvalidSnapshot, tag)
+	}
+
+	if err == nil && br.Buffered() > 0 {
+		err = fmt.Errorf("%w: trailing data", ErrInvalidSnapshot)
+	}
+
+	return err
+}
+
+func (f *Follower[K, V]) retain(value *V) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if len(f.retained) == 0 {
+		return
+	}
+
+	f.retained[f.next] = value
+	f.next = (f.next + 1) % len(f.retained)
+}
+
+// Release drops the strong references to unpinned values, for example once the follower took over
+// and its application references the values it uses.
+func (f *Follower[K, V]) Release() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	clear(f.retained)
+}
@@ -0,0 +1,90 @@
+package cache_test
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+// channelTransport delivers records to a follower through a channel.
+type channelTransport struct {
+	records chan []byte
+}
+
+func (t channelTransport) Send(record []byte) error {
+	t.records <- record
+	return nil
+}
+
+func TestReplication(t *testing.T) {
+	// Not parallel, as it relies on garbage collection.
+	keys := cache.JSONCodec[string]{}
+	values := cache.JSONCodec[string]{}
+
+	leader := cache.NewLockFreeCache[string, string](N / 100)
+	follower := cache.NewLockFreeCache[string, string](N / 100)
+
+	transport := channelTransport{records: make(chan []byte, 16)}
+	replicator := cache.NewReplicator(keys, values, 0, transport)
+	check.True(t, replicator.Attach(leader) == nil)
+
+	f := cache.NewFollower(follower, keys, values)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for record := range transport.records {
+			check.True(t, f.Apply(record) == nil)
+		}
+	}()
+
+	val1, val2 := "value1", "value2"
+	leader.Put("key1", &val1)
+	leader.Put("key2", &val2)
+	check.True(t, leader.Pin("key2"))
+	leader.Put("key3", &val1)
+	leader.Delete("key3")
+
+	check.True(t, replicator.Close() == nil)
+	close(transport.records)
+	wg.Wait()
+
+	check.Equal(t, replicator.Dropped(), 0)
+
+	// The follower retains unpinned values, and pins pinned values.
+	runtime.GC()
+
+	value, ok := follower.Get("key1")
+	check.True(t, ok)
+	check.Equal(t, value, val1)
+
+	value, ok = follower.Get("key2")
+	check.True(t, ok)
+	check.Equal(t, value, val2)
+
+	_, ok = follower.Get("key3")
+	check.True(t, !ok)
+
+	// Once released, only pinned values survive garbage collection.
+	f.Release()
+	runtime.GC()
+	time.Sleep(10 * time.Millisecond)
+
+	_, ok = follower.Get("key1")
+	check.True(t, !ok)
+
+	_, ok = follower.Get("key2")
+	check.True(t, ok)
+
+	err := f.Apply([]byte{9})
+	check.True(t, errors.Is(err, cache.ErrInvalidSnapshot))
+}
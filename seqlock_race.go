@@ -0,0 +1,6 @@
+//go:build race
+
+package cache
+
+// raceEnabled makes seqlock readers lock their slot, as the race detector reports the optimistic reads.
+const raceEnabled = true
@@ -0,0 +1,103 @@
+// Package server serves a cache over HTTP, so processes in other languages can share it.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/samborkent/cache"
+)
+
+// maxValueSize is the maximum size in bytes of a stored value.
+const maxValueSize = 1 << 20
+
+// Handler serves a cache of JSON values over HTTP:
+//
+//	GET /{key}     returns the value of key, or 404 Not Found
+//	PUT /{key}     stores the JSON request body as the value of key, and returns 204 No Content
+//	DELETE /{key}  removes the value of key, and returns 204 No Content
+//
+// The cache should be created with cache.WithOwnedValues, so it holds the stored values until they are deleted,
+// overwritten, evicted, or expire if the cache has a TTL, instead of leaving them to the garbage collector.
+// Errors are returned as a JSON object with an "error" field.
+type Handler struct {
+	cache *cache.LockFreeCache[string, json.RawMessage]
+	mux   *http.ServeMux
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+// NewHandler returns a handler serving c, which should be created with cache.WithOwnedValues.
+func NewHandler(c *cache.LockFreeCache[string, json.RawMessage]) *Handler {
+	h := &Handler{
+		cache: c,
+		mux:   http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /{key...}", h.get)
+	h.mux.HandleFunc("PUT /{key...}", h.put)
+	h.mux.HandleFunc("DELETE /{key...}", h.delete)
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	value, ok := h.cache.Get(r.PathValue("key"))
+	if !ok {
+		writeError(w, http.StatusNotFound, cache.ErrNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(value)
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, errors.New("empty key"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValueSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if !json.Valid(body) {
+		writeError(w, http.StatusBadRequest, errors.New("value is not valid JSON"))
+		return
+	}
+
+	value := json.RawMessage(body)
+	h.cache.Put(key, &value)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	h.cache.Delete(r.PathValue("key"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+}
@@ -0,0 +1,69 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/cache/server"
+	"github.com/samborkent/check"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(server.NewHandler(cache.NewLockFreeCache(16, cache.WithOwnedValues[string, json.RawMessage]())))
+	defer srv.Close()
+
+	do := func(method, key, body string) (int, string) {
+		req, err := http.NewRequest(method, srv.URL+"/"+key, strings.NewReader(body))
+		check.True(t, err == nil)
+
+		resp, err := http.DefaultClient.Do(req)
+		check.True(t, err == nil)
+
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		check.True(t, err == nil)
+
+		return resp.StatusCode, string(respBody)
+	}
+
+	status, _ := do(http.MethodGet, "key", "")
+	check.Equal(t, status, http.StatusNotFound)
+
+	status, _ = do(http.MethodPut, "key", "{")
+	check.Equal(t, status, http.StatusBadRequest)
+
+	status, _ = do(http.MethodPut, "key", `{"value":1}`)
+	check.Equal(t, status, http.StatusNoContent)
+
+	// The cache owns the stored values, so they survive garbage collection.
+	runtime.GC()
+
+	status, body := do(http.MethodGet, "key", "")
+	check.Equal(t, status, http.StatusOK)
+	check.Equal(t, body, `{"value":1}`)
+
+	status, _ = do(http.MethodDelete, "key", "")
+	check.Equal(t, status, http.StatusNoContent)
+
+	status, _ = do(http.MethodGet, "key", "")
+	check.Equal(t, status, http.StatusNotFound)
+
+	// Stored values are evicted like other entries, so writes beyond the capacity succeed.
+	for i := range 64 {
+		status, _ = do(http.MethodPut, strconv.Itoa(i), "1")
+		check.Equal(t, status, http.StatusNoContent)
+	}
+
+	status, _ = do(http.MethodPost, "key", "")
+	check.Equal(t, status, http.StatusMethodNotAllowed)
+}
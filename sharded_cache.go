@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"hash/maphash"
+	"math/bits"
+)
+
+// ShardedCache splits its capacity across independent LockFreeCache shards, selected by key hash.
+// Options are applied to every shard, so limits such as WithMaxCost apply per shard.
+type ShardedCache[K comparable, V any] struct {
+	shards []*LockFreeCache[K, V]
+	seed   maphash.Seed
+}
+
+// ShardMetrics are the metrics and occupancy of a single shard.
+type ShardMetrics struct {
+	Metrics Metrics
+	Len     int
+	Cap     int
+}
+
+// NewShardedCache returns a cache of the given total size, split across shards.
+// If either is not positive, an empty cache is returned.
+func NewShardedCache[K comparable, V any](shards, size int, opts ...Option[K, V]) *ShardedCache[K, V] {
+	if shards <= 0 || size <= 0 {
+		// Uninitialized shards are safe to use, but never store anything.
+		return &ShardedCache[K, V]{shards: []*LockFreeCache[K, V]{{}}}
+	}
+
+	cfg := newConfig(opts)
+	shardSize := (size + shards - 1) / shards
+
+	shardedCache := &ShardedCache[K, V]{
+		shards: make([]*LockFreeCache[K, V], shards),
+		seed:   maphash.MakeSeed(),
+	}
+
+	for i := range shardedCache.shards {
+		shardedCache.shards[i] = newLockFreeCache(shardSize, maphash.MakeSeed(), cfg)
+	}
+
+	return shardedCache
+}
+
+// shard returns the shard for key.
+func (c *ShardedCache[K, V]) shard(key K) *LockFreeCache[K, V] {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+
+	index, _ := bits.Mul64(maphash.Comparable(c.seed, key), uint64(len(c.shards)))
+
+	return c.shards[index]
+}
+
+func (c *ShardedCache[K, V]) Put(key K, value *V) {
+	c.shard(key).Put(key, value)
+}
+
+func (c *ShardedCache[K, V]) Get(key K) (V, bool) {
+	return c.shard(key).Get(key)
+}
+
+// GetRef is like LockFreeCache.GetRef.
+func (c *ShardedCache[K, V]) GetRef(key K) (*V, bool) {
+	return c.shard(key).GetRef(key)
+}
+
+// GetE is like LockFreeCache.GetE.
+func (c *ShardedCache[K, V]) GetE(key K) (V, error) {
+	return c.shard(key).GetE(key)
+}
+
+// PutIfAbsent is like LockFreeCache.PutIfAbsent.
+func (c *ShardedCache[K, V]) PutIfAbsent(key K, value *V) bool {
+	return c.shard(key).PutIfAbsent(key, value)
+}
+
+// Swap is like LockFreeCache.Swap.
+func (c *ShardedCache[K, V]) Swap(key K, value *V) (V, bool) {
+	return c.shard(key).Swap(key, value)
+}
+
+// Delete removes the entry for key.
+func (c *ShardedCache[K, V]) Delete(key K) {
+	c.shard(key).Delete(key)
+}
+
+// GetAndDelete is like LockFreeCache.GetAndDelete.
+func (c *ShardedCache[K, V]) GetAndDelete(key K) (V, bool) {
+	return c.shard(key).GetAndDelete(key)
+}
+
+// CompareAndSwap is like LockFreeCache.CompareAndSwap.
+func (c *ShardedCache[K, V]) CompareAndSwap(key K, old, newValue *V) bool {
+	return c.shard(key).CompareAndSwap(key, old, newValue)
+}
+
+// CompareAndDelete is like LockFreeCache.CompareAndDelete.
+func (c *ShardedCache[K, V]) CompareAndDelete(key K, old *V) bool {
+	return c.shard(key).CompareAndDelete(key, old)
+}
+
+// Update is like LockFreeCache.Update.
+func (c *ShardedCache[K, V]) Update(key K, fn func(current V, ok bool) *V) (V, bool) {
+	return c.shard(key).Update(key, fn)
+}
+
+// Pin is like LockFreeCache.Pin.
+func (c *ShardedCache[K, V]) Pin(key K) bool {
+	return c.shard(key).Pin(key)
+}
+
+// Unpin is like LockFreeCache.Unpin.
+func (c *ShardedCache[K, V]) Unpin(key K) bool {
+	return c.shard(key).Unpin(key)
+}
+
+// Peek is like LockFreeCache.Peek.
+func (c *ShardedCache[K, V]) Peek(key K) (V, bool) {
+	return c.shard(key).Peek(key)
+}
+
+// Contains is like LockFreeCache.Contains.
+func (c *ShardedCache[K, V]) Contains(key K) bool {
+	return c.shard(key).Contains(key)
+}
+
+// GetOrLoad is like LockFreeCache.GetOrLoad. Loads are coalesced per shard.
+func (c *ShardedCache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context, key K) (*V, error)) (V, error) {
+	return c.shard(key).GetOrLoad(ctx, key, load)
+}
+
+func (c *ShardedCache[K, V]) Len() int {
+	count := 0
+
+	for _, shard := range c.shards {
+		count += shard.Len()
+	}
+
+	return count
+}
+
+// ApproxLen returns the sum of the estimates of all shards, as LockFreeCache.ApproxLen.
+// The error bound applies to each shard.
+func (c *ShardedCache[K, V]) ApproxLen(fraction float64) int {
+	count := 0
+
+	for _, shard := range c.shards {
+		count += shard.ApproxLen(fraction)
+	}
+
+	return count
+}
+
+func (c *ShardedCache[K, V]) Cap() int {
+	size := 0
+
+	for _, shard := range c.shards {
+		size += shard.Cap()
+	}
+
+	return size
+}
+
+// Metrics returns the metrics aggregated over all shards.
+func (c *ShardedCache[K, V]) Metrics() Metrics {
+	var metrics Metrics
+
+	for _, shard := range c.shards {
+		metrics = metrics.add(shard.Metrics())
+	}
+
+	return metrics
+}
+
+// ShardMetrics returns the metrics and occupancy of every shard, so skewed key distributions can be spotted.
+func (c *ShardedCache[K, V]) ShardMetrics() []ShardMetrics {
+	metrics := make([]ShardMetrics, len(c.shards))
+
+	for i, shard := range c.shards {
+		metrics[i] = ShardMetrics{
+			Metrics: shard.Metrics(),
+			Len:     shard.Len(),
+			Cap:     shard.Cap(),
+		}
+	}
+
+	return metrics
+}
+
+// ResetMetrics sets the metric counters of all shards to zero.
+func (c *ShardedCache[K, V]) ResetMetrics() {
+	for _, shard := range c.shards {
+		shard.ResetMetrics()
+	}
+}
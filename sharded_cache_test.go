@@ -0,0 +1,67 @@
+package cache_test
+
+import (
+	cryptorand "crypto/rand"
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestShardedCache(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewShardedCache[string, uint64](8, N/10)
+	check.True(t, testCache.Cap() >= N/10)
+
+	keys := make([]string, 1000)
+	values := make([]uint64, len(keys))
+
+	for i := range keys {
+		keys[i] = cryptorand.Text()
+		values[i] = uint64(i)
+		testCache.Put(keys[i], &values[i])
+	}
+
+	for i, key := range keys {
+		value, ok := testCache.Get(key)
+		check.True(t, ok)
+		check.Equal(t, value, values[i])
+	}
+
+	testCache.Delete(keys[0])
+	check.True(t, !testCache.Contains(keys[0]))
+
+	check.Equal(t, testCache.Len(), len(keys)-1)
+
+	shards := testCache.ShardMetrics()
+	check.Equal(t, len(shards), 8)
+
+	var hits uint64
+	var entries int
+
+	for _, shard := range shards {
+		check.True(t, shard.Len > 0)
+		hits += shard.Metrics.ReadHits
+		entries += shard.Len
+	}
+
+	check.Equal(t, hits, testCache.Metrics().ReadHits)
+	check.Equal(t, entries, testCache.Len())
+
+	runtime.KeepAlive(values)
+}
+
+func TestShardedCacheEmpty(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewShardedCache[string, uint64](0, N)
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+
+	_, ok := testCache.Get("key")
+	check.True(t, !ok)
+	check.Equal(t, testCache.Cap(), 0)
+}
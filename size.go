@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"reflect"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// The first sizeSampleWarmup entries are always measured, afterwards one in sizeSampleRate.
+	sizeSampleWarmup = 16
+	sizeSampleRate   = 64
+
+	// maxSizeDepth bounds how deep pointers are followed when measuring a value.
+	maxSizeDepth = 8
+)
+
+// sizeEstimator approximates the memory used by cache entries.
+// Measuring a value walks it with reflection, so only a sample of entries is measured
+// and the others are assumed to have the running average size.
+type sizeEstimator[K comparable, V any] struct {
+	count   atomic.Uint64
+	average atomic.Int64
+}
+
+func (e *sizeEstimator[K, V]) estimate(key K, value *V) int64 {
+	n := e.count.Add(1)
+	if n > sizeSampleWarmup && n%sizeSampleRate != 0 {
+		return e.average.Load()
+	}
+
+	size := int64(unsafe.Sizeof(cacheEntry[K, V]{})+unsafe.Sizeof(uintptr(0))) +
+		dynamicSize(reflect.ValueOf(&key).Elem(), maxSizeDepth) +
+		int64(unsafe.Sizeof(*value)) + dynamicSize(reflect.ValueOf(value).Elem(), maxSizeDepth)
+
+	// Exponentially weighted moving average. Concurrent updates may be lost, which is fine for an estimate.
+	average := e.average.Load()
+	if n == 1 {
+		average = size
+	} else {
+		average += (size - average) / 8
+	}
+
+	e.average.Store(average)
+
+	return size
+}
+
+// dynamicSize returns the approximate number of bytes referenced by v, excluding the size of v itself.
+func dynamicSize(v reflect.Value, depth int) int64 {
+	if depth == 0 {
+		return 0
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := range v.Len() {
+			size += dynamicSize(v.Index(i), depth-1)
+		}
+
+		return size
+	case reflect.Array:
+		var size int64
+		for i := range v.Len() {
+			size += dynamicSize(v.Index(i), depth-1)
+		}
+
+		return size
+	case reflect.Map:
+		size := int64(v.Len()) * int64(v.Type().Key().Size()+v.Type().Elem().Size())
+
+		iter := v.MapRange()
+		for iter.Next() {
+			size += dynamicSize(iter.Key(), depth-1) + dynamicSize(iter.Value(), depth-1)
+		}
+
+		return size
+	case reflect.Pointer:
+		if v.IsNil() {
+			return 0
+		}
+
+		return int64(v.Type().Elem().Size()) + dynamicSize(v.Elem(), depth-1)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+
+		return int64(v.Elem().Type().Size()) + dynamicSize(v.Elem(), depth-1)
+	case reflect.Struct:
+		var size int64
+		for i := range v.NumField() {
+			size += dynamicSize(v.Field(i), depth-1)
+		}
+
+		return size
+	default:
+		return 0
+	}
+}
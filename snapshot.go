@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Snapshots start with a header of snapshotMagic and snapshotVersion, followed by records.
+// Every record starts with a tag byte. Entry records are followed by the encoded key and value, both
+// prefixed with their length as uvarint, the expiry in Unix nanoseconds as varint, and a flags byte.
+// The end record marks a complete snapshot.
+const (
+	snapshotMagic   = "LFCS"
+	snapshotVersion = 1
+
+	snapshotEnd   = 0
+	snapshotEntry = 1
+
+	snapshotPinned = 1 << 0
+
+	// maxSnapshotField is the maximum size in bytes of an encoded key or value.
+	maxSnapshotField = 1 << 30
+)
+
+// Save writes the live entries to w, with keys and values encoded by the codecs, so the cache can be
+// restored with Load, for example after a restart.
+func (c *LockFreeCache[K, V]) Save(w io.Writer, keys Codec[K], values Codec[V]) error {
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+
+	if err := bw.WriteByte(snapshotVersion); err != nil {
+		return err
+	}
+
+	var (
+		record []byte
+		err    error
+	)
+
+	for index := range c.size {
+		entry := c.slot(index).Load()
+		if entry == nil || entry.keyHash == 0 || c.expired(entry) {
+			continue
+		}
+
+		var ok bool
+
+		record, ok, err = appendEncodedEntry(record[:0], entry, keys, values)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		if _, err := bw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	if err := bw.WriteByte(snapshotEnd); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// Load puts the entries of a snapshot written by Save, with keys and values decoded by the codecs.
+// Entries which expired since are skipped, and pinned entries are pinned again. Like values stored by Put,
+// other values are only kept while they are referenced elsewhere.
+// An incomplete or corrupt snapshot returns ErrInvalidSnapshot, after putting the entries before the corruption.
+func (c *LockFreeCache[K, V]) Load(r io.Reader, keys Codec[K], values Codec[V]) error {
+	if err := c.usable(); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("%w: header: %w", ErrInvalidSnapshot, err)
+	}
+
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("%w: not a snapshot", ErrInvalidSnapshot)
+	}
+
+	if version := header[len(snapshotMagic)]; version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, version)
+	}
+
+	for {
+		tag, err := br.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSnapshot, unexpectedEOF(err))
+		}
+
+		switch tag {
+		case snapshotEnd:
+			return nil
+		case snapshotEntry:
+		default:
+			return fmt.Errorf("%w: unknown record %d", ErrInvalidSnapshot, tag)
+		}
+
+		if err := c.loadEntry(br, keys, values); err != nil {
+			return err
+		}
+	}
+}
+
+// appendEncodedEntry appends the entry record of entry to record, encoded by the codecs.
+// It reports false if the value of entry was reclaimed.
+func appendEncodedEntry[K comparable, V any](record []byte, entry *cacheEntry[K, V], keys Codec[K], values Codec[V]) ([]byte, bool, error) {
+	value := entry.valueRef.Value()
+	if value == nil {
+		return record, false, nil
+	}
+
+	key, err := keys.Encode(&entry.key)
+	if err != nil {
+		return record, false, fmt.Errorf("cache: encode key: %w", err)
+	}
+
+	encoded, err := encodeWithKey(values, key, value)
+	if err != nil {
+		return record, false, fmt.Errorf("cache: encode value: %w", err)
+	}
+
+	var flags byte
+	if entry.pinned != nil {
+		flags |= snapshotPinned
+	}
+
+	return appendEntryRecord(record, key, encoded, entry.expires, flags), true, nil
+}
+
+// appendEntryRecord appends an entry record to record.
+func appendEntryRecord(record, key, value []byte, expires int64, flags byte) []byte {
+	record = append(record, snapshotEntry)
+	record = binary.AppendUvarint(record, uint64(len(key)))
+	record = append(record, key...)
+	record = binary.AppendUvarint(record, uint64(len(value)))
+	record = append(record, value...)
+	record = binary.AppendVarint(record, expires)
+
+	return append(record, flags)
+}
+
+// loadEntry reads and puts a single entry record, after its tag.
+func (c *LockFreeCache[K, V]) loadEntry(br *bufio.Reader, keys Codec[K], values Codec[V]) error {
+	entry, _, err := c.readEntry(br, keys, values)
+	if entry != nil {
+		c.put(entry)
+	}
+
+	return err
+}
+
+// readEntry reads a single entry record, after its tag, and returns a new entry and its value.
+// The entry is nil if it expired.
+func (c *LockFreeCache[K, V]) readEntry(br *bufio.Reader, keys Codec[K], values Codec[V]) (*cacheEntry[K, V], *V, error) {
+	encodedKey, err := readSnapshotField(br)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encodedValue, err := readSnapshotField(br)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expires, err := binary.ReadVarint(br)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, unexpectedEOF(err))
+	}
+
+	flags, err := br.ReadByte()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, unexpectedEOF(err))
+	}
+
+	now := time.Now().UnixNano()
+	if expires != 0 && now >= expires {
+		return nil, nil, nil
+	}
+
+	key, err := keys.Decode(encodedKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: decode key: %w", ErrInvalidSnapshot, err)
+	}
+
+	value, err := decodeWithKey(values, encodedKey, encodedValue)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: decode value: %w", ErrInvalidSnapshot, err)
+	}
+
+	entry := c.newEntry(*key, value, now)
+	entry.expires = expires
+
+	if flags&snapshotPinned != 0 {
+		entry.pinned = value
+	}
+
+	return entry, value, nil
+}
+
+// readSnapshotField reads a length-prefixed field.
+func readSnapshotField(br *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, unexpectedEOF(err))
+	}
+
+	if size > maxSnapshotField {
+		return nil, fmt.Errorf("%w: field of %d bytes", ErrInvalidSnapshot, size)
+	}
+
+	field := make([]byte, size)
+	if _, err := io.ReadFull(br, field); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, unexpectedEOF(err))
+	}
+
+	return field, nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF, as every read within a snapshot expects more data.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,71 @@
+package cache_test
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheSnapshot(t *testing.T) {
+	t.Parallel()
+
+	keys := cache.JSONCodec[string]{}
+	values := cache.JSONCodec[uint64]{}
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	val1, val2 := uint64(1), uint64(2)
+	testCache.Put("key1", &val1)
+	testCache.Put("key2", &val2)
+	check.True(t, testCache.Pin("key2"))
+
+	var snapshot bytes.Buffer
+	check.True(t, testCache.Save(&snapshot, keys, values) == nil)
+
+	runtime.KeepAlive(&val1)
+
+	restored := cache.NewLockFreeCache[string, uint64](N / 100)
+	check.True(t, restored.Load(bytes.NewReader(snapshot.Bytes()), keys, values) == nil)
+
+	// Pinned entries stay pinned, so they survive garbage collection.
+	runtime.GC()
+
+	value, ok := restored.Get("key2")
+	check.True(t, ok)
+	check.Equal(t, value, 2)
+	check.True(t, restored.Unpin("key2"))
+
+	// Truncated snapshots are invalid.
+	err := restored.Load(bytes.NewReader(snapshot.Bytes()[:snapshot.Len()-1]), keys, values)
+	check.True(t, errors.Is(err, cache.ErrInvalidSnapshot))
+
+	err = restored.Load(bytes.NewReader([]byte("LFCS\x02")), keys, values)
+	check.True(t, errors.Is(err, cache.ErrInvalidSnapshot))
+}
+
+func TestLockFreeCacheSnapshotExpired(t *testing.T) {
+	t.Parallel()
+
+	keys := cache.JSONCodec[string]{}
+	values := cache.JSONCodec[uint64]{}
+
+	testCache := cache.NewLockFreeCache(N/100, cache.WithTTL[string, uint64](10*time.Millisecond))
+
+	val := uint64(1)
+	testCache.Put("key", &val)
+	check.True(t, testCache.Pin("key"))
+
+	var snapshot bytes.Buffer
+	check.True(t, testCache.Save(&snapshot, keys, values) == nil)
+
+	time.Sleep(20 * time.Millisecond)
+
+	restored := cache.NewLockFreeCache[string, uint64](N / 100)
+	check.True(t, restored.Load(&snapshot, keys, values) == nil)
+	check.Equal(t, restored.Len(), 0)
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"iter"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// SnapshotView is a consistent point-in-time view of a LockFreeCache, while the cache keeps changing.
+// It is copy-on-write: slots are not copied when the view is taken, but writes to the cache keep the entry they
+// replace for the view, so a view costs little more than a pointer per slot, plus the entries replaced since.
+// Every slot reads the same for the lifetime of the view. Once a slot was read or replaced, the view holds its value
+// strongly, but until then, values which are not referenced elsewhere may still be reclaimed, as in the cache.
+// Writes which are in progress while the view is taken may or may not be seen.
+//
+// Active views slow down writes, so call Release once done. Views which are garbage collected are released.
+type SnapshotView[K comparable, V any] struct {
+	state *snapshotState[K, V]
+}
+
+// snapshotState is the state of a SnapshotView which is registered with its cache, so the view itself
+// can be garbage collected.
+type snapshotState[K comparable, V any] struct {
+	cache *LockFreeCache[K, V]
+	// version is the last version given to an entry when the snapshot was taken.
+	// Entries of later versions were written after it.
+	version uint64
+	now     int64
+	oldest  uint64
+
+	// slots are the entries of the snapshot, once a slot was read or replaced.
+	slots    []atomic.Pointer[viewEntry[K, V]]
+	empty    *viewEntry[K, V]
+	released atomic.Bool
+}
+
+// viewEntry is a copy of an entry, as entries are recycled once removed from the cache.
+// A nil value means the slot was empty.
+type viewEntry[K comparable, V any] struct {
+	key     K
+	keyHash uint64
+	value   *V
+	expires int64
+}
+
+// SnapshotView returns a consistent view of the current entries of the cache, such as for analytics jobs which
+// iterate the cache while traffic continues.
+func (c *LockFreeCache[K, V]) SnapshotView() *SnapshotView[K, V] {
+	state := &snapshotState[K, V]{
+		cache:  c,
+		slots:  make([]atomic.Pointer[viewEntry[K, V]], c.size),
+		empty:  &viewEntry[K, V]{},
+		oldest: c.oldestGeneration.Load(),
+		now:    time.Now().UnixNano(),
+	}
+
+	// Register before taking the version, so the writers of all later versions keep the entries they replace.
+	c.registerSnapshot(state)
+	state.version = c.versions.Load()
+
+	view := &SnapshotView[K, V]{state: state}
+	runtime.AddCleanup(view, func(state *snapshotState[K, V]) { state.cache.unregisterSnapshot(state) }, state)
+
+	return view
+}
+
+// Get returns the value for key as of the snapshot.
+func (v *SnapshotView[K, V]) Get(key K) (V, bool) {
+	c := v.state.cache
+	if !c.initialized.Load() {
+		return *new(V), false
+	}
+
+	keyHash := c.hash(key)
+
+	for i := range c.probeDepth() {
+		entry := v.state.read(c.index(keyHash, i))
+		if entry.keyHash == keyHash && entry.key == key && entry.live(v.state.now) {
+			return *entry.value, true
+		}
+	}
+
+	return *new(V), false
+}
+
+// Len returns the number of live entries as of the snapshot. It reads every slot.
+func (v *SnapshotView[K, V]) Len() int {
+	count := 0
+
+	for range v.All() {
+		count++
+	}
+
+	return count
+}
+
+// All returns an iterator over the keys and values of the live entries as of the snapshot, in table order.
+func (v *SnapshotView[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for index := range v.state.slots {
+			entry := v.state.read(index)
+			if entry.live(v.state.now) && !yield(entry.key, *entry.value) {
+				return
+			}
+		}
+	}
+}
+
+// Release stops keeping replaced entries for the view. Afterwards, the view is no longer consistent.
+func (v *SnapshotView[K, V]) Release() {
+	v.state.released.Store(true)
+	v.state.cache.unregisterSnapshot(v.state)
+}
+
+// read returns the entry of the slot at index as of the snapshot. The first read of a slot fixes its entry,
+// unless a writer already kept the entry it replaced.
+func (s *snapshotState[K, V]) read(index int) *viewEntry[K, V] {
+	c := s.cache
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	for {
+		if entry := s.slots[index].Load(); entry != nil {
+			return entry
+		}
+
+		entry := c.slot(index).Load()
+		if entry != nil && entry.version > s.version {
+			if s.released.Load() {
+				return s.empty
+			}
+
+			// The entry was written after the snapshot, so its writer keeps the entry it replaced.
+			runtime.Gosched()
+			continue
+		}
+
+		s.slots[index].CompareAndSwap(nil, s.copy(entry))
+	}
+}
+
+// preserve keeps entry, which was replaced in the slot at index, unless the slot was already read or replaced.
+func (s *snapshotState[K, V]) preserve(index int, entry *cacheEntry[K, V]) {
+	if s.slots[index].Load() == nil {
+		s.slots[index].CompareAndSwap(nil, s.copy(entry))
+	}
+}
+
+func (s *snapshotState[K, V]) copy(entry *cacheEntry[K, V]) *viewEntry[K, V] {
+	if entry == nil || entry.keyHash == 0 || entry.generation < s.oldest {
+		return s.empty
+	}
+
+	value := entry.valueRef.Value()
+	if value == nil {
+		return s.empty
+	}
+
+	return &viewEntry[K, V]{
+		key:     entry.key,
+		keyHash: entry.keyHash,
+		value:   value,
+		expires: entry.expires,
+	}
+}
+
+// live reports whether the entry holds a value which was not expired at now.
+func (e *viewEntry[K, V]) live(now int64) bool {
+	return e.value != nil && (e.expires == 0 || now < e.expires)
+}
+
+// registerSnapshot adds an active snapshot.
+func (c *LockFreeCache[K, V]) registerSnapshot(state *snapshotState[K, V]) {
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+
+	var snapshots []*snapshotState[K, V]
+	if current := c.snapshots.Load(); current != nil {
+		snapshots = slices.Clone(*current)
+	}
+
+	snapshots = append(snapshots, state)
+	c.snapshots.Store(&snapshots)
+}
+
+// unregisterSnapshot removes an active snapshot, if it was not removed yet.
+func (c *LockFreeCache[K, V]) unregisterSnapshot(state *snapshotState[K, V]) {
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+
+	current := c.snapshots.Load()
+	if current == nil {
+		return
+	}
+
+	snapshots := slices.DeleteFunc(slices.Clone(*current), func(snapshot *snapshotState[K, V]) bool {
+		return snapshot == state
+	})
+
+	if len(snapshots) == 0 {
+		c.snapshots.Store(nil)
+		return
+	}
+
+	c.snapshots.Store(&snapshots)
+}
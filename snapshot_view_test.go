@@ -0,0 +1,124 @@
+package cache_test
+
+import (
+	cryptorand "crypto/rand"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheSnapshotView(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	keys := make([]string, 100)
+	values := make([]uint64, len(keys))
+
+	for i := range keys {
+		keys[i] = cryptorand.Text()
+		values[i] = uint64(i)
+		testCache.Put(keys[i], &values[i])
+	}
+
+	present := make([]bool, len(keys))
+	for i, key := range keys {
+		_, present[i] = testCache.Peek(key)
+	}
+
+	length := testCache.Len()
+	view := testCache.SnapshotView()
+
+	// Replace, delete and add entries after the snapshot.
+	updated := make([]uint64, len(keys))
+	added := make([]uint64, len(keys))
+
+	for i := range keys {
+		switch i % 3 {
+		case 0:
+			updated[i] = uint64(i) + 1000
+			testCache.Put(keys[i], &updated[i])
+		case 1:
+			testCache.Delete(keys[i])
+		default:
+			testCache.Put(cryptorand.Text(), &added[i])
+		}
+	}
+
+	check.Equal(t, view.Len(), length)
+
+	for i, key := range keys {
+		value, ok := view.Get(key)
+		check.Equal(t, ok, present[i])
+
+		if ok {
+			check.Equal(t, value, uint64(i))
+		}
+	}
+
+	for key, value := range view.All() {
+		check.Equal(t, key, keys[value])
+	}
+
+	view.Release()
+
+	runtime.KeepAlive(values)
+	runtime.KeepAlive(updated)
+	runtime.KeepAlive(added)
+}
+
+func TestLockFreeCacheSnapshotViewConcurrent(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	values := make([]uint64, 1000)
+	for i := range values {
+		values[i] = uint64(i)
+		testCache.Put(strconv.Itoa(i), &values[i])
+	}
+
+	view := testCache.SnapshotView()
+	defer view.Release()
+
+	first := make(map[string]uint64)
+	for key, value := range view.All() {
+		first[key] = value
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		written := make([]uint64, 10000)
+		for i := range written {
+			written[i] = uint64(i)
+			testCache.Put(strconv.Itoa(i%2000), &written[i])
+		}
+
+		runtime.KeepAlive(written)
+	}()
+
+	// Iterations while the cache changes see the same entries.
+	for range 10 {
+		seen := 0
+
+		for key, value := range view.All() {
+			check.Equal(t, value, first[key])
+			seen++
+		}
+
+		check.Equal(t, seen, len(first))
+	}
+
+	wg.Wait()
+
+	runtime.KeepAlive(values)
+}
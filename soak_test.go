@@ -0,0 +1,204 @@
+//go:build soak
+
+package cache
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"flag"
+	mathrand "math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	soakDuration = flag.Duration("soak.duration", 2*time.Minute, "duration of the soak test")
+	soakInterval = flag.Duration("soak.interval", time.Second, "interval between invariant checks")
+)
+
+// Run with: go test -tags soak -run Soak -soak.duration 10m
+func TestSoakLockFreeCache(t *testing.T) {
+	testCache := NewLockFreeCache[string, uint64](1 << 12)
+
+	soak(t, func(key string, value *uint64) {
+		testCache.Put(key, value)
+	}, func(key string) {
+		testCache.Get(key)
+	}, func(t *testing.T) {
+		checkLockFreeInvariants(t, testCache)
+	})
+}
+
+func TestSoakCache(t *testing.T) {
+	testCache := NewCache[string, uint64](0, 1<<10)
+
+	soak(t, func(key string, value *uint64) {
+		testCache.Put(key, value)
+	}, func(key string) {
+		testCache.Get(key)
+	}, func(t *testing.T) {
+		checkCacheInvariants(t, testCache)
+		testCache.Compact()
+		checkCacheInvariants(t, testCache)
+	})
+}
+
+func soak(t *testing.T, put func(string, *uint64), get func(string), verify func(*testing.T)) {
+	t.Helper()
+
+	// Workers hold a read lock per operation, so invariants are verified on a quiescent cache.
+	var pause sync.RWMutex
+
+	ctx, cancel := context.WithTimeout(t.Context(), *soakDuration)
+	defer cancel()
+
+	// A bounded key space, so keys are regularly overwritten.
+	keys := make([]string, 1<<14)
+	for i := range keys {
+		keys[i] = cryptorand.Text()
+	}
+
+	// Values which are kept alive, next to values which are left for the garbage collector.
+	retained := make([]atomic.Pointer[uint64], len(keys))
+
+	var wg sync.WaitGroup
+
+	for range runtime.GOMAXPROCS(0) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				index := mathrand.IntN(len(keys))
+
+				pause.RLock()
+
+				switch mathrand.IntN(4) {
+				case 0:
+					value := mathrand.Uint64()
+					put(keys[index], &value)
+				case 1:
+					value := mathrand.Uint64()
+					retained[mathrand.IntN(len(retained))].Store(&value)
+					put(keys[index], &value)
+				default:
+					get(keys[index])
+				}
+
+				pause.RUnlock()
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(*soakInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			verify(t)
+			runtime.KeepAlive(retained)
+
+			return
+		case <-ticker.C:
+			runtime.GC()
+			pause.Lock()
+			verify(t)
+			pause.Unlock()
+		}
+	}
+}
+
+func checkLockFreeInvariants(t *testing.T, c *LockFreeCache[string, uint64]) {
+	t.Helper()
+
+	if length := c.Len(); length < 0 || length > c.Cap() {
+		t.Errorf("length %d out of bounds [0, %d]", length, c.Cap())
+	}
+
+	metrics := c.Metrics()
+	writes := metrics.FirstWrites + metrics.ProbeWrites + metrics.EmptyWrites +
+		metrics.RandomCASWrites + metrics.RandomWrites
+
+	// Counters are unsigned, so a decrement below zero shows up as a huge value.
+	for name, counter := range map[string]uint64{
+		"ReadHits":   metrics.ReadHits,
+		"ReadMisses": metrics.ReadMisses,
+		"Writes":     writes,
+	} {
+		if counter > 1<<62 {
+			t.Errorf("counter %s underflowed: %d", name, counter)
+		}
+	}
+
+	live := make(map[string]int)
+
+	for i := range c.size {
+		entry := c.slot(i).Load()
+		if entry == nil || entry.keyHash == 0 || entry.valueRef.Value() == nil {
+			continue
+		}
+
+		if previous, ok := live[entry.key]; ok {
+			t.Errorf("key %q is live in slots %d and %d", entry.key, previous, i)
+		}
+
+		live[entry.key] = i
+	}
+}
+
+func checkCacheInvariants(t *testing.T, c *Cache[string, uint64]) {
+	t.Helper()
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.maxSize != 0 && len(c.keyHashes) > c.maxSize {
+		t.Errorf("length %d exceeds max size %d", len(c.keyHashes), c.maxSize)
+	}
+
+	if len(c.keyHashes) != len(c.entries) {
+		t.Errorf("key hashes (%d) and entries (%d) out of sync", len(c.keyHashes), len(c.entries))
+	}
+
+	if len(c.keyHashes) != len(c.cleanups) {
+		t.Errorf("key hashes (%d) and cleanups (%d) out of sync", len(c.keyHashes), len(c.cleanups))
+	}
+
+	live := make(map[uint64]int)
+
+	for i, keyHash := range c.keyHashes {
+		if keyHash == 0 || c.entries[i].valueRef.Value() == nil {
+			continue
+		}
+
+		if previous, ok := live[keyHash]; ok {
+			t.Errorf("key hash %d is live in slots %d and %d", keyHash, previous, i)
+		}
+
+		live[keyHash] = i
+	}
+
+	indexed := 0
+
+	for i, keyHash := range c.keyHashes {
+		if keyHash == 0 {
+			continue
+		}
+
+		indexed++
+
+		if position := c.find(keyHash); position != i {
+			t.Errorf("key hash %d in slot %d is indexed at %d", keyHash, i, position)
+		}
+	}
+
+	if indexed != c.index.count || c.index.count*4 > len(c.index.slots)*3 {
+		t.Errorf("index holds %d of %d key hashes in %d slots", c.index.count, indexed, len(c.index.slots))
+	}
+}
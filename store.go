@@ -0,0 +1,253 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Store is a backing store, such as a database, from which missing values are loaded.
+// Load returns ErrNotFound if no value exists for key.
+type Store[K comparable, V any] interface {
+	Load(ctx context.Context, key K) (*V, error)
+}
+
+// WriteStore is a Store which can also persist and delete values, as used by WithWriteThrough.
+type WriteStore[K comparable, V any] interface {
+	Store[K, V]
+	Save(ctx context.Context, key K, value *V) error
+	Delete(ctx context.Context, key K) error
+}
+
+// ReadThroughCache is a LockFreeCache which transparently loads missing values from a Store.
+// Concurrent loads of the same key are coalesced.
+type ReadThroughCache[K comparable, V any] struct {
+	cache *LockFreeCache[K, V]
+	store Store[K, V]
+
+	// writer is set if write-through is enabled.
+	writer WriteStore[K, V]
+	// behind is set if write-behind is enabled.
+	behind *writeBehind[K, V]
+}
+
+// NewReadThroughCache returns a read-through cache of the given size in front of store.
+// The options configure the underlying LockFreeCache, and WithWriteThrough or WithWriteBehind the read-through cache itself.
+// It panics with an error wrapping ErrInvalidConfig if both WithWriteThrough and WithWriteBehind are given.
+func NewReadThroughCache[K comparable, V any](size int, store Store[K, V], opts ...Option[K, V]) *ReadThroughCache[K, V] {
+	cfg := newConfig(opts)
+	if err := cfg.validateWriteMode(); err != nil {
+		panic(err)
+	}
+
+	readThroughCache := &ReadThroughCache[K, V]{
+		cache: newConfiguredLockFreeCache(size, cfg),
+		store: store,
+	}
+
+	if cfg.writeThrough || cfg.writeBehind != nil {
+		// A store without write support fails every write, see Put.
+		writer, _ := store.(WriteStore[K, V])
+		if writer == nil {
+			writer = readOnlyStore[K, V]{store}
+		}
+
+		if cfg.writeBehind != nil {
+			readThroughCache.behind = newWriteBehind(writer, cfg.writeBehind.queueSize, cfg.writeBehind.workers, cfg.writeBehind.interval)
+		} else {
+			readThroughCache.writer = writer
+		}
+	}
+
+	return readThroughCache
+}
+
+// Get returns the value for key, loading it from the store if it is not cached.
+// Store errors are returned and not cached.
+func (c *ReadThroughCache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return c.cache.GetOrLoad(ctx, key, c.store.Load)
+}
+
+// Put stores value for key. With write-through, the value is first saved to the store,
+// and the cache is only updated if that succeeded. With write-behind, the cache is updated and the write
+// is queued, blocking while the queue is full. Otherwise only the cache is updated.
+func (c *ReadThroughCache[K, V]) Put(ctx context.Context, key K, value *V) error {
+	if c.cache.closed.Load() {
+		return ErrClosed
+	}
+
+	if c.behind != nil {
+		if err := c.behind.enqueue(ctx, writeOp[K, V]{key: key, value: value}); err != nil {
+			return err
+		}
+	}
+
+	if c.writer != nil {
+		if err := c.writer.Save(ctx, key, value); err != nil {
+			return err
+		}
+	}
+
+	c.cache.Put(key, value)
+
+	return nil
+}
+
+// Delete removes the entry for key. With write-through, it is first deleted from the store,
+// and the cache is only updated if that succeeded. With write-behind, the deletion is queued like a Put.
+// Otherwise only the cache is updated.
+func (c *ReadThroughCache[K, V]) Delete(ctx context.Context, key K) error {
+	if c.cache.closed.Load() {
+		return ErrClosed
+	}
+
+	if c.behind != nil {
+		if err := c.behind.enqueue(ctx, writeOp[K, V]{key: key}); err != nil {
+			return err
+		}
+	}
+
+	if c.writer != nil {
+		if err := c.writer.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	c.cache.Delete(key)
+
+	return nil
+}
+
+// Flush waits until all writes queued by write-behind before the call are saved to the store, and then flushes
+// the cache, as LockFreeCache.Flush. It returns the errors of all saves since the last Flush, or the error of ctx
+// once it is done.
+func (c *ReadThroughCache[K, V]) Flush(ctx context.Context) error {
+	if c.behind != nil {
+		if err := c.behind.flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	return c.cache.Flush(ctx)
+}
+
+// Close stops write-behind, waiting until all queued writes are saved to the store, and then closes the cache,
+// as LockFreeCache.Close. It returns the errors of all saves since the last Flush and of closing the cache.
+// Later writes return ErrClosed.
+func (c *ReadThroughCache[K, V]) Close() error {
+	var err error
+	if c.behind != nil {
+		err = c.behind.close()
+	}
+
+	return errors.Join(err, c.cache.Close())
+}
+
+func (c *ReadThroughCache[K, V]) Len() int {
+	return c.cache.Len()
+}
+
+func (c *ReadThroughCache[K, V]) Cap() int {
+	return c.cache.Cap()
+}
+
+func (c *ReadThroughCache[K, V]) Metrics() Metrics {
+	return c.cache.Metrics()
+}
+
+// readOnlyStore fails all writes, for write-through caches in front of a store without write support.
+type readOnlyStore[K comparable, V any] struct {
+	Store[K, V]
+}
+
+func (readOnlyStore[K, V]) Save(context.Context, K, *V) error {
+	return fmt.Errorf("%w: write-through requires a WriteStore", ErrInvalidConfig)
+}
+
+func (readOnlyStore[K, V]) Delete(context.Context, K) error {
+	return fmt.Errorf("%w: write-through requires a WriteStore", ErrInvalidConfig)
+}
+
+// StoreTier adapts a WriteStore to Interface, for example to use a shared store as the L2 of a TieredCache.
+// Every call uses a context with a timeout. As Interface does not return errors, store errors are
+// counted, and Get returns a miss on an error, while failed writes are dropped.
+type StoreTier[K comparable, V any] struct {
+	store   WriteStore[K, V]
+	timeout time.Duration
+
+	readMisses, readHits atomic.Uint64
+	writes               atomic.Uint64
+	failures             atomic.Uint64
+}
+
+var _ Interface[string, any] = (*StoreTier[string, any])(nil)
+
+// NewStoreTier returns a tier which calls store with the given timeout.
+func NewStoreTier[K comparable, V any](store WriteStore[K, V], timeout time.Duration) *StoreTier[K, V] {
+	return &StoreTier[K, V]{store: store, timeout: timeout}
+}
+
+func (t *StoreTier[K, V]) Get(key K) (V, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	value, err := t.store.Load(ctx, key)
+	if err != nil || value == nil {
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			t.failures.Add(1)
+		}
+
+		t.readMisses.Add(1)
+
+		return *new(V), false
+	}
+
+	t.readHits.Add(1)
+
+	return *value, true
+}
+
+func (t *StoreTier[K, V]) Put(key K, value *V) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	t.writes.Add(1)
+
+	if err := t.store.Save(ctx, key, value); err != nil {
+		t.failures.Add(1)
+	}
+}
+
+func (t *StoreTier[K, V]) Delete(key K) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	if err := t.store.Delete(ctx, key); err != nil {
+		t.failures.Add(1)
+	}
+}
+
+// Len returns 0, as the number of entries of a store is unknown.
+func (t *StoreTier[K, V]) Len() int {
+	return 0
+}
+
+// Cap returns 0, as the capacity of a store is unknown.
+func (t *StoreTier[K, V]) Cap() int {
+	return 0
+}
+
+// Errors returns the number of failed store calls, other than ErrNotFound.
+func (t *StoreTier[K, V]) Errors() uint64 {
+	return t.failures.Load()
+}
+
+func (t *StoreTier[K, V]) Metrics() Metrics {
+	return Metrics{
+		ReadMisses:  t.readMisses.Load(),
+		ReadHits:    t.readHits.Load(),
+		FirstWrites: t.writes.Load(),
+	}
+}
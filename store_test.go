@@ -0,0 +1,223 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+// mapStore is a store backed by a map, counting its loads.
+type mapStore[K comparable, V any] struct {
+	lock   sync.Mutex
+	values map[K]*V
+	loads  atomic.Int64
+
+	// err is returned by all writes, if set.
+	err error
+}
+
+func newMapStore[K comparable, V any]() *mapStore[K, V] {
+	return &mapStore[K, V]{values: make(map[K]*V)}
+}
+
+func (s *mapStore[K, V]) Load(_ context.Context, key K) (*V, error) {
+	s.loads.Add(1)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+
+	return value, nil
+}
+
+func (s *mapStore[K, V]) Save(_ context.Context, key K, value *V) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	s.values[key] = value
+
+	return nil
+}
+
+func (s *mapStore[K, V]) Delete(_ context.Context, key K) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	delete(s.values, key)
+
+	return nil
+}
+
+func TestReadThroughCache(t *testing.T) {
+	t.Parallel()
+
+	store := newMapStore[string, uint64]()
+
+	val := uint64(1)
+	store.values["key"] = &val
+
+	testCache := cache.NewReadThroughCache[string, uint64](16, store)
+
+	for range 3 {
+		value, err := testCache.Get(t.Context(), "key")
+		check.True(t, err == nil)
+		check.Equal(t, value, 1)
+	}
+
+	// The value is held by the store, so it stays cached.
+	check.Equal(t, store.loads.Load(), 1)
+
+	_, err := testCache.Get(t.Context(), "missing")
+	check.True(t, errors.Is(err, cache.ErrNotFound))
+}
+
+func TestReadThroughCacheWriteThrough(t *testing.T) {
+	t.Parallel()
+
+	store := newMapStore[string, uint64]()
+	testCache := cache.NewReadThroughCache(16, store, cache.WithWriteThrough[string, uint64]())
+
+	val := uint64(1)
+	check.True(t, testCache.Put(t.Context(), "key", &val) == nil)
+	check.Equal(t, *store.values["key"], 1)
+
+	// Failed writes do not update the cache.
+	errWrite := errors.New("write failed")
+	store.err = errWrite
+
+	other := uint64(2)
+	check.True(t, errors.Is(testCache.Put(t.Context(), "other", &other), errWrite))
+	check.True(t, errors.Is(testCache.Delete(t.Context(), "key"), errWrite))
+
+	store.err = nil
+
+	value, err := testCache.Get(t.Context(), "key")
+	check.True(t, err == nil)
+	check.Equal(t, value, 1)
+
+	check.True(t, testCache.Delete(t.Context(), "key") == nil)
+	check.Equal(t, len(store.values), 0)
+
+	// Stores without write support fail every write.
+	readOnlyStore := struct{ cache.Store[string, uint64] }{store}
+	readOnly := cache.NewReadThroughCache[string, uint64](16, readOnlyStore, cache.WithWriteThrough[string, uint64]())
+	check.True(t, errors.Is(readOnly.Put(t.Context(), "key", &val), cache.ErrInvalidConfig))
+}
+
+func TestReadThroughCacheWriteModes(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		err, _ := recover().(error)
+		check.True(t, errors.Is(err, cache.ErrInvalidConfig))
+	}()
+
+	cache.NewReadThroughCache(16, newMapStore[string, uint64](),
+		cache.WithWriteThrough[string, uint64](),
+		cache.WithWriteBehind[string, uint64](64, 2, time.Hour),
+	)
+	t.Fatal("both write modes were accepted")
+}
+
+func TestReadThroughCacheWriteBehind(t *testing.T) {
+	t.Parallel()
+
+	store := newMapStore[string, uint64]()
+	testCache := cache.NewReadThroughCache(16, store, cache.WithWriteBehind[string, uint64](64, 2, time.Hour))
+
+	values := make([]uint64, 10)
+	for i := range values {
+		values[i] = uint64(i)
+		check.True(t, testCache.Put(t.Context(), strconv.Itoa(i), &values[i]) == nil)
+	}
+
+	check.True(t, testCache.Delete(t.Context(), "0") == nil)
+	check.True(t, testCache.Flush(t.Context()) == nil)
+
+	store.lock.Lock()
+	check.Equal(t, len(store.values), len(values)-1)
+	check.Equal(t, *store.values["9"], 9)
+	store.lock.Unlock()
+
+	// Close saves the remaining writes and reports failed saves.
+	errWrite := errors.New("write failed")
+
+	store.lock.Lock()
+	store.err = errWrite
+	store.lock.Unlock()
+
+	check.True(t, testCache.Put(t.Context(), "1", &values[1]) == nil)
+	check.True(t, errors.Is(testCache.Close(), errWrite))
+	check.True(t, errors.Is(testCache.Put(t.Context(), "1", &values[1]), cache.ErrClosed))
+
+	runtime.KeepAlive(values)
+}
+
+func TestReadThroughCacheNegativeTTL(t *testing.T) {
+	t.Parallel()
+
+	store := newMapStore[string, uint64]()
+	testCache := cache.NewReadThroughCache(16, store, cache.WithNegativeTTL[string, uint64](time.Hour))
+
+	for range 3 {
+		_, err := testCache.Get(t.Context(), "missing")
+		check.True(t, errors.Is(err, cache.ErrNotFound))
+	}
+
+	check.Equal(t, store.loads.Load(), 1)
+
+	// Storing a value removes the negative result.
+	val := uint64(1)
+	check.True(t, testCache.Put(t.Context(), "missing", &val) == nil)
+
+	value, err := testCache.Get(t.Context(), "missing")
+	check.True(t, err == nil)
+	check.Equal(t, value, 1)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestStoreTier(t *testing.T) {
+	t.Parallel()
+
+	store := newMapStore[string, uint64]()
+	tier := cache.NewStoreTier[string, uint64](store, time.Second)
+
+	_, ok := tier.Get("key")
+	check.True(t, !ok)
+
+	val := uint64(1)
+	tier.Put("key", &val)
+
+	value, ok := tier.Get("key")
+	check.True(t, ok)
+	check.Equal(t, value, 1)
+
+	store.err = errors.New("store unavailable")
+	tier.Delete("key")
+	check.Equal(t, tier.Errors(), 1)
+
+	metrics := tier.Metrics()
+	check.Equal(t, metrics.ReadHits, 1)
+	check.Equal(t, metrics.ReadMisses, 1)
+}
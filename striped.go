@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// stripedCounter is a counter split across cache line padded stripes, so concurrent adds from
+// different cores rarely touch the same cache line. Each add picks a random stripe, which
+// approximates per-P counters. Loads sum all stripes.
+type stripedCounter struct {
+	stripes []paddedCounter
+	mask    uint32
+}
+
+type paddedCounter struct {
+	count atomic.Uint64
+	_     [cacheLineSize - 8]byte
+}
+
+func newStripedCounter() stripedCounter {
+	stripes := stripeCount()
+
+	return stripedCounter{
+		stripes: make([]paddedCounter, stripes),
+		mask:    uint32(stripes - 1),
+	}
+}
+
+func (s *stripedCounter) Add(n uint64) {
+	s.stripes[rand.Uint32()&s.mask].count.Add(n)
+}
+
+func (s *stripedCounter) Load() uint64 {
+	var total uint64
+
+	for i := range s.stripes {
+		total += s.stripes[i].count.Load()
+	}
+
+	return total
+}
+
+func (s *stripedCounter) reset() {
+	for i := range s.stripes {
+		s.stripes[i].count.Store(0)
+	}
+}
+
+// stripeCount returns the number of stripes for per-P approximations, GOMAXPROCS rounded up to a power of two.
+func stripeCount() int {
+	return 1 << bits.Len(uint(runtime.GOMAXPROCS(0)-1))
+}
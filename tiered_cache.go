@@ -0,0 +1,56 @@
+package cache
+
+// TieredCache composes a small, fast L1 LockFreeCache with a larger L2 cache.
+// Get falls through to L2 on an L1 miss and back-fills L1, while Put and Delete apply to both tiers.
+type TieredCache[K comparable, V any] struct {
+	l1 *LockFreeCache[K, V]
+	l2 Interface[K, V]
+}
+
+var _ Interface[string, any] = (*TieredCache[string, any])(nil)
+
+// NewTieredCache returns a cache using l1 in front of l2.
+func NewTieredCache[K comparable, V any](l1 *LockFreeCache[K, V], l2 Interface[K, V]) *TieredCache[K, V] {
+	return &TieredCache[K, V]{l1: l1, l2: l2}
+}
+
+func (c *TieredCache[K, V]) Get(key K) (V, bool) {
+	if value, ok := c.l1.Get(key); ok {
+		return value, true
+	}
+
+	value, ok := c.l2.Get(key)
+	if !ok {
+		return *new(V), false
+	}
+
+	// L2 only returns a copy of the value, which nothing else references, so L1 owns it.
+	c.l1.putOwned(key, &value)
+
+	return value, true
+}
+
+func (c *TieredCache[K, V]) Put(key K, value *V) {
+	c.l2.Put(key, value)
+	c.l1.Put(key, value)
+}
+
+func (c *TieredCache[K, V]) Delete(key K) {
+	c.l1.Delete(key)
+	c.l2.Delete(key)
+}
+
+// Len returns the number of entries in L2, as L1 is expected to hold a subset of them.
+func (c *TieredCache[K, V]) Len() int {
+	return c.l2.Len()
+}
+
+// Cap returns the capacity of L2.
+func (c *TieredCache[K, V]) Cap() int {
+	return c.l2.Cap()
+}
+
+// Metrics returns the sum of the metrics of both tiers. An L1 miss which hits L2 counts as both a miss and a hit.
+func (c *TieredCache[K, V]) Metrics() Metrics {
+	return c.l1.Metrics().add(c.l2.Metrics())
+}
@@ -0,0 +1,65 @@
+package cache_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestTieredCache(t *testing.T) {
+	t.Parallel()
+
+	l1 := cache.NewLockFreeCache[string, uint64](16)
+	l2 := cache.NewCache[string, uint64](0, 0)
+
+	testCache := cache.NewTieredCache[string, uint64](l1, l2)
+
+	val := uint64(1)
+
+	// Values only in L2 are back-filled into L1.
+	l2.Put("key", &val)
+	check.True(t, !l1.Contains("key"))
+
+	value, ok := testCache.Get("key")
+	check.True(t, ok)
+	check.Equal(t, value, 1)
+	check.True(t, l1.Contains("key"))
+
+	other := uint64(2)
+	testCache.Put("other", &other)
+	check.True(t, l1.Contains("other"))
+	check.True(t, l2.Contains("other"))
+
+	testCache.Delete("other")
+	check.True(t, !l1.Contains("other"))
+	check.True(t, !l2.Contains("other"))
+
+	runtime.KeepAlive(&val)
+	runtime.KeepAlive(&other)
+}
+
+// Not parallel, as it depends on a garbage collection.
+func TestTieredCacheBackFillSurvivesGC(t *testing.T) {
+	l1 := cache.NewLockFreeCache[string, uint64](16)
+	l2 := cache.NewCache[string, uint64](0, 0)
+
+	testCache := cache.NewTieredCache[string, uint64](l1, l2)
+
+	val := uint64(1)
+	l2.Put("key", &val)
+
+	_, ok := testCache.Get("key")
+	check.True(t, ok)
+
+	// The back-filled copy is owned by L1, so it is not reclaimed while the entry is cached.
+	runtime.GC()
+	runtime.GC()
+
+	value, ok := l1.Get("key")
+	check.True(t, ok)
+	check.Equal(t, value, 1)
+
+	runtime.KeepAlive(&val)
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"sync"
+	"time"
+	"weak"
+)
+
+const (
+	// wheelBits is the number of bits of the ticks per level of a timing wheel, so every level has 64 buckets.
+	wheelBits = 6
+	wheelSize = 1 << wheelBits
+	wheelMask = wheelSize - 1
+	// wheelLevels is the number of levels of a timing wheel. With a tick of a second, they span 194 days.
+	wheelLevels = 4
+)
+
+// timingWheel schedules expirations in buckets by tick, in levels of increasing span. Every level covers
+// 64 buckets of the level below, and buckets are moved down a level as their time nears, so adding
+// an expiration and expiring it both take constant time, and advancing only touches due buckets.
+type timingWheel[K comparable] struct {
+	tick int64
+
+	lock sync.Mutex
+	// current is the next tick to expire.
+	current int64
+	levels  [wheelLevels][wheelSize][]wheelItem[K]
+	// overflow holds the expirations beyond the span of the last level.
+	overflow []wheelItem[K]
+}
+
+type wheelItem[K comparable] struct {
+	key     K
+	expires int64
+}
+
+func newTimingWheel[K comparable](tick time.Duration, now int64) *timingWheel[K] {
+	return &timingWheel[K]{
+		tick:    int64(tick),
+		current: now / int64(tick),
+	}
+}
+
+// add schedules the expiration of key at expires, in Unix nanoseconds.
+func (w *timingWheel[K]) add(key K, expires int64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.schedule(wheelItem[K]{key: key, expires: expires})
+}
+
+// schedule puts item in the lowest level whose span covers it, holding the lock.
+func (w *timingWheel[K]) schedule(item wheelItem[K]) {
+	// Expirations are due once their tick has passed.
+	tick := max(w.current, item.expires/w.tick+1)
+
+	for level := range wheelLevels {
+		shift := level * wheelBits
+		if (tick>>shift)-(w.current>>shift) < wheelSize {
+			bucket := &w.levels[level][(tick>>shift)&wheelMask]
+			*bucket = append(*bucket, item)
+
+			return
+		}
+	}
+
+	w.overflow = append(w.overflow, item)
+}
+
+// advance expires all buckets up to now, in Unix nanoseconds, and returns the due expirations.
+func (w *timingWheel[K]) advance(now int64) []wheelItem[K] {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var due []wheelItem[K]
+
+	for target := now / w.tick; w.current <= target; w.current++ {
+		w.cascade()
+
+		bucket := &w.levels[0][w.current&wheelMask]
+		due = append(due, *bucket...)
+		*bucket = nil
+	}
+
+	return due
+}
+
+// cascade moves the buckets of the higher levels which start at the current tick down a level,
+// starting with the highest level, holding the lock.
+func (w *timingWheel[K]) cascade() {
+	if w.current&(1<<(wheelLevels*wheelBits)-1) == 0 {
+		overflow := w.overflow
+		w.overflow = nil
+
+		for _, item := range overflow {
+			w.schedule(item)
+		}
+	}
+
+	for level := wheelLevels - 1; level > 0; level-- {
+		shift := level * wheelBits
+		if w.current&(1<<shift-1) != 0 {
+			continue
+		}
+
+		bucket := &w.levels[level][(w.current>>shift)&wheelMask]
+		items := *bucket
+		*bucket = nil
+
+		for _, item := range items {
+			w.schedule(item)
+		}
+	}
+}
+
+// startExpirationWheel starts the worker which removes expired entries every tick,
+// which stops once the cache is closed or garbage collected.
+func (c *LockFreeCache[K, V]) startExpirationWheel(tick time.Duration) {
+	c.wheel = newTimingWheel[K](tick, time.Now().UnixNano())
+
+	cache, stop := weak.Make(c), c.life.stop
+	c.life.goroutine(func() { expirationLoop(cache, tick, stop) })
+}
+
+// expirationLoop advances the timing wheel of the cache every tick. It only holds a weak reference between ticks,
+// so it does not keep the cache alive.
+func expirationLoop[K comparable, V any](cache weak.Pointer[LockFreeCache[K, V]], tick time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c := cache.Value()
+			if c == nil {
+				return
+			}
+
+			for _, item := range c.wheel.advance(time.Now().UnixNano()) {
+				c.expireKey(item.key)
+			}
+		}
+	}
+}
+
+// expireKey removes the entry for key if it expired, unless expired entries are kept to be served stale.
+// Expirations of entries which were since replaced, deleted or given a later expiry are ignored, as their new
+// expiry was scheduled separately. Removed entries are not recycled, as readers may still refer to them.
+func (c *LockFreeCache[K, V]) expireKey(key K) {
+	if c.staleWhileRevalidate {
+		return
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	keyHash := c.hash(key)
+
+	for i := range c.probeDepth() {
+		index := c.index(keyHash, i)
+
+		entry := c.slot(index).Load()
+		if entry != nil && entry.keyHash == keyHash && entry.key == key && c.expired(entry) {
+			c.detach(entry, index, EvictionExpired)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package cache_test
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheExpirationWheel(t *testing.T) {
+	t.Parallel()
+
+	var expired atomic.Int64
+
+	testCache := cache.NewLockFreeCache(N/100,
+		cache.WithTTL[string, uint64](5*time.Millisecond),
+		cache.WithExpirationWheel[string, uint64](time.Millisecond),
+		cache.WithHooks(cache.Hooks[string, uint64]{
+			OnEvict: func(_ string, _ *uint64, reason cache.EvictionReason) {
+				if reason == cache.EvictionExpired {
+					expired.Add(1)
+				}
+			},
+		}),
+	)
+
+	val := uint64(1)
+	testCache.Put("key1", &val)
+	testCache.Put("key2", &val)
+	// Beyond the span of the first level of the wheel.
+	testCache.PutWithExpiry("key3", &val, time.Now().Add(100*time.Millisecond))
+	testCache.PutWithExpiry("key4", &val, time.Time{})
+
+	// Expired entries are removed without being read.
+	for range 1000 {
+		if expired.Load() == 2 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	check.Equal(t, expired.Load(), 2)
+	check.True(t, testCache.Contains("key3"))
+
+	for range 1000 {
+		if expired.Load() == 3 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	check.Equal(t, expired.Load(), 3)
+	check.True(t, testCache.Contains("key4"))
+
+	runtime.KeepAlive(&val)
+}
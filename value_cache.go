@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"hash/maphash"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// ValueCache is a fixed-size cache which stores keys and values inline in its slots, for small value types such as
+// integers and small structs. Unlike LockFreeCache, values are passed and stored by value, so they do not rely on
+// weak pointers and are never reclaimed by the garbage collector. Every slot is guarded by a seqlock: writers lock
+// the slot, while readers copy it without locking and retry if it was written concurrently, so reads are cheap but
+// large values are copied repeatedly under contention.
+// Once all slots within the probe depth of a key are in use, a random entry is evicted.
+type ValueCache[K comparable, V any] struct {
+	slots          []valueSlot[K, V]
+	seed           maphash.Seed
+	prober         Prober
+	hashProbeDepth int
+
+	readMisses, readHits atomic.Uint64
+	writes               atomic.Uint64
+	overwrites           atomic.Uint64
+}
+
+// valueSlot is a slot of a ValueCache. Its sequence is odd while a writer holds the slot.
+type valueSlot[K comparable, V any] struct {
+	sequence atomic.Uint64
+	// keyHash is zero for empty slots.
+	keyHash uint64
+	key     K
+	value   V
+}
+
+func NewValueCache[K comparable, V any](size int) *ValueCache[K, V] {
+	if size <= 0 {
+		return &ValueCache[K, V]{}
+	}
+
+	return &ValueCache[K, V]{
+		slots:          make([]valueSlot[K, V], size),
+		seed:           maphash.MakeSeed(),
+		prober:         NewProber(size),
+		hashProbeDepth: max(1, int(math.Log2(float64(size)))),
+	}
+}
+
+// Get returns a copy of the value for key.
+func (c *ValueCache[K, V]) Get(key K) (V, bool) {
+	if len(c.slots) == 0 {
+		return *new(V), false
+	}
+
+	keyHash := c.hash(key)
+
+	for i := range c.hashProbeDepth {
+		slotHash, slotKey, value := c.slots[c.prober.Index(keyHash, i)].read()
+		if slotHash == keyHash && slotKey == key {
+			c.readHits.Add(1)
+			return value, true
+		}
+	}
+
+	c.readMisses.Add(1)
+
+	return *new(V), false
+}
+
+// Put stores a copy of value for key.
+func (c *ValueCache[K, V]) Put(key K, value V) {
+	if len(c.slots) == 0 {
+		return
+	}
+
+	keyHash := c.hash(key)
+
+	c.writes.Add(1)
+
+	// Overwrite the slot of the same key, or otherwise claim the first empty one.
+	empty := -1
+
+	for i := range c.hashProbeDepth {
+		slot := &c.slots[c.prober.Index(keyHash, i)]
+
+		sequence := slot.lock()
+		if slot.keyHash == keyHash && slot.key == key {
+			slot.write(sequence, keyHash, key, value)
+			return
+		}
+
+		if slot.keyHash == 0 && empty < 0 {
+			empty = i
+		}
+
+		slot.sequence.Store(sequence)
+	}
+
+	if empty >= 0 {
+		slot := &c.slots[c.prober.Index(keyHash, empty)]
+
+		sequence := slot.lock()
+		if slot.keyHash == 0 {
+			slot.write(sequence, keyHash, key, value)
+			return
+		}
+
+		slot.sequence.Store(sequence)
+	}
+
+	// Evict a random entry within the probe depth.
+	slot := &c.slots[c.prober.Index(keyHash, rand.IntN(c.hashProbeDepth))]
+	slot.write(slot.lock(), keyHash, key, value)
+
+	c.overwrites.Add(1)
+}
+
+// Delete removes the entry for key.
+func (c *ValueCache[K, V]) Delete(key K) {
+	if len(c.slots) == 0 {
+		return
+	}
+
+	keyHash := c.hash(key)
+
+	for i := range c.hashProbeDepth {
+		slot := &c.slots[c.prober.Index(keyHash, i)]
+
+		sequence := slot.lock()
+		if slot.keyHash == keyHash && slot.key == key {
+			slot.write(sequence, 0, *new(K), *new(V))
+			return
+		}
+
+		slot.sequence.Store(sequence)
+	}
+}
+
+// Len returns the number of entries.
+func (c *ValueCache[K, V]) Len() int {
+	count := 0
+
+	for i := range c.slots {
+		if keyHash, _, _ := c.slots[i].read(); keyHash != 0 {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (c *ValueCache[K, V]) Cap() int {
+	return len(c.slots)
+}
+
+func (c *ValueCache[K, V]) Metrics() Metrics {
+	metrics := Metrics{
+		ReadMisses:  c.readMisses.Load(),
+		ReadHits:    c.readHits.Load(),
+		EmptyWrites: c.writes.Load(),
+	}
+
+	metrics.Evictions[EvictionOverwritten] = c.overwrites.Load()
+
+	return metrics
+}
+
+// hash returns the key hash, where zero is reserved for empty slots.
+func (c *ValueCache[K, V]) hash(key K) uint64 {
+	return max(1, maphash.Comparable(c.seed, key))
+}
+
+// lock waits until no other writer holds the slot, locks it, and returns the sequence to unlock it with.
+func (s *valueSlot[K, V]) lock() uint64 {
+	for {
+		sequence := s.sequence.Load()
+		if sequence&1 == 0 && s.sequence.CompareAndSwap(sequence, sequence+1) {
+			return sequence
+		}
+
+		runtime.Gosched()
+	}
+}
+
+// write writes the locked slot, and unlocks it with the next sequence, so concurrent readers retry.
+func (s *valueSlot[K, V]) write(sequence, keyHash uint64, key K, value V) {
+	s.keyHash, s.key, s.value = keyHash, key, value
+	s.sequence.Store(sequence + 2)
+}
+
+// read returns a consistent copy of the slot. The copy is only used once its sequence was validated,
+// as a torn copy may hold a mix of two writes.
+func (s *valueSlot[K, V]) read() (uint64, K, V) {
+	if raceEnabled {
+		sequence := s.lock()
+		keyHash, key, value := s.keyHash, s.key, s.value
+		s.sequence.Store(sequence)
+
+		return keyHash, key, value
+	}
+
+	for {
+		sequence := s.sequence.Load()
+		if sequence&1 != 0 {
+			runtime.Gosched()
+			continue
+		}
+
+		keyHash, key, value := s.keyHash, s.key, s.value
+
+		if s.sequence.Load() == sequence {
+			return keyHash, key, value
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package cache_test
+
+import (
+	cryptorand "crypto/rand"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+type point struct {
+	x, y int64
+}
+
+func TestValueCache(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewValueCache[string, point](N / 100)
+
+	key := cryptorand.Text()
+	testCache.Put(key, point{x: 1, y: 2})
+
+	value, ok := testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, point{x: 1, y: 2})
+
+	testCache.Put(key, point{x: 3, y: 4})
+
+	value, ok = testCache.Get(key)
+	check.True(t, ok)
+	check.Equal(t, value, point{x: 3, y: 4})
+	check.Equal(t, testCache.Len(), 1)
+
+	testCache.Delete(key)
+
+	_, ok = testCache.Get(key)
+	check.True(t, !ok)
+	check.Equal(t, testCache.Len(), 0)
+
+	metrics := testCache.Metrics()
+	check.Equal(t, metrics.ReadHits, 2)
+	check.Equal(t, metrics.ReadMisses, 1)
+
+	// Values are stored inline, so they survive without references and garbage collection.
+	for i := range 2 * N / 100 {
+		testCache.Put(strconv.Itoa(i), point{x: int64(i)})
+	}
+
+	check.True(t, testCache.Len() <= testCache.Cap())
+	check.True(t, testCache.Metrics().Evictions[cache.EvictionOverwritten] > 0)
+
+	check.Equal(t, cache.NewValueCache[string, point](0).Len(), 0)
+}
+
+func TestValueCacheConcurrent(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewValueCache[string, point](N / 100)
+
+	var wg sync.WaitGroup
+
+	for writer := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				value := int64(writer*1000 + i)
+				testCache.Put(strconv.Itoa(i%100), point{x: value, y: -value})
+			}
+		}()
+	}
+
+	for range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				// Reads are never torn.
+				if value, ok := testCache.Get(strconv.Itoa(i % 100)); ok {
+					check.Equal(t, value.y, -value.x)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"strconv"
+	"time"
+)
+
+// Version identifies a write of an entry, for optimistic concurrency control with PutVersioned.
+// The zero Version stands for a missing entry. Versions can be passed between services as text.
+type Version struct {
+	v uint64
+}
+
+func (v Version) MarshalText() ([]byte, error) {
+	return strconv.AppendUint(nil, v.v, 10), nil
+}
+
+func (v *Version) UnmarshalText(text []byte) error {
+	parsed, err := strconv.ParseUint(string(text), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	v.v = parsed
+
+	return nil
+}
+
+func (v Version) String() string {
+	return strconv.FormatUint(v.v, 10)
+}
+
+// GetVersioned is like Get, but also returns the version of the entry, or the zero Version if it was not found.
+func (c *LockFreeCache[K, V]) GetVersioned(key K) (V, Version, bool) {
+	if !c.initialized.Load() {
+		return *new(V), Version{}, false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	_, entry, value := c.find(key)
+	if entry == nil {
+		c.readMisses.Add(1)
+		return *new(V), Version{}, false
+	}
+
+	c.readHits.Add(1)
+
+	return *value, Version{v: entry.version}, true
+}
+
+// PutVersioned stores value for key only if the entry was not written since GetVersioned returned version,
+// or if no entry exists for the zero Version, and reports whether it did. Of concurrent calls with the same
+// version, at most one succeeds, so read-modify-write flows can retry on conflicts.
+// Pinning an entry does not change its version.
+func (c *LockFreeCache[K, V]) PutVersioned(key K, value *V, version Version) bool {
+	if !c.initialized.Load() {
+		return false
+	}
+
+	defer c.reclaim.exit(c.reclaim.enter())
+
+	newEntry := c.newEntry(key, value, time.Now().UnixNano())
+
+	if version == (Version{}) {
+		if !c.putIfAbsent(newEntry) {
+			return false
+		}
+
+		c.publish(key)
+
+		return true
+	}
+
+	for {
+		index, entry, _ := c.find(key)
+		if entry == nil || entry.version != version.v {
+			return false
+		}
+
+		if c.swapSlot(index, entry, newEntry) {
+			c.account(newEntry, entry, EvictionReplaced)
+			c.publish(key)
+
+			return true
+		}
+	}
+}
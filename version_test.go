@@ -0,0 +1,54 @@
+package cache_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCachePutVersioned(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	val1, val2, val3 := uint64(1), uint64(2), uint64(3)
+
+	_, version, ok := testCache.GetVersioned("key")
+	check.True(t, !ok)
+
+	// The zero version only stores missing entries.
+	check.True(t, testCache.PutVersioned("key", &val1, version))
+	check.True(t, !testCache.PutVersioned("key", &val2, version))
+
+	value, version, ok := testCache.GetVersioned("key")
+	check.True(t, ok)
+	check.Equal(t, value, 1)
+
+	// Pinning keeps the version.
+	check.True(t, testCache.Pin("key"))
+
+	check.True(t, testCache.PutVersioned("key", &val2, version))
+
+	// The version is outdated after a write.
+	check.True(t, !testCache.PutVersioned("key", &val3, version))
+
+	value, _, _ = testCache.GetVersioned("key")
+	check.Equal(t, value, 2)
+
+	// Versions survive a round trip as text.
+	_, version, _ = testCache.GetVersioned("key")
+
+	text, err := version.MarshalText()
+	check.True(t, err == nil)
+
+	var parsed cache.Version
+	check.True(t, parsed.UnmarshalText(text) == nil)
+	check.Equal(t, parsed, version)
+	check.True(t, testCache.PutVersioned("key", &val3, parsed))
+
+	runtime.KeepAlive(&val1)
+	runtime.KeepAlive(&val2)
+	runtime.KeepAlive(&val3)
+}
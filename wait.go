@@ -0,0 +1,105 @@
+package cache
+
+import "context"
+
+// putWait is the wait of all concurrent GetOrWait calls for the same key, until the next put of the key.
+type putWait[V any] struct {
+	done  chan struct{}
+	value *V
+
+	// waiters is the number of calls waiting, guarded by the wait lock.
+	waiters int
+}
+
+// GetOrWait returns the value for key. If no live entry exists, it waits until the next write of key by another
+// goroutine, such as a Put, and returns its value. Concurrent calls for the same key share a single wait.
+// It returns the error of ctx once it is done, so a timeout is set with context.WithTimeout, and ErrClosed once
+// the cache is closed.
+func (c *LockFreeCache[K, V]) GetOrWait(ctx context.Context, key K) (V, error) {
+	if err := c.usable(); err != nil {
+		return *new(V), err
+	}
+
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	wait := c.startWait(key)
+
+	// The key may have been written since the miss, before the wait was registered.
+	if value, ok := c.Peek(key); ok {
+		c.stopWait(key, wait)
+		return value, nil
+	}
+
+	select {
+	case <-wait.done:
+		return *wait.value, nil
+	case <-ctx.Done():
+		c.stopWait(key, wait)
+		return *new(V), ctx.Err()
+	case <-c.life.stop:
+		c.stopWait(key, wait)
+		return *new(V), ErrClosed
+	}
+}
+
+// startWait registers a call waiting for the next put of key.
+func (c *LockFreeCache[K, V]) startWait(key K) *putWait[V] {
+	c.waitLock.Lock()
+	defer c.waitLock.Unlock()
+
+	if wait, ok := c.waits[key]; ok {
+		wait.waiters++
+		return wait
+	}
+
+	wait := &putWait[V]{done: make(chan struct{}), waiters: 1}
+
+	if c.waits == nil {
+		c.waits = make(map[K]*putWait[V])
+	}
+
+	c.waits[key] = wait
+	c.waiting.Add(1)
+
+	return wait
+}
+
+// stopWait unregisters a call which no longer waits for wait, and removes wait once no calls are left.
+func (c *LockFreeCache[K, V]) stopWait(key K, wait *putWait[V]) {
+	c.waitLock.Lock()
+	defer c.waitLock.Unlock()
+
+	if wait.waiters--; wait.waiters == 0 && c.waits[key] == wait {
+		delete(c.waits, key)
+		c.waiting.Add(-1)
+	}
+}
+
+// wake releases the calls waiting for a put of the key of entry, with its value.
+func (c *LockFreeCache[K, V]) wake(entry *cacheEntry[K, V]) {
+	value := entry.valueRef.Value()
+	if value == nil {
+		return
+	}
+
+	c.waitLock.Lock()
+	defer c.waitLock.Unlock()
+
+	wait, ok := c.waits[entry.key]
+	if !ok {
+		return
+	}
+
+	delete(c.waits, entry.key)
+	c.waiting.Add(-1)
+
+	wait.value = value
+	close(wait.done)
+}
+
+// GetOrWait is like LockFreeCache.GetOrWait.
+func (c *ShardedCache[K, V]) GetOrWait(ctx context.Context, key K) (V, error) {
+	return c.shard(key).GetOrWait(ctx, key)
+}
@@ -0,0 +1,72 @@
+package cache_test
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"errors"
+	mathrand "math/rand/v2"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samborkent/cache"
+	"github.com/samborkent/check"
+)
+
+func TestLockFreeCacheGetOrWait(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	key := cryptorand.Text()
+	val := mathrand.Uint64()
+
+	var wg sync.WaitGroup
+
+	for range 16 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			value, err := testCache.GetOrWait(t.Context(), key)
+			check.True(t, err == nil)
+			check.Equal(t, value, val)
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	testCache.Put(key, &val)
+
+	wg.Wait()
+
+	// Live entries are returned without waiting.
+	value, err := testCache.GetOrWait(t.Context(), key)
+	check.True(t, err == nil)
+	check.Equal(t, value, val)
+
+	runtime.KeepAlive(&val)
+}
+
+func TestLockFreeCacheGetOrWaitTimeout(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := testCache.GetOrWait(ctx, cryptorand.Text())
+	check.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	closed := cache.NewLockFreeCache[string, uint64](N / 100)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		check.True(t, closed.Close() == nil)
+	}()
+
+	_, err = closed.GetOrWait(t.Context(), cryptorand.Text())
+	check.True(t, errors.Is(err, cache.ErrClosed))
+}
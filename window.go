@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// windowCounter counts events in a ring of fixed-interval buckets,
+// so the number of events within a recent window can be queried.
+type windowCounter struct {
+	buckets  []windowBucket
+	interval int64
+}
+
+type windowBucket struct {
+	epoch atomic.Int64
+	count atomic.Uint64
+}
+
+func newWindowCounter(interval time.Duration, buckets int) windowCounter {
+	return windowCounter{
+		buckets:  make([]windowBucket, buckets),
+		interval: int64(interval),
+	}
+}
+
+func (w *windowCounter) add(now time.Time, n uint64) {
+	if len(w.buckets) == 0 {
+		return
+	}
+
+	epoch := now.UnixNano() / w.interval
+	bucket := &w.buckets[epoch%int64(len(w.buckets))]
+
+	if current := bucket.epoch.Load(); current != epoch {
+		// Bucket belongs to an earlier lap of the ring, reset it.
+		// Concurrent adds during the reset may be lost, which is acceptable for rates.
+		if bucket.epoch.CompareAndSwap(current, epoch) {
+			bucket.count.Store(0)
+		}
+	}
+
+	bucket.count.Add(n)
+}
+
+// sum returns the number of events within window before now, and the window which was actually covered.
+func (w *windowCounter) sum(now time.Time, window time.Duration) (uint64, time.Duration) {
+	if len(w.buckets) == 0 {
+		return 0, 0
+	}
+
+	epoch := now.UnixNano() / w.interval
+	count := min(max(1, int64(window)/w.interval), int64(len(w.buckets)))
+
+	var total uint64
+
+	for i := range count {
+		bucket := &w.buckets[(epoch-i)%int64(len(w.buckets))]
+		if bucket.epoch.Load() == epoch-i {
+			total += bucket.count.Load()
+		}
+	}
+
+	return total, time.Duration(count * w.interval)
+}
+
+func (w *windowCounter) reset() {
+	for i := range w.buckets {
+		w.buckets[i].epoch.Store(0)
+		w.buckets[i].count.Store(0)
+	}
+}
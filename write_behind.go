@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// BatchWriteStore is a WriteStore which can save multiple values at once.
+// Write-behind caches use it to save batches, if the store implements it.
+type BatchWriteStore[K comparable, V any] interface {
+	WriteStore[K, V]
+	SaveBatch(ctx context.Context, values map[K]*V) error
+}
+
+// writeOp is a queued write. A nil value deletes the key.
+type writeOp[K comparable, V any] struct {
+	key   K
+	value *V
+}
+
+// writeBehind queues writes to a store, which are saved in batches by background workers.
+// Each key is routed to the queue of one worker by its hash, so the writes of a key are saved in order.
+type writeBehind[K comparable, V any] struct {
+	store    WriteStore[K, V]
+	seed     maphash.Seed
+	queues   []chan writeOp[K, V]
+	flushes  []chan chan error
+	interval time.Duration
+	wg       sync.WaitGroup
+
+	// lock guards closed against concurrent enqueues.
+	lock   sync.RWMutex
+	closed bool
+
+	// errs collects errors of background flushes, until they are returned by Flush or Close.
+	errsLock sync.Mutex
+	errs     []error
+}
+
+func newWriteBehind[K comparable, V any](store WriteStore[K, V], queueSize, workers int, interval time.Duration) *writeBehind[K, V] {
+	w := &writeBehind[K, V]{
+		store:    store,
+		seed:     maphash.MakeSeed(),
+		queues:   make([]chan writeOp[K, V], workers),
+		flushes:  make([]chan chan error, workers),
+		interval: interval,
+	}
+
+	batchSize := max(1, queueSize/workers)
+
+	for i := range w.queues {
+		w.queues[i] = make(chan writeOp[K, V], batchSize)
+		w.flushes[i] = make(chan chan error)
+		w.wg.Add(1)
+
+		go w.work(w.queues[i], w.flushes[i], batchSize)
+	}
+
+	return w
+}
+
+// enqueue queues a write on the queue of its key, blocking while the queue is full.
+func (w *writeBehind[K, V]) enqueue(ctx context.Context, op writeOp[K, V]) error {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+
+	queue := w.queues[maphash.Comparable(w.seed, op.key)%uint64(len(w.queues))]
+
+	select {
+	case queue <- op:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work saves batches of queued writes, when a batch is full, every interval, on flush, and when the queue is closed.
+func (w *writeBehind[K, V]) work(queue chan writeOp[K, V], flushes chan chan error, batchSize int) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	batch := make(map[K]*V, batchSize)
+
+	for {
+		select {
+		case op, ok := <-queue:
+			if !ok {
+				w.report(w.save(batch))
+				return
+			}
+
+			batch[op.key] = op.value
+
+			if len(batch) >= batchSize {
+				w.report(w.save(batch))
+			}
+		case <-ticker.C:
+			w.report(w.save(batch))
+		case done := <-flushes:
+			w.drain(queue, batch)
+			done <- w.save(batch)
+		}
+	}
+}
+
+// drain moves all currently queued writes into the batch.
+func (w *writeBehind[K, V]) drain(queue chan writeOp[K, V], batch map[K]*V) {
+	for {
+		select {
+		case op, ok := <-queue:
+			if !ok {
+				return
+			}
+
+			batch[op.key] = op.value
+		default:
+			return
+		}
+	}
+}
+
+// save writes the batch to the store and clears it.
+// Writes of the same key within a batch are coalesced, the last one wins.
+func (w *writeBehind[K, V]) save(batch map[K]*V) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	var errs []error
+
+	values := make(map[K]*V, len(batch))
+
+	for key, value := range batch {
+		if value == nil {
+			if err := w.store.Delete(ctx, key); err != nil {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		values[key] = value
+	}
+
+	if batchStore, ok := w.store.(BatchWriteStore[K, V]); ok {
+		if len(values) > 0 {
+			errs = append(errs, batchStore.SaveBatch(ctx, values))
+		}
+	} else {
+		for key, value := range values {
+			errs = append(errs, w.store.Save(ctx, key, value))
+		}
+	}
+
+	clear(batch)
+
+	return errors.Join(errs...)
+}
+
+func (w *writeBehind[K, V]) report(err error) {
+	if err == nil {
+		return
+	}
+
+	w.errsLock.Lock()
+	w.errs = append(w.errs, err)
+	w.errsLock.Unlock()
+}
+
+// reported returns and clears the errors of background flushes.
+func (w *writeBehind[K, V]) reported() []error {
+	w.errsLock.Lock()
+	defer w.errsLock.Unlock()
+
+	errs := w.errs
+	w.errs = nil
+
+	return errs
+}
+
+// flush saves all writes queued before the call, and returns any errors since the last flush.
+// Once ctx is done, it returns its error without waiting for the remaining workers.
+func (w *writeBehind[K, V]) flush(ctx context.Context) error {
+	// Hold the lock, so the workers are not stopped during the flush.
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.closed {
+		return errors.Join(w.reported()...)
+	}
+
+	errs := make([]error, 0, len(w.flushes))
+
+	for _, flushes := range w.flushes {
+		done := make(chan error, 1)
+
+		select {
+		case flushes <- done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		select {
+		case err := <-done:
+			errs = append(errs, err)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return errors.Join(append(w.reported(), errs...)...)
+}
+
+// close stops accepting writes, waits until all queued writes are saved, and returns any errors since the last flush.
+func (w *writeBehind[K, V]) close() error {
+	w.lock.Lock()
+
+	if w.closed {
+		w.lock.Unlock()
+		return nil
+	}
+
+	w.closed = true
+
+	for _, queue := range w.queues {
+		close(queue)
+	}
+
+	w.lock.Unlock()
+
+	w.wg.Wait()
+
+	return errors.Join(w.reported()...)
+}